@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,7 @@ import (
 	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"github.com/PeerDB-io/peer-flow/peerdbenv"
 	"github.com/PeerDB-io/peer-flow/shared"
 	"github.com/PeerDB-io/peer-flow/shared/alerting"
@@ -149,6 +152,31 @@ func (a *FlowableActivity) CreateRawTable(
 	return res, nil
 }
 
+// MigrateRawTable migrates the raw table of a mirror that is already running on the destination flowable,
+// adding the columns it is missing.
+func (a *FlowableActivity) MigrateRawTable(ctx context.Context, config *protos.CreateRawTableInput) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	dstConn, err := connectors.GetCDCSyncConnector(ctx, config.PeerConnectionConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	switch conn := dstConn.(type) {
+	case *connpostgres.PostgresConnector:
+		err = conn.MigrateRawTable(config.FlowJobName)
+	case *connsnowflake.SnowflakeConnector:
+		err = conn.MigrateRawTable(config.FlowJobName)
+	case *connbigquery.BigQueryConnector:
+		err = conn.MigrateRawTable(config.FlowJobName)
+	}
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return err
+	}
+	return nil
+}
+
 // GetTableSchema returns the schema of a table.
 func (a *FlowableActivity) GetTableSchema(
 	ctx context.Context,
@@ -209,6 +237,29 @@ func (a *FlowableActivity) StartFlow(ctx context.Context,
 		tblNameMapping[v.SourceTableIdentifier] = model.NewNameAndExclude(v.DestinationTableIdentifier, v.Exclude)
 	}
 
+	// fan-out destinations are synced from the same pull, which starts after the checkpoint every destination
+	// has synced up to, so that the slot is only advanced once all of them have synced
+	checkpoints := []int64{input.LastSyncState.Checkpoint}
+	fanOutConns := make([]connectors.CDCSyncConnector, 0, len(conn.FanOutDestinations))
+	for _, destination := range conn.FanOutDestinations {
+		fanOutConn, err := connectors.GetCDCSyncConnector(ctx, destination)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fan-out destination connector %s: %w", destination.Name, err)
+		}
+		defer connectors.CloseConnector(fanOutConn)
+
+		err = fanOutConn.InitializeTableSchema(input.FlowConnectionConfigs.TableNameSchemaMapping)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize table schema of fan-out destination %s: %w", destination.Name, err)
+		}
+		checkpoint, err := fanOutConn.GetLastOffset(input.FlowConnectionConfigs.FlowJobName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last offset of fan-out destination %s: %w", destination.Name, err)
+		}
+		checkpoints = append(checkpoints, checkpoint)
+		fanOutConns = append(fanOutConns, fanOutConn)
+	}
+
 	errGroup, errCtx := errgroup.WithContext(ctx)
 	srcConn, err := connectors.GetCDCPullConnector(errCtx, conn.Source)
 	if err != nil {
@@ -238,7 +289,7 @@ func (a *FlowableActivity) StartFlow(ctx context.Context,
 			FlowJobName:           flowName,
 			SrcTableIDNameMapping: input.FlowConnectionConfigs.SrcTableIdNameMapping,
 			TableNameMapping:      tblNameMapping,
-			LastOffset:            input.LastSyncState.Checkpoint,
+			LastOffset:            slices.Min(checkpoints),
 			MaxBatchSize:          uint32(input.SyncFlowOptions.BatchSize),
 			IdleTimeout: peerdbenv.PeerDBCDCIdleTimeoutSeconds(
 				int(input.FlowConnectionConfigs.IdleTimeoutSeconds),
@@ -248,9 +299,26 @@ func (a *FlowableActivity) StartFlow(ctx context.Context,
 			OverrideReplicationSlotName: input.FlowConnectionConfigs.ReplicationSlotName,
 			RelationMessageMapping:      input.RelationMessageMapping,
 			RecordStream:                recordBatch,
+			// the pull starts from the lowest offset of all destinations, so it is advanced on all of them
 			SetLastOffset: func(lastOffset int64) error {
-				return dstConn.SetLastOffset(flowName, lastOffset)
+				for _, syncConn := range append([]connectors.CDCSyncConnector{dstConn}, fanOutConns...) {
+					if err := syncConn.SetLastOffset(flowName, lastOffset); err != nil {
+						return err
+					}
+				}
+				return nil
 			},
+			BackfillUnchangedToastColumns: input.FlowConnectionConfigs.BackfillUnchangedToastColumns,
+			CaptureBeforeImages:           input.FlowConnectionConfigs.BeforeImageColName != "",
+			LogicalMessagePrefixes:        input.FlowConnectionConfigs.LogicalMessagePrefixes,
+			LogicalMessageDestination:     input.FlowConnectionConfigs.LogicalMessageDestination,
+			PullFromStandby:               input.FlowConnectionConfigs.PullFromStandby,
+			SkipReplicatedChanges:         input.FlowConnectionConfigs.Bidirectional,
+			IncrementalSnapshotChunkSize:  input.FlowConnectionConfigs.IncrementalSnapshotChunkSize,
+			DeadLetterFailedRecords: input.FlowConnectionConfigs.RecordErrorPolicy ==
+				protos.RecordErrorPolicy_RECORD_ERROR_POLICY_DEAD_LETTER,
+			SourceColName:  input.FlowConnectionConfigs.SourceColName,
+			SourceColValue: input.FlowConnectionConfigs.Source.Name,
 		})
 	})
 
@@ -290,13 +358,13 @@ func (a *FlowableActivity) StartFlow(ctx context.Context,
 	}
 
 	syncStartTime := time.Now()
-	res, err := dstConn.SyncRecords(&model.SyncRecordsRequest{
-		Records:         recordBatch,
-		FlowJobName:     input.FlowConnectionConfigs.FlowJobName,
-		StagingPath:     input.FlowConnectionConfigs.CdcStagingPath,
-		PushBatchSize:   input.FlowConnectionConfigs.PushBatchSize,
-		PushParallelism: input.FlowConnectionConfigs.PushParallelism,
-	})
+	var res *model.SyncResponse
+	if len(fanOutConns) == 0 {
+		res, err = dstConn.SyncRecords(syncRecordsRequest(input.FlowConnectionConfigs, recordBatch))
+	} else {
+		res, err = syncRecordsFanOut(input.FlowConnectionConfigs, append([]connectors.CDCSyncConnector{dstConn},
+			fanOutConns...), recordBatch.FanOut(checkpoints))
+	}
 	if err != nil {
 		slog.Warn("failed to push records", slog.Any("error", err))
 		a.Alerter.LogFlowError(ctx, flowName, err)
@@ -366,6 +434,46 @@ func (a *FlowableActivity) StartFlow(ctx context.Context,
 	return res, nil
 }
 
+func syncRecordsRequest(config *protos.FlowConnectionConfigs, records *model.CDCRecordStream) *model.SyncRecordsRequest {
+	return &model.SyncRecordsRequest{
+		Records:            records,
+		FlowJobName:        config.FlowJobName,
+		StagingPath:        config.CdcStagingPath,
+		PushBatchSize:      config.PushBatchSize,
+		PushParallelism:    config.PushParallelism,
+		BeforeImageColName: config.BeforeImageColName,
+	}
+}
+
+// syncRecordsFanOut syncs the records pulled once to every destination, each from its own stream.
+// It returns the response of the first destination, the destination of the mirror.
+func syncRecordsFanOut(
+	config *protos.FlowConnectionConfigs,
+	dstConns []connectors.CDCSyncConnector,
+	streams []*model.CDCRecordStream,
+) (*model.SyncResponse, error) {
+	responses := make([]*model.SyncResponse, len(dstConns))
+	var syncGroup errgroup.Group
+	for i, dstConn := range dstConns {
+		i, dstConn := i, dstConn
+		syncGroup.Go(func() error {
+			res, err := dstConn.SyncRecords(syncRecordsRequest(config, streams[i]))
+			if err != nil {
+				// the stream is drained so that the other destinations aren't blocked on it
+				for range streams[i].GetRecords() {
+				}
+				return err
+			}
+			responses[i] = res
+			return nil
+		})
+	}
+	if err := syncGroup.Wait(); err != nil {
+		return nil, err
+	}
+	return responses[0], nil
+}
+
 func (a *FlowableActivity) StartNormalize(
 	ctx context.Context,
 	input *protos.StartNormalizeInput,
@@ -405,10 +513,15 @@ func (a *FlowableActivity) StartNormalize(
 	}
 
 	res, err := dstConn.NormalizeRecords(&model.NormalizeRecordsRequest{
-		FlowJobName:       input.FlowConnectionConfigs.FlowJobName,
-		SoftDelete:        input.FlowConnectionConfigs.SoftDelete,
-		SoftDeleteColName: input.FlowConnectionConfigs.SoftDeleteColName,
-		SyncedAtColName:   input.FlowConnectionConfigs.SyncedAtColName,
+		FlowJobName:         input.FlowConnectionConfigs.FlowJobName,
+		SoftDelete:          input.FlowConnectionConfigs.SoftDelete,
+		SoftDeleteColName:   input.FlowConnectionConfigs.SoftDeleteColName,
+		SyncedAtColName:     input.FlowConnectionConfigs.SyncedAtColName,
+		BeforeImageColName:  input.FlowConnectionConfigs.BeforeImageColName,
+		HistoryMode:         input.FlowConnectionConfigs.HistoryMode,
+		TransactionMetadata: input.FlowConnectionConfigs.TransactionMetadata,
+		Bidirectional:       input.FlowConnectionConfigs.Bidirectional,
+		ConflictResolution:  input.FlowConnectionConfigs.ConflictResolution,
 	})
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, input.FlowConnectionConfigs.FlowJobName, err)
@@ -437,11 +550,90 @@ func (a *FlowableActivity) StartNormalize(
 	return res, nil
 }
 
+// GetLastSyncBatchID returns the ID of the last batch synced to the destination of a CDC mirror.
+func (a *FlowableActivity) GetLastSyncBatchID(ctx context.Context, config *protos.FlowConnectionConfigs) (int64, error) {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	dstConn, err := connectors.GetCDCSyncConnector(ctx, config.Destination)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	return dstConn.GetLastSyncBatchID(config.FlowJobName)
+}
+
+// ReplayNormalizedChanges normalizes changes to destination tables from the batches after fromBatchID again,
+// up to the last normalized batch. Tables that were swapped for resynced ones catch up with these changes.
+func (a *FlowableActivity) ReplayNormalizedChanges(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	tables []string,
+	fromBatchID int64,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	dstConn, err := connectors.GetCDCNormalizeConnector(ctx, config.Destination)
+	if err != nil {
+		return fmt.Errorf("failed to get connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	shutdown := utils.HeartbeatRoutine(ctx, 2*time.Minute, func() string {
+		return fmt.Sprintf("replaying changes to tables %v from batch %d for job - %s",
+			tables, fromBatchID, config.FlowJobName)
+	})
+	defer shutdown()
+
+	err = dstConn.InitializeTableSchema(config.TableNameSchemaMapping)
+	if err != nil {
+		return fmt.Errorf("failed to initialize table schema: %w", err)
+	}
+
+	res, err := dstConn.NormalizeRecords(&model.NormalizeRecordsRequest{
+		FlowJobName:         config.FlowJobName,
+		SoftDelete:          config.SoftDelete,
+		SoftDeleteColName:   config.SoftDeleteColName,
+		SyncedAtColName:     config.SyncedAtColName,
+		BeforeImageColName:  config.BeforeImageColName,
+		HistoryMode:         config.HistoryMode,
+		TransactionMetadata: config.TransactionMetadata,
+		Bidirectional:       config.Bidirectional,
+		ConflictResolution:  config.ConflictResolution,
+		ReplayTables:        tables,
+		ReplayFromBatchID:   fromBatchID,
+	})
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to replay changes: %w", err)
+	}
+
+	if res.Done {
+		slog.InfoContext(ctx, fmt.Sprintf("replayed changes to tables %v from batch %d to batch %d",
+			tables, res.StartBatchID, res.EndBatchID))
+	}
+	return nil
+}
+
 func (a *FlowableActivity) ReplayTableSchemaDeltas(
 	ctx context.Context,
 	input *protos.ReplayTableSchemaDeltaInput,
 ) error {
-	dest, err := connectors.GetCDCNormalizeConnector(ctx, input.FlowConnectionConfigs.Destination)
+	config := input.FlowConnectionConfigs
+	for _, destination := range append([]*protos.Peer{config.Destination}, config.FanOutDestinations...) {
+		err := a.replayTableSchemaDeltas(ctx, destination, config.FlowJobName, input.TableSchemaDeltas)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *FlowableActivity) replayTableSchemaDeltas(
+	ctx context.Context,
+	destination *protos.Peer,
+	flowJobName string,
+	schemaDeltas []*protos.TableSchemaDelta,
+) error {
+	dest, err := connectors.GetCDCNormalizeConnector(ctx, destination)
 	if errors.Is(err, connectors.ErrUnsupportedFunctionality) {
 		return nil
 	} else if err != nil {
@@ -449,15 +641,42 @@ func (a *FlowableActivity) ReplayTableSchemaDeltas(
 	}
 	defer connectors.CloseConnector(dest)
 
-	err = dest.ReplayTableSchemaDeltas(input.FlowConnectionConfigs.FlowJobName, input.TableSchemaDeltas)
+	err = dest.ReplayTableSchemaDeltas(flowJobName, schemaDeltas)
 	if err != nil {
-		a.Alerter.LogFlowError(ctx, input.FlowConnectionConfigs.FlowJobName, err)
+		a.Alerter.LogFlowError(ctx, flowJobName, err)
 		return fmt.Errorf("failed to replay table schema deltas: %w", err)
 	}
 
 	return nil
 }
 
+// AlertSchemaChange raises an alert for schema changes that paused or failed a mirror.
+func (a *FlowableActivity) AlertSchemaChange(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	schemaDeltas []*protos.TableSchemaDelta,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	changes := make([]string, 0, len(schemaDeltas))
+	for _, delta := range schemaDeltas {
+		columns := make([]string, 0, len(delta.AddedColumns))
+		for _, column := range delta.AddedColumns {
+			columns = append(columns, fmt.Sprintf("%s (%s)", column.ColumnName, column.ColumnType))
+		}
+		changes = append(changes, fmt.Sprintf("%s: added columns %s", delta.SrcTableName, strings.Join(columns, ", ")))
+	}
+
+	action := "has failed"
+	if config.SchemaChangePolicy == protos.SchemaChangePolicy_SCHEMA_CHANGE_POLICY_PAUSE {
+		action = "is paused until the schema changes are accepted or rejected"
+	}
+	message := fmt.Sprintf("mirror %s %s, schema changes detected on source tables: %s",
+		config.FlowJobName, action, strings.Join(changes, "; "))
+	a.Alerter.LogFlowInfo(ctx, config.FlowJobName, message)
+	a.Alerter.AlertIf(ctx, config.FlowJobName+"-schema-change", message)
+	return nil
+}
+
 // SetupQRepMetadataTables sets up the metadata tables for QReplication.
 func (a *FlowableActivity) SetupQRepMetadataTables(ctx context.Context, config *protos.QRepConfig) error {
 	conn, err := connectors.GetQRepSyncConnector(ctx, config.DestinationPeer)
@@ -493,6 +712,35 @@ func (a *FlowableActivity) GetQRepPartitions(ctx context.Context,
 	})
 	defer shutdown()
 
+	if config.ResumePartitions {
+		recorded, finished, err := monitoring.GetRecordedQRepPartitions(ctx, a.CatalogPool, config.FlowJobName)
+		if err != nil {
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+			return nil, fmt.Errorf("failed to get recorded partitions: %w", err)
+		}
+		if len(recorded) > 0 {
+			// partitions synced to the destination but not marked finished are skipped by the destination
+			remaining := make([]*protos.QRepPartition, 0, len(recorded)-len(finished))
+			for _, partition := range recorded {
+				if _, ok := finished[partition.PartitionId]; !ok {
+					remaining = append(remaining, partition)
+				}
+			}
+			slog.InfoContext(ctx, fmt.Sprintf("resuming with %d of %d partitions remaining",
+				len(remaining), len(recorded)))
+
+			if len(remaining) > 0 {
+				err = monitoring.InitializeQRepRun(ctx, a.CatalogPool, config, runUUID, remaining)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return &protos.QRepParitionResult{
+				Partitions: remaining,
+			}, nil
+		}
+	}
+
 	partitions, err := srcConn.GetQRepPartitions(config, last)
 	if err != nil {
 		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
@@ -703,19 +951,31 @@ func (a *FlowableActivity) DropFlow(ctx context.Context, config *protos.Shutdown
 	}
 	defer connectors.CloseConnector(srcConn)
 
-	dstConn, err := connectors.GetCDCSyncConnector(ctx, config.DestinationPeer)
+	err = srcConn.PullFlowCleanup(config.FlowJobName, config.PullFromStandby)
 	if err != nil {
-		return fmt.Errorf("failed to get destination connector: %w", err)
+		return fmt.Errorf("failed to cleanup source: %w", err)
 	}
-	defer connectors.CloseConnector(dstConn)
 
-	err = srcConn.PullFlowCleanup(config.FlowJobName)
+	// fan-out destinations have their own raw tables and metadata, like the destination peer
+	for _, destination := range append([]*protos.Peer{config.DestinationPeer}, config.FanOutDestinationPeers...) {
+		err = a.cleanupDestination(ctx, destination, config.FlowJobName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *FlowableActivity) cleanupDestination(ctx context.Context, destination *protos.Peer, flowJobName string) error {
+	dstConn, err := connectors.GetCDCSyncConnector(ctx, destination)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup source: %w", err)
+		return fmt.Errorf("failed to get connector of destination %s: %w", destination.Name, err)
 	}
-	err = dstConn.SyncFlowCleanup(config.FlowJobName)
+	defer connectors.CloseConnector(dstConn)
+
+	err = dstConn.SyncFlowCleanup(flowJobName)
 	if err != nil {
-		return fmt.Errorf("failed to cleanup destination: %w", err)
+		return fmt.Errorf("failed to cleanup destination %s: %w", destination.Name, err)
 	}
 	return nil
 }
@@ -904,6 +1164,349 @@ func (a *FlowableActivity) CreateTablesFromExisting(ctx context.Context, req *pr
 	return nil, fmt.Errorf("create tables from existing is only supported on snowflake and bigquery")
 }
 
+// SyncSequences advances the sequences owned by the destination tables of a Postgres to Postgres mirror
+// past the last values of the sequences owned by the source tables, so writes can move to the destination.
+func (a *FlowableActivity) SyncSequences(ctx context.Context, config *protos.FlowConnectionConfigs) error {
+	if config.Source.Type != protos.DBType_POSTGRES || config.Destination.Type != protos.DBType_POSTGRES {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	srcConn, err := connpostgres.NewPostgresConnector(ctx, config.Source.GetPostgresConfig())
+	if err != nil {
+		return fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(srcConn)
+
+	dstConn, err := connpostgres.NewPostgresConnector(ctx, config.Destination.GetPostgresConfig())
+	if err != nil {
+		return fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	srcTables := make([]string, 0, len(config.TableMappings))
+	for _, tableMapping := range config.TableMappings {
+		srcTables = append(srcTables, tableMapping.SourceTableIdentifier)
+	}
+	srcSequenceValues, err := srcConn.GetOwnedSequenceValues(srcTables)
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to get sequence values from source: %w", err)
+	}
+
+	dstSequenceValues := make(map[string][]connpostgres.SequenceValue, len(srcSequenceValues))
+	for _, tableMapping := range config.TableMappings {
+		if values, ok := srcSequenceValues[tableMapping.SourceTableIdentifier]; ok {
+			dstSequenceValues[tableMapping.DestinationTableIdentifier] = values
+		}
+	}
+
+	margin := config.SequenceSyncMargin
+	if margin <= 0 {
+		margin = connpostgres.DefaultSequenceSyncMargin
+	}
+	err = dstConn.SyncSequenceValues(dstSequenceValues, margin)
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return fmt.Errorf("failed to sync sequence values to destination: %w", err)
+	}
+	return nil
+}
+
+// ReplicateTableMetadata creates the secondary indexes, constraints, defaults and column comments of the
+// source tables of a Postgres to Postgres mirror on the destination tables.
+// Either only indexes and unique constraints are created, or everything else.
+func (a *FlowableActivity) ReplicateTableMetadata(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	indexes bool,
+) error {
+	if config.Source.Type != protos.DBType_POSTGRES || config.Destination.Type != protos.DBType_POSTGRES {
+		return nil
+	}
+
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	srcConn, err := connpostgres.NewPostgresConnector(ctx, config.Source.GetPostgresConfig())
+	if err != nil {
+		return fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(srcConn)
+
+	dstConn, err := connpostgres.NewPostgresConnector(ctx, config.Destination.GetPostgresConfig())
+	if err != nil {
+		return fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	for _, tableMapping := range config.TableMappings {
+		ddl, err := srcConn.GetTableMetadataDDL(tableMapping.SourceTableIdentifier,
+			tableMapping.DestinationTableIdentifier, tableMapping.Exclude,
+			config.HistoryMode || config.SoftDelete || config.SourceColName != "")
+		if err != nil {
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+			return fmt.Errorf("failed to get metadata of table %s: %w", tableMapping.SourceTableIdentifier, err)
+		}
+
+		stmts := ddl.Constraints
+		if indexes {
+			stmts = ddl.Indexes
+		}
+		err = dstConn.ExecuteTableMetadataDDL(tableMapping.DestinationTableIdentifier, stmts)
+		if err != nil {
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+			return fmt.Errorf("failed to replicate metadata of table %s: %w", tableMapping.SourceTableIdentifier, err)
+		}
+	}
+	return nil
+}
+
+// RequestIncrementalSnapshot starts incremental snapshots of all tables of a mirror,
+// they are read in chunks by the sync flows while changes are pulled.
+func (a *FlowableActivity) RequestIncrementalSnapshot(ctx context.Context, config *protos.FlowConnectionConfigs) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	srcTables := make([]string, 0, len(config.TableMappings))
+	for _, tableMapping := range config.TableMappings {
+		tableSchema, ok := config.TableNameSchemaMapping[tableMapping.DestinationTableIdentifier]
+		if ok && len(tableSchema.PrimaryKeyColumns) == 0 {
+			err := fmt.Errorf("table %s has no primary key, it can't be snapshotted incrementally",
+				tableMapping.SourceTableIdentifier)
+			a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+			return err
+		}
+		srcTables = append(srcTables, tableMapping.SourceTableIdentifier)
+	}
+
+	err := monitoring.RequestIncrementalSnapshot(ctx, a.CatalogPool, config.FlowJobName, srcTables)
+	if err != nil {
+		a.Alerter.LogFlowError(ctx, config.FlowJobName, err)
+		return err
+	}
+	return nil
+}
+
+// rows compared at a time when validating a table, source tables are split into ranges of this many rows.
+const validationRowsPerRange = 100000
+
+// StartMirrorValidation records the start of a validation of the tables of a mirror.
+func (a *FlowableActivity) StartMirrorValidation(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	validationID string,
+	numTables int,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	return monitoring.InitializeMirrorValidation(ctx, a.CatalogPool, config.FlowJobName, validationID, numTables)
+}
+
+// FinishMirrorValidation records the end of a validation of the tables of a mirror.
+func (a *FlowableActivity) FinishMirrorValidation(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	validationID string,
+	errorMessage string,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	return monitoring.FinishMirrorValidation(ctx, a.CatalogPool, validationID, errorMessage)
+}
+
+// ValidateTable compares the rows of a table of a mirror between source and destination, range by range.
+// Ranges of the first column of the primary key are taken from the source table, ranges whose row counts
+// or checksums differ are recorded in the catalog. Rows changed while a range is compared may make it differ.
+func (a *FlowableActivity) ValidateTable(
+	ctx context.Context,
+	config *protos.FlowConnectionConfigs,
+	validationID string,
+	mapping *protos.TableMapping,
+) error {
+	ctx = context.WithValue(ctx, shared.FlowNameKey, config.FlowJobName)
+	srcName := mapping.SourceTableIdentifier
+	dstName := mapping.DestinationTableIdentifier
+	tableSchema, ok := config.TableNameSchemaMapping[dstName]
+	if !ok {
+		return fmt.Errorf("schema of table %s not found", dstName)
+	}
+	if len(tableSchema.PrimaryKeyColumns) == 0 {
+		return fmt.Errorf("table %s has no primary key, it can't be validated", srcName)
+	}
+
+	srcConn, err := connectors.GetValidationConnector(ctx, config.Source)
+	if err != nil {
+		return fmt.Errorf("failed to get source connector: %w", err)
+	}
+	defer connectors.CloseConnector(srcConn)
+
+	dstConn, err := connectors.GetValidationConnector(ctx, config.Destination)
+	if err != nil {
+		return fmt.Errorf("failed to get destination connector: %w", err)
+	}
+	defer connectors.CloseConnector(dstConn)
+
+	// the source column is only on the destination, its rows from other sources are left out instead
+	keyColumns := slices.DeleteFunc(slices.Clone(tableSchema.PrimaryKeyColumns), func(name string) bool {
+		return name == config.SourceColName
+	})
+	var columns, jsonColumns []string
+	var rangeColumn string
+	var rangeAsText bool
+	utils.IterColumns(tableSchema, func(name, kind string) {
+		if config.SourceColName != "" && name == config.SourceColName {
+			return
+		}
+		if name == keyColumns[0] {
+			// ranges are taken from partitions on integer and timestamp keys, and on the text of string and
+			// uuid keys, which destinations store as strings. Tables keyed otherwise are compared as a whole
+			switch qvalue.QValueKind(kind) {
+			case qvalue.QValueKindInt16, qvalue.QValueKindInt32, qvalue.QValueKindInt64,
+				qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ:
+				rangeColumn = name
+			case qvalue.QValueKindString, qvalue.QValueKindUUID:
+				if config.Source.Type == protos.DBType_POSTGRES {
+					rangeColumn = name
+					rangeAsText = true
+				}
+			}
+		}
+		if qvalue.QValueKind(kind) == qvalue.QValueKindJSON {
+			jsonColumns = append(jsonColumns, name)
+		}
+		if !slices.Contains(keyColumns, name) {
+			columns = append(columns, name)
+		}
+	})
+	slices.Sort(columns)
+
+	// each range ends where the next starts, so rows between partitions of the source are compared too
+	var rangeEnds []any
+	if rangeAsText {
+		srcPgConn, err := connpostgres.NewPostgresConnector(ctx, config.Source.GetPostgresConfig())
+		if err != nil {
+			return fmt.Errorf("failed to get source connector: %w", err)
+		}
+		defer connectors.CloseConnector(srcPgConn)
+
+		textRangeEnds, err := srcPgConn.GetTextKeyRangeEnds(srcName, rangeColumn, validationRowsPerRange)
+		if err != nil {
+			return fmt.Errorf("failed to get ranges of table %s: %w", srcName, err)
+		}
+		for _, rangeEnd := range textRangeEnds {
+			rangeEnds = append(rangeEnds, rangeEnd)
+		}
+	} else if rangeColumn != "" {
+		partitionConn, err := connectors.GetQRepPullConnector(ctx, config.Source)
+		if err != nil {
+			return fmt.Errorf("failed to get source connector: %w", err)
+		}
+		defer connectors.CloseConnector(partitionConn)
+
+		partitions, err := partitionConn.GetQRepPartitions(&protos.QRepConfig{
+			FlowJobName:         config.FlowJobName,
+			SourcePeer:          config.Source,
+			WatermarkTable:      srcName,
+			WatermarkColumn:     rangeColumn,
+			NumRowsPerPartition: validationRowsPerRange,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to get ranges of table %s: %w", srcName, err)
+		}
+		for _, partition := range partitions {
+			switch r := partition.Range.GetRange().(type) {
+			case *protos.PartitionRange_IntRange:
+				rangeEnds = append(rangeEnds, r.IntRange.End)
+			case *protos.PartitionRange_TimestampRange:
+				rangeEnds = append(rangeEnds, r.TimestampRange.End.AsTime())
+			}
+		}
+		// the last range is unbounded, to compare rows inserted since
+		if len(rangeEnds) > 0 {
+			rangeEnds = rangeEnds[:len(rangeEnds)-1]
+		}
+	}
+
+	err = monitoring.ClearMirrorValidationTable(ctx, a.CatalogPool, validationID, srcName)
+	if err != nil {
+		return err
+	}
+
+	numRanges := len(rangeEnds) + 1
+	shutdown := utils.HeartbeatRoutine(ctx, 2*time.Minute, func() string {
+		return fmt.Sprintf("validating %d ranges of table %s", numRanges, srcName)
+	})
+	defer shutdown()
+
+	for rangeNum := 0; rangeNum < numRanges; rangeNum++ {
+		srcReq := &model.TableRangeRequest{
+			TableIdentifier: srcName,
+			KeyColumns:      keyColumns,
+			Columns:         columns,
+			JSONColumns:     jsonColumns,
+			RangeColumn:     rangeColumn,
+			RangeAsText:     rangeAsText,
+		}
+		if rangeNum > 0 {
+			srcReq.Start = rangeEnds[rangeNum-1]
+		}
+		if rangeNum < len(rangeEnds) {
+			srcReq.End = rangeEnds[rangeNum]
+		}
+		dstReq := *srcReq
+		dstReq.TableIdentifier = dstName
+		if config.SoftDelete {
+			dstReq.SoftDeleteColName = config.SoftDeleteColName
+		}
+		if config.HistoryMode {
+			dstReq.CurrentColName = shared.HistoryIsCurrentColName
+		}
+		if config.SourceColName != "" {
+			dstReq.SourceColName = config.SourceColName
+			dstReq.SourceColValue = config.Source.Name
+		}
+
+		srcChecksum, err := srcConn.ChecksumTableRange(srcReq)
+		if err != nil {
+			return fmt.Errorf("failed to checksum source table %s: %w", srcName, err)
+		}
+		dstChecksum, err := dstConn.ChecksumTableRange(&dstReq)
+		if err != nil {
+			return fmt.Errorf("failed to checksum destination table %s: %w", dstName, err)
+		}
+
+		keysMatch, rowsMatch := srcChecksum.Matches(dstChecksum)
+		if !rowsMatch {
+			slog.WarnContext(ctx, fmt.Sprintf("range %d of table %s differs between source and destination",
+				rangeNum+1, srcName),
+				slog.Int64("sourceRows", srcChecksum.NumRows), slog.Int64("destinationRows", dstChecksum.NumRows))
+			err := monitoring.AddMirrorValidationMismatch(ctx, a.CatalogPool, validationID,
+				&monitoring.MirrorValidationRange{
+					TableName:       srcName,
+					RangeStart:      validationRangeBound(srcReq.Start),
+					RangeEnd:        validationRangeBound(srcReq.End),
+					SourceRows:      srcChecksum.NumRows,
+					DestinationRows: dstChecksum.NumRows,
+					KeysMatch:       keysMatch,
+					RowsMatch:       rowsMatch,
+				})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return monitoring.SetMirrorValidationRanges(ctx, a.CatalogPool, validationID, srcName, numRanges)
+}
+
+func validationRangeBound(bound any) *string {
+	var s string
+	switch b := bound.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		s = b.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(b)
+	}
+	return &s
+}
+
 // ReplicateXminPartition replicates a XminPartition from the source to the destination.
 func (a *FlowableActivity) ReplicateXminPartition(ctx context.Context,
 	config *protos.QRepConfig,
@@ -20,6 +20,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/encoding/protojson"
 
 	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
@@ -37,6 +38,28 @@ type APIServerParams struct {
 	TemporalKey       string
 }
 
+// sseMarshaler writes streamed responses as server-sent events,
+// it is used by the gateway for requests that accept text/event-stream.
+type sseMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *sseMarshaler) ContentType(_ interface{}) string {
+	return "text/event-stream"
+}
+
+func (m *sseMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("data: "), data...), nil
+}
+
+func (m *sseMarshaler) Delimiter() []byte {
+	return []byte("\n\n")
+}
+
 // setupGRPCGatewayServer sets up the grpc-gateway mux
 func setupGRPCGatewayServer(args *APIServerParams) (*http.Server, error) {
 	conn, err := grpc.DialContext(
@@ -49,7 +72,12 @@ func setupGRPCGatewayServer(args *APIServerParams) (*http.Server, error) {
 		return nil, fmt.Errorf("unable to dial grpc server: %w", err)
 	}
 
-	gwmux := runtime.NewServeMux()
+	gwmux := runtime.NewServeMux(runtime.WithMarshalerOption("text/event-stream", &sseMarshaler{
+		Marshaler: &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}))
 	err = protos.RegisterFlowServiceHandler(context.Background(), gwmux, conn)
 	if err != nil {
 		return nil, fmt.Errorf("unable to register gateway: %w", err)
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"golang.org/x/exp/maps"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+type ApplyOptions struct {
+	File              string
+	FlowServerAddress string
+	Plan              bool
+}
+
+// mirrorDefinitions is the desired state read from a mirrors file.
+// Mirrors refer to peers by name, the peer configs are filled in from the peers
+// defined in the same file or, failing that, from the catalog.
+type mirrorDefinitions struct {
+	Peers       []*protos.Peer
+	CDCMirrors  []*protos.FlowConnectionConfigs
+	QRepMirrors []*protos.QRepConfig
+}
+
+type mirrorsFile struct {
+	Peers       []map[string]interface{} `yaml:"peers"`
+	CDCMirrors  []map[string]interface{} `yaml:"cdc_mirrors"`
+	QRepMirrors []map[string]interface{} `yaml:"qrep_mirrors"`
+}
+
+type applyChange struct {
+	// one of + (create), - (drop) or ~ (differs from the catalog but is left as is)
+	action string
+	kind   string
+	name   string
+	note   string
+	apply  func(context.Context, protos.FlowServiceClient) error
+}
+
+func (c *applyChange) String() string {
+	if c.note != "" {
+		return fmt.Sprintf("%s %s %s (%s)", c.action, c.kind, c.name, c.note)
+	}
+	return fmt.Sprintf("%s %s %s", c.action, c.kind, c.name)
+}
+
+// catalogState is the state of the catalog, as listed through the flow API.
+type catalogState struct {
+	peers map[string]*protos.Peer
+	// peers used by a mirror in the catalog, including as a fan-out destination
+	usedPeers map[string]struct{}
+	mirrors   map[string]*catalogMirror
+}
+
+// catalogMirror is a mirror in the catalog, with the config it was created with.
+type catalogMirror struct {
+	mirrorType protos.MirrorType
+	// configs are only fetched for mirrors that are also defined, one of them is set for the type of mirror
+	cdcConfig  *protos.FlowConnectionConfigs
+	qrepConfig *protos.QRepConfig
+}
+
+// ApplyMain makes the peers and mirrors in the catalog match a mirrors file.
+// Peers missing from the catalog are created and peers neither defined in the file nor used by a mirror
+// are dropped, peers whose config differs are reported but not changed as peers cannot be updated in place.
+// Mirrors missing from the catalog are created, existing mirrors are left untouched and reported
+// if their definition differs from the catalog.
+func ApplyMain(ctx context.Context, opts *ApplyOptions) error {
+	defs, err := readMirrorDefinitions(opts.File)
+	if err != nil {
+		return err
+	}
+
+	flowClient, closeConn, err := dialFlowServer(ctx, opts.FlowServerAddress)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	catalog, err := getCatalogState(ctx, flowClient, defs)
+	if err != nil {
+		return err
+	}
+
+	changes, err := planMirrorDefinitions(defs, catalog)
+	if err != nil {
+		return err
+	}
+
+	numChanges := 0
+	for _, change := range changes {
+		fmt.Println(change.String())
+		if change.apply != nil {
+			numChanges++
+		}
+	}
+	if numChanges == 0 {
+		fmt.Println("No changes, the catalog matches " + opts.File)
+		return nil
+	}
+	if opts.Plan {
+		fmt.Printf("Plan: %d changes to apply\n", numChanges)
+		return nil
+	}
+
+	for _, change := range changes {
+		if change.apply == nil {
+			continue
+		}
+		if err := change.apply(ctx, flowClient); err != nil {
+			return fmt.Errorf("unable to apply %s: %w", change.String(), err)
+		}
+		fmt.Println("applied " + change.String())
+	}
+	return nil
+}
+
+func readMirrorDefinitions(path string) (*mirrorDefinitions, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mirrors file: %w", err)
+	}
+
+	var file mirrorsFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse mirrors file %s: %w", path, err)
+	}
+
+	defs := &mirrorDefinitions{}
+	for i, peerDef := range file.Peers {
+		peer := &protos.Peer{}
+		if err := unmarshalDefinition(peerDef, peer); err != nil {
+			return nil, fmt.Errorf("invalid peer #%d in %s: %w", i+1, path, err)
+		}
+		defs.Peers = append(defs.Peers, peer)
+	}
+	for i, mirrorDef := range file.CDCMirrors {
+		cfg := &protos.FlowConnectionConfigs{}
+		if err := unmarshalDefinition(mirrorDef, cfg); err != nil {
+			return nil, fmt.Errorf("invalid cdc mirror #%d in %s: %w", i+1, path, err)
+		}
+		defs.CDCMirrors = append(defs.CDCMirrors, cfg)
+	}
+	for i, mirrorDef := range file.QRepMirrors {
+		cfg := &protos.QRepConfig{}
+		if err := unmarshalDefinition(mirrorDef, cfg); err != nil {
+			return nil, fmt.Errorf("invalid qrep mirror #%d in %s: %w", i+1, path, err)
+		}
+		defs.QRepMirrors = append(defs.QRepMirrors, cfg)
+	}
+	return defs, nil
+}
+
+// unmarshalDefinition goes through JSON so definitions use the protobuf JSON mapping,
+// accepting both snake_case and camelCase field names and enums by name.
+func unmarshalDefinition(def map[string]interface{}, msg proto.Message) error {
+	jsonDef, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	return protojson.Unmarshal(jsonDef, msg)
+}
+
+// getCatalogState lists the peers and mirrors in the catalog, with the configs of the mirrors
+// that are also defined to compare them with their definitions.
+func getCatalogState(
+	ctx context.Context,
+	flowClient protos.FlowServiceClient,
+	defs *mirrorDefinitions,
+) (*catalogState, error) {
+	catalog := &catalogState{
+		peers:     make(map[string]*protos.Peer),
+		usedPeers: make(map[string]struct{}),
+		mirrors:   make(map[string]*catalogMirror),
+	}
+
+	peersRes, err := flowClient.ListPeers(ctx, &protos.ListPeersRequest{IncludeConfigs: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list peers: %w", err)
+	}
+	for _, item := range peersRes.Peers {
+		catalog.peers[item.Name] = item.Peer
+		if item.NumSourceMirrors > 0 || item.NumDestinationMirrors > 0 {
+			catalog.usedPeers[item.Name] = struct{}{}
+		}
+	}
+
+	mirrorsReq := &protos.ListMirrorsRequest{}
+	for {
+		mirrorsRes, err := flowClient.ListMirrors(ctx, mirrorsReq)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list mirrors: %w", err)
+		}
+		for _, item := range mirrorsRes.Mirrors {
+			catalog.mirrors[item.FlowJobName] = &catalogMirror{mirrorType: item.MirrorType}
+		}
+		if mirrorsRes.NextPageToken == "" {
+			break
+		}
+		mirrorsReq.PageToken = mirrorsRes.NextPageToken
+	}
+
+	definedMirrorNames := make([]string, 0, len(defs.CDCMirrors)+len(defs.QRepMirrors))
+	for _, cfg := range defs.CDCMirrors {
+		definedMirrorNames = append(definedMirrorNames, cfg.FlowJobName)
+	}
+	for _, cfg := range defs.QRepMirrors {
+		definedMirrorNames = append(definedMirrorNames, cfg.FlowJobName)
+	}
+	for _, name := range definedMirrorNames {
+		mirror, ok := catalog.mirrors[name]
+		if !ok {
+			continue
+		}
+		status, err := getMirrorStatus(ctx, flowClient, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get config of mirror %s: %w", name, err)
+		}
+		switch status := status.Status.(type) {
+		case *protos.MirrorStatusResponse_CdcStatus:
+			mirror.cdcConfig = status.CdcStatus.Config
+		case *protos.MirrorStatusResponse_QrepStatus:
+			mirror.qrepConfig = status.QrepStatus.Config
+		}
+	}
+	return catalog, nil
+}
+
+// definitionDrift returns the fields set in a definition whose value differs from the catalog.
+// Peers are compared by name, their configs are compared with the peers of the catalog.
+func definitionDrift(def proto.Message, catalogConfig proto.Message) []string {
+	var fields []string
+	catalogMsg := catalogConfig.ProtoReflect()
+	def.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		defField := catalogMsg.New()
+		defField.Set(fd, namedPeersOnly(defField, fd, v))
+		catalogField := catalogMsg.New()
+		if catalogMsg.Has(fd) {
+			catalogField.Set(fd, namedPeersOnly(catalogField, fd, catalogMsg.Get(fd)))
+		}
+		if !proto.Equal(defField.Interface(), catalogField.Interface()) {
+			fields = append(fields, fd.TextName())
+		}
+		return true
+	})
+	slices.Sort(fields)
+	return fields
+}
+
+// namedPeersOnly replaces the peers in the value of a field of a mirror config with their names.
+func namedPeersOnly(msg protoreflect.Message, fd protoreflect.FieldDescriptor, v protoreflect.Value) protoreflect.Value {
+	if fd.Message() == nil || fd.Message().FullName() != (*protos.Peer)(nil).ProtoReflect().Descriptor().FullName() {
+		return v
+	}
+	peerName := func(v protoreflect.Value) protoreflect.Value {
+		peer := v.Message().Interface().(*protos.Peer)
+		return protoreflect.ValueOfMessage((&protos.Peer{Name: peer.Name}).ProtoReflect())
+	}
+	if fd.IsList() {
+		peers := msg.NewField(fd).List()
+		for i := 0; i < v.List().Len(); i++ {
+			peers.Append(peerName(v.List().Get(i)))
+		}
+		return protoreflect.ValueOfList(peers)
+	}
+	return peerName(v)
+}
+
+// driftChange reports an existing mirror whose definition differs from the catalog.
+func driftChange(kind string, name string, drift []string) *applyChange {
+	return &applyChange{
+		action: "~",
+		kind:   kind,
+		name:   name,
+		note: fmt.Sprintf("differs from the catalog in %s, mirrors cannot be updated in place",
+			strings.Join(drift, ", ")),
+	}
+}
+
+// planMirrorDefinitions diffs the definitions against the catalog, filling in the peers of the mirrors.
+// Peers are created before the mirrors that use them and dropped after.
+func planMirrorDefinitions(
+	defs *mirrorDefinitions,
+	catalog *catalogState,
+) ([]*applyChange, error) {
+	var changes []*applyChange
+
+	peers := make(map[string]*protos.Peer, len(defs.Peers))
+	for _, peer := range defs.Peers {
+		peer := peer
+		if peer.Name == "" {
+			return nil, errors.New("peer without a name in mirrors file")
+		}
+		if _, ok := peers[peer.Name]; ok {
+			return nil, fmt.Errorf("peer %s is defined more than once", peer.Name)
+		}
+		peers[peer.Name] = peer
+
+		catalogPeer, ok := catalog.peers[peer.Name]
+		switch {
+		case !ok:
+			changes = append(changes, &applyChange{
+				action: "+",
+				kind:   "peer",
+				name:   peer.Name,
+				apply: func(ctx context.Context, flowClient protos.FlowServiceClient) error {
+					res, err := flowClient.CreatePeer(ctx, &protos.CreatePeerRequest{Peer: peer})
+					if err != nil {
+						return err
+					}
+					if res.Status != protos.CreatePeerStatus_CREATED {
+						return errors.New(res.Message)
+					}
+					return nil
+				},
+			})
+		case catalogPeer.Config != nil && !proto.Equal(catalogPeer, peer):
+			changes = append(changes, &applyChange{
+				action: "~",
+				kind:   "peer",
+				name:   peer.Name,
+				note:   "config differs from the catalog, peers cannot be updated in place",
+			})
+		}
+	}
+	// peers only in the catalog are kept around while a defined mirror uses them
+	usedPeers := make(map[string]struct{})
+	resolvePeer := func(mirrorName string, peer *protos.Peer) (*protos.Peer, error) {
+		if peer == nil || peer.Name == "" {
+			return nil, fmt.Errorf("mirror %s is missing a peer", mirrorName)
+		}
+		usedPeers[peer.Name] = struct{}{}
+		if definedPeer, ok := peers[peer.Name]; ok {
+			return definedPeer, nil
+		}
+		if catalogPeer, ok := catalog.peers[peer.Name]; ok {
+			return catalogPeer, nil
+		}
+		return nil, fmt.Errorf("peer %s of mirror %s is not defined", peer.Name, mirrorName)
+	}
+
+	mirrorNames := make(map[string]struct{}, len(defs.CDCMirrors)+len(defs.QRepMirrors))
+	addMirrorName := func(name string) error {
+		if name == "" {
+			return errors.New("mirror without a flow_job_name in mirrors file")
+		}
+		if _, ok := mirrorNames[name]; ok {
+			return fmt.Errorf("mirror %s is defined more than once", name)
+		}
+		mirrorNames[name] = struct{}{}
+		return nil
+	}
+
+	for _, cfg := range defs.CDCMirrors {
+		cfg := cfg
+		if err := addMirrorName(cfg.FlowJobName); err != nil {
+			return nil, err
+		}
+		var err error
+		if cfg.Source, err = resolvePeer(cfg.FlowJobName, cfg.Source); err != nil {
+			return nil, err
+		}
+		if cfg.Destination, err = resolvePeer(cfg.FlowJobName, cfg.Destination); err != nil {
+			return nil, err
+		}
+		for i, destination := range cfg.FanOutDestinations {
+			if cfg.FanOutDestinations[i], err = resolvePeer(cfg.FlowJobName, destination); err != nil {
+				return nil, err
+			}
+		}
+		if mirror, ok := catalog.mirrors[cfg.FlowJobName]; ok {
+			if mirror.mirrorType != protos.MirrorType_CDC {
+				changes = append(changes, driftChange("cdc mirror", cfg.FlowJobName, []string{"mirror type"}))
+			} else if mirror.cdcConfig != nil {
+				if drift := definitionDrift(cfg, mirror.cdcConfig); len(drift) > 0 {
+					changes = append(changes, driftChange("cdc mirror", cfg.FlowJobName, drift))
+				}
+			}
+			continue
+		}
+		changes = append(changes, &applyChange{
+			action: "+",
+			kind:   "cdc mirror",
+			name:   cfg.FlowJobName,
+			apply: func(ctx context.Context, flowClient protos.FlowServiceClient) error {
+				_, err := flowClient.CreateCDCFlow(ctx, &protos.CreateCDCFlowRequest{
+					ConnectionConfigs:  cfg,
+					CreateCatalogEntry: true,
+				})
+				return err
+			},
+		})
+	}
+
+	for _, cfg := range defs.QRepMirrors {
+		cfg := cfg
+		if err := addMirrorName(cfg.FlowJobName); err != nil {
+			return nil, err
+		}
+		var err error
+		if cfg.SourcePeer, err = resolvePeer(cfg.FlowJobName, cfg.SourcePeer); err != nil {
+			return nil, err
+		}
+		if cfg.DestinationPeer, err = resolvePeer(cfg.FlowJobName, cfg.DestinationPeer); err != nil {
+			return nil, err
+		}
+		if mirror, ok := catalog.mirrors[cfg.FlowJobName]; ok {
+			if mirror.mirrorType != protos.MirrorType_QREP {
+				changes = append(changes, driftChange("qrep mirror", cfg.FlowJobName, []string{"mirror type"}))
+			} else if mirror.qrepConfig != nil {
+				if drift := definitionDrift(cfg, mirror.qrepConfig); len(drift) > 0 {
+					changes = append(changes, driftChange("qrep mirror", cfg.FlowJobName, drift))
+				}
+			}
+			continue
+		}
+		changes = append(changes, &applyChange{
+			action: "+",
+			kind:   "qrep mirror",
+			name:   cfg.FlowJobName,
+			apply: func(ctx context.Context, flowClient protos.FlowServiceClient) error {
+				_, err := flowClient.CreateQRepFlow(ctx, &protos.CreateQRepFlowRequest{
+					QrepConfig:         cfg,
+					CreateCatalogEntry: true,
+				})
+				return err
+			},
+		})
+	}
+
+	// mirrors are never dropped, so the peers of mirrors in the catalog stay in use
+	for name := range catalog.usedPeers {
+		usedPeers[name] = struct{}{}
+	}
+	catalogPeerNames := maps.Keys(catalog.peers)
+	slices.Sort(catalogPeerNames)
+	for _, name := range catalogPeerNames {
+		name := name
+		_, defined := peers[name]
+		_, used := usedPeers[name]
+		if !defined && !used {
+			changes = append(changes, &applyChange{
+				action: "-",
+				kind:   "peer",
+				name:   name,
+				apply: func(ctx context.Context, flowClient protos.FlowServiceClient) error {
+					res, err := flowClient.DropPeer(ctx, &protos.DropPeerRequest{PeerName: name})
+					if err != nil {
+						return err
+					}
+					if !res.Ok {
+						return errors.New(res.ErrorMessage)
+					}
+					return nil
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/stretchr/testify/require"
+)
+
+func planStrings(t *testing.T, changes []*applyChange) []string {
+	t.Helper()
+
+	planned := make([]string, 0, len(changes))
+	for _, change := range changes {
+		planned = append(planned, change.String())
+	}
+	return planned
+}
+
+func testPeer(name string, host string) *protos.Peer {
+	return &protos.Peer{
+		Name:   name,
+		Type:   protos.DBType_POSTGRES,
+		Config: &protos.Peer_PostgresConfig{PostgresConfig: &protos.PostgresConfig{Host: host}},
+	}
+}
+
+func TestPlanMirrorDefinitionsCreatesMissing(t *testing.T) {
+	defs := &mirrorDefinitions{
+		Peers: []*protos.Peer{testPeer("source", "source-host"), testPeer("destination", "destination-host")},
+		CDCMirrors: []*protos.FlowConnectionConfigs{{
+			FlowJobName: "users",
+			Source:      &protos.Peer{Name: "source"},
+			Destination: &protos.Peer{Name: "destination"},
+		}},
+		QRepMirrors: []*protos.QRepConfig{{
+			FlowJobName:     "events",
+			SourcePeer:      &protos.Peer{Name: "source"},
+			DestinationPeer: &protos.Peer{Name: "destination"},
+		}},
+	}
+
+	changes, err := planMirrorDefinitions(defs, &catalogState{
+		peers: map[string]*protos.Peer{"source": testPeer("source", "source-host")},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"+ peer destination", "+ cdc mirror users", "+ qrep mirror events"},
+		planStrings(t, changes))
+	// mirrors are created with the configs of their peers
+	require.Equal(t, "destination-host", defs.CDCMirrors[0].Destination.GetPostgresConfig().Host)
+}
+
+func TestPlanMirrorDefinitionsKeepsPeersOfCatalogMirrors(t *testing.T) {
+	// the mirror is only in the catalog, its peers aren't in the file either
+	catalog := &catalogState{
+		peers: map[string]*protos.Peer{
+			"source":      testPeer("source", "source-host"),
+			"destination": testPeer("destination", "destination-host"),
+			"fan_out":     testPeer("fan_out", "fan-out-host"),
+			"unused":      testPeer("unused", "unused-host"),
+		},
+		usedPeers: map[string]struct{}{"source": {}, "destination": {}, "fan_out": {}},
+		mirrors:   map[string]*catalogMirror{"users": {mirrorType: protos.MirrorType_CDC}},
+	}
+
+	changes, err := planMirrorDefinitions(&mirrorDefinitions{}, catalog)
+	require.NoError(t, err)
+	require.Equal(t, []string{"- peer unused"}, planStrings(t, changes))
+}
+
+func TestPlanMirrorDefinitionsReportsDrift(t *testing.T) {
+	catalogPeers := map[string]*protos.Peer{
+		"source":      testPeer("source", "source-host"),
+		"destination": testPeer("destination", "destination-host"),
+		"other":       testPeer("other", "other-host"),
+	}
+	catalogMirrors := map[string]*catalogMirror{
+		"users": {
+			mirrorType: protos.MirrorType_CDC,
+			cdcConfig: &protos.FlowConnectionConfigs{
+				FlowJobName:  "users",
+				Source:       testPeer("source", "source-host"),
+				Destination:  testPeer("destination", "destination-host"),
+				MaxBatchSize: 1_000_000,
+				TableMappings: []*protos.TableMapping{{
+					SourceTableIdentifier:      "public.users",
+					DestinationTableIdentifier: "public.users",
+				}},
+			},
+		},
+		"orders": {
+			mirrorType: protos.MirrorType_CDC,
+			cdcConfig: &protos.FlowConnectionConfigs{
+				FlowJobName: "orders",
+				Source:      testPeer("source", "source-host"),
+				Destination: testPeer("destination", "destination-host"),
+			},
+		},
+		"events": {
+			mirrorType: protos.MirrorType_CDC,
+			cdcConfig:  &protos.FlowConnectionConfigs{FlowJobName: "events"},
+		},
+	}
+	defs := &mirrorDefinitions{
+		CDCMirrors: []*protos.FlowConnectionConfigs{
+			{
+				FlowJobName: "users",
+				Source:      &protos.Peer{Name: "source"},
+				Destination: &protos.Peer{Name: "other"},
+				TableMappings: []*protos.TableMapping{{
+					SourceTableIdentifier:      "public.users",
+					DestinationTableIdentifier: "public.users_v2",
+				}},
+			},
+			// fields left to their defaults aren't compared
+			{
+				FlowJobName: "orders",
+				Source:      &protos.Peer{Name: "source"},
+				Destination: &protos.Peer{Name: "destination"},
+			},
+		},
+		QRepMirrors: []*protos.QRepConfig{{
+			FlowJobName:     "events",
+			SourcePeer:      &protos.Peer{Name: "source"},
+			DestinationPeer: &protos.Peer{Name: "destination"},
+		}},
+	}
+
+	changes, err := planMirrorDefinitions(defs, &catalogState{
+		peers:     catalogPeers,
+		usedPeers: map[string]struct{}{"source": {}, "destination": {}},
+		mirrors:   catalogMirrors,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"~ cdc mirror users (differs from the catalog in destination, table_mappings, mirrors cannot be updated in place)",
+		"~ qrep mirror events (differs from the catalog in mirror type, mirrors cannot be updated in place)",
+	}, planStrings(t, changes))
+	for _, change := range changes {
+		require.Nil(t, change.apply)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/urfave/cli/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// clientCommands are the subcommands that manage peers and mirrors through the flow API server.
+func clientCommands(flowServerAddressFlag cli.Flag) []*cli.Command {
+	outputFlag := &cli.StringFlag{
+		Name:       "output",
+		Aliases:    []string{"o"},
+		Value:      "table",
+		Usage:      "Output format, table or json",
+		Persistent: true,
+	}
+
+	return []*cli.Command{
+		{
+			Name:  "mirrors",
+			Usage: "Manage mirrors",
+			Flags: []cli.Flag{flowServerAddressFlag, outputFlag},
+			Commands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "List mirrors",
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "source", Usage: "Only list mirrors from this peer"},
+						&cli.StringFlag{Name: "destination", Usage: "Only list mirrors to this peer"},
+						&cli.StringFlag{Name: "type", Usage: "Only list mirrors of this type, cdc or qrep"},
+						&cli.StringFlag{Name: "state", Usage: "Only list mirrors in this state, like running or paused"},
+						&cli.IntFlag{Name: "page-size", Usage: "Number of mirrors to list, 100 by default"},
+						&cli.StringFlag{Name: "page-token", Usage: "Continue listing after a previous page"},
+					},
+					Action: listMirrors,
+				},
+				{
+					Name:      "status",
+					Usage:     "Show the status of a mirror",
+					ArgsUsage: "<mirror>",
+					Action:    mirrorStatus,
+				},
+				{
+					Name:      "pause",
+					Usage:     "Pause a CDC mirror",
+					ArgsUsage: "<mirror>",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						return changeMirrorState(ctx, cmd, protos.FlowStatus_STATUS_PAUSED)
+					},
+				},
+				{
+					Name:      "resume",
+					Usage:     "Resume a paused CDC mirror",
+					ArgsUsage: "<mirror>",
+					Action: func(ctx context.Context, cmd *cli.Command) error {
+						return changeMirrorState(ctx, cmd, protos.FlowStatus_STATUS_RUNNING)
+					},
+				},
+				{
+					Name:      "drop",
+					Usage:     "Drop a mirror and clean up its source and destination",
+					ArgsUsage: "<mirror>",
+					Action:    dropMirror,
+				},
+			},
+		},
+		{
+			Name:  "peers",
+			Usage: "Manage peers",
+			Flags: []cli.Flag{flowServerAddressFlag, outputFlag},
+			Commands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "List peers",
+					Flags: []cli.Flag{
+						&cli.StringFlag{Name: "type", Usage: "Only list peers of this type, like postgres or snowflake"},
+					},
+					Action: listPeers,
+				},
+				{
+					Name:      "validate",
+					Usage:     "Check that a peer can be connected to",
+					ArgsUsage: "<peer>",
+					Action:    validatePeer,
+				},
+			},
+		},
+		{
+			Name:      "slots",
+			Usage:     "Show the replication slots of a Postgres peer",
+			ArgsUsage: "<peer>",
+			Flags:     []cli.Flag{flowServerAddressFlag, outputFlag},
+			Action:    slotInfo,
+		},
+	}
+}
+
+func dialFlowServer(ctx context.Context, address string) (protos.FlowServiceClient, func() error, error) {
+	conn, err := grpc.DialContext(ctx, strings.TrimPrefix(address, "grpc://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to dial flow server: %w", err)
+	}
+	return protos.NewFlowServiceClient(conn), conn.Close, nil
+}
+
+// withFlowClient runs fn with a client for the flow server of the command.
+func withFlowClient(
+	ctx context.Context,
+	cmd *cli.Command,
+	fn func(protos.FlowServiceClient) error,
+) error {
+	flowClient, closeConn, err := dialFlowServer(ctx, cmd.String("flow-server-address"))
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+	return fn(flowClient)
+}
+
+func commandArg(cmd *cli.Command, name string) (string, error) {
+	if cmd.Args().Len() != 1 {
+		return "", fmt.Errorf("expected a single %s argument", name)
+	}
+	return cmd.Args().First(), nil
+}
+
+// writeOutput prints the response as JSON or the rows as a table, depending on the output flag.
+func writeOutput(cmd *cli.Command, res proto.Message, header []string, rows [][]string) error {
+	switch cmd.String("output") {
+	case "json":
+		out, err := protojson.MarshalOptions{Multiline: true}.Marshal(res)
+		if err != nil {
+			return fmt.Errorf("unable to marshal output: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %s, expected table or json", cmd.String("output"))
+	}
+}
+
+func flowStatusName(status protos.FlowStatus) string {
+	return strings.TrimPrefix(status.String(), "STATUS_")
+}
+
+func listMirrors(ctx context.Context, cmd *cli.Command) error {
+	req := &protos.ListMirrorsRequest{
+		SourcePeer:      cmd.String("source"),
+		DestinationPeer: cmd.String("destination"),
+		PageSize:        int32(cmd.Int("page-size")),
+		PageToken:       cmd.String("page-token"),
+	}
+	if mirrorType := cmd.String("type"); mirrorType != "" {
+		value, ok := protos.MirrorType_value[strings.ToUpper(mirrorType)]
+		if !ok {
+			return fmt.Errorf("unknown mirror type %s, expected cdc or qrep", mirrorType)
+		}
+		req.MirrorType = protos.MirrorType(value).Enum()
+	}
+	if state := cmd.String("state"); state != "" {
+		value, ok := protos.FlowStatus_value["STATUS_"+strings.ToUpper(state)]
+		if !ok {
+			return fmt.Errorf("unknown mirror state %s", state)
+		}
+		req.FlowState = protos.FlowStatus(value).Enum()
+	}
+
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		res, err := flowClient.ListMirrors(ctx, req)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(res.Mirrors))
+		for _, mirror := range res.Mirrors {
+			rows = append(rows, []string{
+				mirror.FlowJobName,
+				strings.ToLower(mirror.MirrorType.String()),
+				flowStatusName(mirror.CurrentFlowState),
+				mirror.SourcePeer,
+				mirror.DestinationPeer,
+			})
+		}
+		if err := writeOutput(cmd, res, []string{"NAME", "TYPE", "STATUS", "SOURCE", "DESTINATION"}, rows); err != nil {
+			return err
+		}
+		if res.NextPageToken != "" && cmd.String("output") == "table" {
+			fmt.Fprintf(os.Stderr, "more mirrors are listed with --page-token %s\n", res.NextPageToken)
+		}
+		return nil
+	})
+}
+
+func getMirrorStatus(
+	ctx context.Context,
+	flowClient protos.FlowServiceClient,
+	mirrorName string,
+) (*protos.MirrorStatusResponse, error) {
+	res, err := flowClient.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: mirrorName})
+	if err != nil {
+		return nil, err
+	}
+	if res.ErrorMessage != "" {
+		return nil, errors.New(res.ErrorMessage)
+	}
+	return res, nil
+}
+
+func mirrorStatus(ctx context.Context, cmd *cli.Command) error {
+	mirrorName, err := commandArg(cmd, "mirror")
+	if err != nil {
+		return err
+	}
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		res, err := getMirrorStatus(ctx, flowClient, mirrorName)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{
+			{"NAME", res.FlowJobName},
+			{"STATUS", flowStatusName(res.CurrentFlowState)},
+		}
+		switch status := res.Status.(type) {
+		case *protos.MirrorStatusResponse_CdcStatus:
+			cdcStatus := status.CdcStatus
+			var numRows int64
+			for _, cdcSync := range cdcStatus.CdcSyncs {
+				numRows += int64(cdcSync.NumRows)
+			}
+			rows = append(rows,
+				[]string{"TYPE", "cdc"},
+				[]string{"SOURCE", cdcStatus.Config.GetSource().GetName()},
+				[]string{"DESTINATION", cdcStatus.Config.GetDestination().GetName()},
+				[]string{"TABLES", strconv.Itoa(len(cdcStatus.Config.GetTableMappings()))},
+				[]string{"SYNC BATCHES", strconv.Itoa(len(cdcStatus.CdcSyncs))},
+				[]string{"ROWS SYNCED", strconv.FormatInt(numRows, 10)},
+				[]string{"PENDING SCHEMA CHANGES", strconv.Itoa(len(cdcStatus.PendingSchemaDeltas))},
+				[]string{"DEAD LETTER RECORDS", strconv.FormatInt(cdcStatus.NumDeadLetterRecords, 10)},
+			)
+		case *protos.MirrorStatusResponse_QrepStatus:
+			qrepStatus := status.QrepStatus
+			var numRows int64
+			for _, partition := range qrepStatus.Partitions {
+				numRows += int64(partition.NumRows)
+			}
+			rows = append(rows,
+				[]string{"TYPE", "qrep"},
+				[]string{"SOURCE", qrepStatus.Config.GetSourcePeer().GetName()},
+				[]string{"DESTINATION", qrepStatus.Config.GetDestinationPeer().GetName()},
+				[]string{"PARTITIONS", strconv.Itoa(len(qrepStatus.Partitions))},
+				[]string{"ROWS SYNCED", strconv.FormatInt(numRows, 10)},
+			)
+		}
+		return writeOutput(cmd, res, []string{"FIELD", "VALUE"}, rows)
+	})
+}
+
+func changeMirrorState(ctx context.Context, cmd *cli.Command, requestedState protos.FlowStatus) error {
+	mirrorName, err := commandArg(cmd, "mirror")
+	if err != nil {
+		return err
+	}
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		res, err := flowClient.FlowStateChange(ctx, &protos.FlowStateChangeRequest{
+			FlowJobName:        mirrorName,
+			RequestedFlowState: requestedState,
+		})
+		if err != nil {
+			return err
+		}
+		if !res.Ok {
+			return errors.New(res.ErrorMessage)
+		}
+		return writeOutput(cmd, res, []string{"MIRROR", "REQUESTED STATUS"},
+			[][]string{{mirrorName, flowStatusName(requestedState)}})
+	})
+}
+
+func dropMirror(ctx context.Context, cmd *cli.Command) error {
+	mirrorName, err := commandArg(cmd, "mirror")
+	if err != nil {
+		return err
+	}
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		status, err := getMirrorStatus(ctx, flowClient, mirrorName)
+		if err != nil {
+			return err
+		}
+		req := &protos.ShutdownRequest{
+			FlowJobName:     mirrorName,
+			RemoveFlowEntry: true,
+		}
+		switch status := status.Status.(type) {
+		case *protos.MirrorStatusResponse_CdcStatus:
+			req.SourcePeer = status.CdcStatus.Config.GetSource()
+			req.DestinationPeer = status.CdcStatus.Config.GetDestination()
+		case *protos.MirrorStatusResponse_QrepStatus:
+			req.SourcePeer = status.QrepStatus.Config.GetSourcePeer()
+			req.DestinationPeer = status.QrepStatus.Config.GetDestinationPeer()
+		}
+
+		res, err := flowClient.ShutdownFlow(ctx, req)
+		if err != nil {
+			return err
+		}
+		if !res.Ok {
+			return errors.New(res.ErrorMessage)
+		}
+		return writeOutput(cmd, res, []string{"MIRROR", "DROPPED"}, [][]string{{mirrorName, "true"}})
+	})
+}
+
+func listPeers(ctx context.Context, cmd *cli.Command) error {
+	req := &protos.ListPeersRequest{}
+	if peerType := cmd.String("type"); peerType != "" {
+		value, ok := protos.DBType_value[strings.ToUpper(peerType)]
+		if !ok {
+			return fmt.Errorf("unknown peer type %s", peerType)
+		}
+		req.PeerType = protos.DBType(value).Enum()
+	}
+
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		res, err := flowClient.ListPeers(ctx, req)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(res.Peers))
+		for _, peer := range res.Peers {
+			rows = append(rows, []string{
+				peer.Name,
+				peer.Type.String(),
+				strconv.Itoa(int(peer.NumSourceMirrors)),
+				strconv.Itoa(int(peer.NumDestinationMirrors)),
+			})
+		}
+		return writeOutput(cmd, res, []string{"NAME", "TYPE", "SOURCE OF", "DESTINATION OF"}, rows)
+	})
+}
+
+func validatePeer(ctx context.Context, cmd *cli.Command) error {
+	peerName, err := commandArg(cmd, "peer")
+	if err != nil {
+		return err
+	}
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		peersRes, err := flowClient.ListPeers(ctx, &protos.ListPeersRequest{IncludeConfigs: true})
+		if err != nil {
+			return err
+		}
+		idx := slices.IndexFunc(peersRes.Peers, func(item *protos.ListPeersItem) bool {
+			return item.Name == peerName
+		})
+		if idx == -1 {
+			return fmt.Errorf("peer %s not found", peerName)
+		}
+
+		res, err := flowClient.ValidatePeer(ctx, &protos.ValidatePeerRequest{Peer: peersRes.Peers[idx].Peer})
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(cmd, res, []string{"PEER", "STATUS", "MESSAGE"},
+			[][]string{{peerName, res.Status.String(), res.Message}}); err != nil {
+			return err
+		}
+		if res.Status != protos.ValidatePeerStatus_VALID {
+			return fmt.Errorf("peer %s is not valid", peerName)
+		}
+		return nil
+	})
+}
+
+func slotInfo(ctx context.Context, cmd *cli.Command) error {
+	peerName, err := commandArg(cmd, "peer")
+	if err != nil {
+		return err
+	}
+	return withFlowClient(ctx, cmd, func(flowClient protos.FlowServiceClient) error {
+		res, err := flowClient.GetSlotInfo(ctx, &protos.PostgresPeerActivityInfoRequest{PeerName: peerName})
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(res.SlotData))
+		for _, slot := range res.SlotData {
+			rows = append(rows, []string{
+				slot.SlotName,
+				strconv.FormatBool(slot.Active),
+				slot.WalStatus,
+				strconv.FormatFloat(float64(slot.LagInMb), 'f', 2, 32),
+				slot.ConfirmedFlushLSN,
+				slot.RestartLSN,
+			})
+		}
+		return writeOutput(cmd, res,
+			[]string{"SLOT", "ACTIVE", "WAL STATUS", "LAG (MB)", "CONFIRMED FLUSH LSN", "RESTART LSN"}, rows)
+	})
+}
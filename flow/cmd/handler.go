@@ -25,6 +25,8 @@ type FlowRequestHandler struct {
 	temporalClient      client.Client
 	pool                *pgxpool.Pool
 	peerflowTaskQueueID string
+	// shared by the watchers of the status of each mirror
+	mirrorStatusPollers *mirrorStatusPollers
 	protos.UnimplementedFlowServiceServer
 }
 
@@ -33,6 +35,7 @@ func NewFlowRequestHandler(temporalClient client.Client, pool *pgxpool.Pool, tas
 		temporalClient:      temporalClient,
 		pool:                pool,
 		peerflowTaskQueueID: taskQueue,
+		mirrorStatusPollers: newMirrorStatusPollers(watchMirrorStatusInterval),
 	}
 }
 
@@ -83,6 +86,19 @@ func (h *FlowRequestHandler) createCdcJobEntry(ctx context.Context,
 		}
 	}
 
+	for _, destination := range req.ConnectionConfigs.FanOutDestinations {
+		fanOutPeerID, _, err := h.getPeerID(ctx, destination.Name)
+		if err != nil {
+			return fmt.Errorf("unable to get peer id for fan-out peer %s: %w", destination.Name, err)
+		}
+		_, err = h.pool.Exec(ctx, "INSERT INTO flow_fan_out_destinations (flow_name, peer_id) VALUES ($1, $2)",
+			req.ConnectionConfigs.FlowJobName, fanOutPeerID)
+		if err != nil {
+			return fmt.Errorf("unable to insert fan-out peer %s of flow %s: %w",
+				destination.Name, req.ConnectionConfigs.FlowJobName, err)
+		}
+	}
+
 	return nil
 }
 
@@ -131,6 +147,33 @@ func (h *FlowRequestHandler) CreateCDCFlow(
 		},
 	}
 
+	// the initial copy reads from the primary and can't use the snapshot of a slot on the standby,
+	// incremental snapshots are consistent with the slot as their watermarks are replicated to the standby
+	if cfg.PullFromStandby && cfg.DoInitialCopy && (!cfg.IncrementalSnapshot || cfg.InitialCopyOnly) {
+		return nil, fmt.Errorf("mirror %s pulls from the standby, which requires an incremental snapshot "+
+			"instead of an initial copy", cfg.FlowJobName)
+	}
+
+	// history mode inserts every change as a new version, so there is no conflict to resolve
+	if cfg.HistoryMode && cfg.ConflictResolution == protos.ConflictResolution_CONFLICT_RESOLUTION_LAST_WRITER_WINS {
+		return nil, fmt.Errorf("mirror %s can't use last writer wins conflict resolution in history mode", cfg.FlowJobName)
+	}
+
+	// fan-out destinations are told apart by peer name
+	destinationNames := map[string]struct{}{cfg.Destination.Name: {}}
+	for _, destination := range cfg.FanOutDestinations {
+		if _, ok := destinationNames[destination.Name]; ok {
+			return nil, fmt.Errorf("peer %s is a destination of mirror %s more than once", destination.Name, cfg.FlowJobName)
+		}
+		destinationNames[destination.Name] = struct{}{}
+	}
+
+	// a resync replaces the destination tables, which also hold the rows of the other sources
+	if cfg.Resync && cfg.SourceColName != "" {
+		return nil, fmt.Errorf("mirror %s can't be resynced, its destination tables are shared with other sources",
+			cfg.FlowJobName)
+	}
+
 	maxBatchSize := int(cfg.MaxBatchSize)
 	if maxBatchSize == 0 {
 		maxBatchSize = 1_000_000
@@ -157,6 +200,10 @@ func (h *FlowRequestHandler) CreateCDCFlow(
 		req.ConnectionConfigs.SyncedAtColName = strings.ToUpper(req.ConnectionConfigs.SyncedAtColName)
 	}
 
+	// before images are opt-in, an empty column name stays empty when normalizing its casing
+	req.ConnectionConfigs.BeforeImageColName = strings.ToUpper(req.ConnectionConfigs.BeforeImageColName)
+	req.ConnectionConfigs.SourceColName = strings.ToUpper(req.ConnectionConfigs.SourceColName)
+
 	if req.CreateCatalogEntry {
 		err := h.createCdcJobEntry(ctx, req, workflowID)
 		if err != nil {
@@ -222,6 +269,13 @@ func (h *FlowRequestHandler) removeFlowEntryInCatalog(
 		return fmt.Errorf("unable to remove flow entry in catalog: %w", err)
 	}
 
+	_, err = h.pool.Exec(context.Background(),
+		"DELETE FROM flow_fan_out_destinations WHERE flow_name = $1",
+		flowName)
+	if err != nil {
+		return fmt.Errorf("unable to remove fan-out destinations of flow in catalog: %w", err)
+	}
+
 	return nil
 }
 
@@ -321,11 +375,42 @@ func (h *FlowRequestHandler) ShutdownFlow(
 	ctx context.Context,
 	req *protos.ShutdownRequest,
 ) (*protos.ShutdownResponse, error) {
+	// clients that only know the mirror name leave the workflow to be looked up
+	if req.WorkflowId == "" {
+		workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+		if err != nil {
+			return &protos.ShutdownResponse{
+				Ok:           false,
+				ErrorMessage: err.Error(),
+			}, err
+		}
+		req.WorkflowId = workflowID
+	}
+	// the slot of CDC mirrors pulling from the standby has to be dropped on the standby,
+	// and fan-out destinations are cleaned up like the destination peer
+	cdcFlow, err := h.isCDCFlow(ctx, req.FlowJobName)
+	if err != nil {
+		return &protos.ShutdownResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, err
+	}
+	if cdcFlow {
+		cfg, err := h.getFlowConfigFromCatalog(req.FlowJobName)
+		if err != nil {
+			return &protos.ShutdownResponse{
+				Ok:           false,
+				ErrorMessage: err.Error(),
+			}, err
+		}
+		req.PullFromStandby = cfg.PullFromStandby
+		req.FanOutDestinationPeers = cfg.FanOutDestinations
+	}
 	logs := slog.Group("shutdown-log",
 		slog.String(string(shared.FlowNameKey), req.FlowJobName),
 		slog.String("workflowId", req.WorkflowId),
 	)
-	err := h.temporalClient.SignalWorkflow(
+	err = h.temporalClient.SignalWorkflow(
 		ctx,
 		req.WorkflowId,
 		"",
@@ -711,7 +796,8 @@ func (h *FlowRequestHandler) DropPeer(
 
 	var inMirror pgtype.Int8
 	queryErr := h.pool.QueryRow(ctx,
-		"SELECT COUNT(*) FROM flows WHERE source_peer=$1 or destination_peer=$2",
+		`SELECT (SELECT COUNT(*) FROM flows WHERE source_peer=$1 or destination_peer=$2) +
+		(SELECT COUNT(*) FROM flow_fan_out_destinations WHERE peer_id=$2)`,
 		peerID, peerID).Scan(&inMirror)
 	if queryErr != nil {
 		return &protos.DropPeerResponse{
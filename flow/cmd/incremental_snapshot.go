@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// IncrementalSnapshot snapshots tables of a running CDC mirror again, without pausing it.
+// The tables are read in chunks by the sync flows of the mirror while changes are pulled.
+func (h *FlowRequestHandler) IncrementalSnapshot(
+	ctx context.Context,
+	req *protos.IncrementalSnapshotRequest,
+) (*protos.IncrementalSnapshotResponse, error) {
+	slog.Info("Incremental snapshot endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName))
+	config, err := h.getFlowConfigFromCatalog(req.FlowJobName)
+	if err != nil {
+		return &protos.IncrementalSnapshotResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get config of mirror %s: %s", req.FlowJobName, err.Error()),
+		}, nil
+	}
+	if config.Source.Type != protos.DBType_POSTGRES {
+		return &protos.IncrementalSnapshotResponse{
+			Ok:           false,
+			ErrorMessage: "incremental snapshots are only supported for mirrors from Postgres",
+		}, nil
+	}
+
+	mirroredTables := make(map[string]struct{}, len(config.TableMappings))
+	for _, tableMapping := range config.TableMappings {
+		mirroredTables[tableMapping.SourceTableIdentifier] = struct{}{}
+	}
+	tables := req.SourceTableIdentifiers
+	if len(tables) == 0 {
+		tables = make([]string, 0, len(config.TableMappings))
+		for _, tableMapping := range config.TableMappings {
+			tables = append(tables, tableMapping.SourceTableIdentifier)
+		}
+	}
+	for _, table := range tables {
+		if _, ok := mirroredTables[table]; !ok {
+			return &protos.IncrementalSnapshotResponse{
+				Ok:           false,
+				ErrorMessage: fmt.Sprintf("table %s is not part of mirror %s", table, req.FlowJobName),
+			}, nil
+		}
+	}
+
+	err = monitoring.RequestIncrementalSnapshot(ctx, h.pool, req.FlowJobName, tables)
+	if err != nil {
+		slog.Error("unable to request incremental snapshot", slog.Any("error", err))
+		return &protos.IncrementalSnapshotResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &protos.IncrementalSnapshotResponse{
+		Ok: true,
+	}, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultListMirrorsPageSize = 100
+	// workflows whose state is queried at once when listing mirrors
+	listMirrorsStateConcurrency = 16
+)
+
+type listedMirror struct {
+	item       *protos.ListMirrorsItem
+	workflowID string
+}
+
+// ListMirrors lists the mirrors in the catalog ordered by name, with the state of their workflows.
+// Pages are keyed by the name of the last mirror of the previous page. Mirrors are filtered by state
+// after the page is read from the catalog, so pages can hold fewer mirrors when filtering by state.
+func (h *FlowRequestHandler) ListMirrors(
+	ctx context.Context,
+	req *protos.ListMirrorsRequest,
+) (*protos.ListMirrorsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultListMirrorsPageSize
+	}
+
+	// one more mirror than fits the page is read to tell if there is a next page
+	rows, err := h.pool.Query(ctx, `
+		SELECT f.name, min(f.workflow_id), bool_or(coalesce(f.query_string, '') = ''),
+			min(sp.name), min(dp.name), min(f.created_at)
+		FROM flows f
+		JOIN peers sp ON sp.id = f.source_peer
+		JOIN peers dp ON dp.id = f.destination_peer
+		WHERE f.name > $1 AND ($2 = '' OR sp.name = $2) AND ($3 = '' OR dp.name = $3)
+		GROUP BY f.name
+		HAVING $4::BOOLEAN IS NULL OR bool_or(coalesce(f.query_string, '') = '') = $4
+		ORDER BY f.name LIMIT $5`,
+		req.PageToken, req.SourcePeer, req.DestinationPeer, isCDCFilter(req.MirrorType), pageSize+1)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query mirrors from catalog: %w", err)
+	}
+	defer rows.Close()
+
+	var mirrors []listedMirror
+	for rows.Next() {
+		var workflowID pgtype.Text
+		var isCDC bool
+		var createdAt time.Time
+		item := &protos.ListMirrorsItem{}
+		if err := rows.Scan(&item.FlowJobName, &workflowID, &isCDC,
+			&item.SourcePeer, &item.DestinationPeer, &createdAt); err != nil {
+			return nil, fmt.Errorf("unable to scan mirror from catalog: %w", err)
+		}
+		item.MirrorType = protos.MirrorType_QREP
+		if isCDC {
+			item.MirrorType = protos.MirrorType_CDC
+		}
+		item.CreatedAt = timestamppb.New(createdAt)
+		mirrors = append(mirrors, listedMirror{item: item, workflowID: workflowID.String})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query mirrors from catalog: %w", err)
+	}
+	rows.Close()
+
+	page, nextPageToken := pageMirrors(mirrors, pageSize)
+	return &protos.ListMirrorsResponse{
+		Mirrors:       filterMirrorStates(ctx, page, req.FlowState, h.getWorkflowStatus),
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// isCDCFilter is the value mirrors are filtered by in the catalog for a mirror type, null to not filter.
+func isCDCFilter(mirrorType *protos.MirrorType) pgtype.Bool {
+	if mirrorType == nil {
+		return pgtype.Bool{}
+	}
+	return pgtype.Bool{Bool: *mirrorType == protos.MirrorType_CDC, Valid: true}
+}
+
+// pageMirrors splits the mirrors read after a page token into a page and the token of the next page,
+// which is empty when all mirrors fit the page.
+func pageMirrors(mirrors []listedMirror, pageSize int) ([]listedMirror, string) {
+	if len(mirrors) <= pageSize {
+		return mirrors, ""
+	}
+	return mirrors[:pageSize], mirrors[pageSize-1].item.FlowJobName
+}
+
+// filterMirrorStates queries the states of the workflows of mirrors concurrently,
+// keeping the mirrors in the requested state. The state of mirrors whose workflow can't be queried
+// is left unknown.
+func filterMirrorStates(
+	ctx context.Context,
+	mirrors []listedMirror,
+	flowState *protos.FlowStatus,
+	getWorkflowStatus func(context.Context, string) (*protos.FlowStatus, error),
+) []*protos.ListMirrorsItem {
+	var group errgroup.Group
+	group.SetLimit(listMirrorsStateConcurrency)
+	for _, mirror := range mirrors {
+		mirror := mirror
+		group.Go(func() error {
+			state, err := getWorkflowStatus(ctx, mirror.workflowID)
+			if err != nil {
+				slog.Warn("unable to get state of mirror", slog.String(string(shared.FlowNameKey), mirror.item.FlowJobName),
+					slog.Any("error", err))
+			} else {
+				mirror.item.CurrentFlowState = *state
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	items := make([]*protos.ListMirrorsItem, 0, len(mirrors))
+	for _, mirror := range mirrors {
+		if flowState == nil || *flowState == mirror.item.CurrentFlowState {
+			items = append(items, mirror.item)
+		}
+	}
+	return items
+}
+
+// ListPeers lists the peers in the catalog ordered by name, with the number of mirrors using each of them.
+func (h *FlowRequestHandler) ListPeers(
+	ctx context.Context,
+	req *protos.ListPeersRequest,
+) (*protos.ListPeersResponse, error) {
+	var peerType pgtype.Int4
+	if req.PeerType != nil {
+		peerType = pgtype.Int4{Int32: int32(*req.PeerType), Valid: true}
+	}
+
+	rows, err := h.pool.Query(ctx, `
+		SELECT p.name, p.type, p.options,
+			(SELECT COUNT(DISTINCT f.name) FROM flows f WHERE f.source_peer = p.id),
+			(SELECT COUNT(DISTINCT d.name) FROM (
+				SELECT f.name FROM flows f WHERE f.destination_peer = p.id
+				UNION SELECT fd.flow_name FROM flow_fan_out_destinations fd WHERE fd.peer_id = p.id) d)
+		FROM peers p
+		WHERE $1::INTEGER IS NULL OR p.type = $1
+		ORDER BY p.name`, peerType)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query peers from catalog: %w", err)
+	}
+	defer rows.Close()
+
+	res := &protos.ListPeersResponse{}
+	for rows.Next() {
+		var peerType int32
+		var options []byte
+		item := &protos.ListPeersItem{}
+		if err := rows.Scan(&item.Name, &peerType, &options,
+			&item.NumSourceMirrors, &item.NumDestinationMirrors); err != nil {
+			return nil, fmt.Errorf("unable to scan peer from catalog: %w", err)
+		}
+		item.Type = protos.DBType(peerType)
+		if req.IncludeConfigs {
+			if item.Peer, err = peerFromCatalog(item.Name, item.Type, options); err != nil {
+				return nil, err
+			}
+		}
+		res.Peers = append(res.Peers, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to query peers from catalog: %w", err)
+	}
+	return res, nil
+}
+
+// peerFromCatalog decodes the options of the peer types CreatePeer can store,
+// other peers are returned without a config.
+func peerFromCatalog(name string, peerType protos.DBType, options []byte) (*protos.Peer, error) {
+	peer := &protos.Peer{Name: name, Type: peerType}
+	var config proto.Message
+	switch peerType {
+	case protos.DBType_POSTGRES:
+		pgConfig := &protos.PostgresConfig{}
+		peer.Config = &protos.Peer_PostgresConfig{PostgresConfig: pgConfig}
+		config = pgConfig
+	case protos.DBType_SNOWFLAKE:
+		sfConfig := &protos.SnowflakeConfig{}
+		peer.Config = &protos.Peer_SnowflakeConfig{SnowflakeConfig: sfConfig}
+		config = sfConfig
+	case protos.DBType_BIGQUERY:
+		bqConfig := &protos.BigqueryConfig{}
+		peer.Config = &protos.Peer_BigqueryConfig{BigqueryConfig: bqConfig}
+		config = bqConfig
+	case protos.DBType_SQLSERVER:
+		sqlServerConfig := &protos.SqlServerConfig{}
+		peer.Config = &protos.Peer_SqlserverConfig{SqlserverConfig: sqlServerConfig}
+		config = sqlServerConfig
+	case protos.DBType_S3:
+		s3Config := &protos.S3Config{}
+		peer.Config = &protos.Peer_S3Config{S3Config: s3Config}
+		config = s3Config
+	default:
+		return peer, nil
+	}
+	if err := proto.Unmarshal(options, config); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal config of %s peer %s: %w", peerType, name, err)
+	}
+	return peer, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/stretchr/testify/require"
+)
+
+func testListedMirrors(names ...string) []listedMirror {
+	mirrors := make([]listedMirror, 0, len(names))
+	for _, name := range names {
+		mirrors = append(mirrors, listedMirror{
+			item:       &protos.ListMirrorsItem{FlowJobName: name},
+			workflowID: name + "-workflow",
+		})
+	}
+	return mirrors
+}
+
+func listedMirrorNames(items []*protos.ListMirrorsItem) []string {
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.FlowJobName)
+	}
+	return names
+}
+
+func TestPageMirrors(t *testing.T) {
+	// the catalog is read for one more mirror than fits the page
+	page, nextPageToken := pageMirrors(testListedMirrors("a", "b", "c"), 2)
+	require.Len(t, page, 2)
+	require.Equal(t, "b", nextPageToken)
+
+	page, nextPageToken = pageMirrors(testListedMirrors("c"), 2)
+	require.Len(t, page, 1)
+	require.Empty(t, nextPageToken)
+
+	page, nextPageToken = pageMirrors(testListedMirrors("a", "b"), 2)
+	require.Len(t, page, 2)
+	require.Empty(t, nextPageToken)
+}
+
+func TestIsCDCFilter(t *testing.T) {
+	require.False(t, isCDCFilter(nil).Valid)
+	require.True(t, isCDCFilter(protos.MirrorType_CDC.Enum()).Bool)
+	require.True(t, isCDCFilter(protos.MirrorType_QREP.Enum()).Valid)
+	require.False(t, isCDCFilter(protos.MirrorType_QREP.Enum()).Bool)
+}
+
+func TestFilterMirrorStates(t *testing.T) {
+	states := map[string]protos.FlowStatus{
+		"a-workflow": protos.FlowStatus_STATUS_RUNNING,
+		"b-workflow": protos.FlowStatus_STATUS_PAUSED,
+		"d-workflow": protos.FlowStatus_STATUS_RUNNING,
+	}
+	var numQueries atomic.Int32
+	getWorkflowStatus := func(_ context.Context, workflowID string) (*protos.FlowStatus, error) {
+		numQueries.Add(1)
+		state, ok := states[workflowID]
+		if !ok {
+			return nil, errors.New("workflow not found")
+		}
+		return &state, nil
+	}
+
+	items := filterMirrorStates(context.Background(), testListedMirrors("a", "b", "c", "d"), nil, getWorkflowStatus)
+	require.Equal(t, []string{"a", "b", "c", "d"}, listedMirrorNames(items))
+	// the state of mirrors whose workflow can't be queried is left unknown
+	require.Equal(t, protos.FlowStatus_STATUS_UNKNOWN, items[2].CurrentFlowState)
+	require.Equal(t, protos.FlowStatus_STATUS_PAUSED, items[1].CurrentFlowState)
+
+	items = filterMirrorStates(context.Background(), testListedMirrors("a", "b", "c", "d"),
+		protos.FlowStatus_STATUS_RUNNING.Enum(), getWorkflowStatus)
+	require.Equal(t, []string{"a", "d"}, listedMirrorNames(items))
+	require.Equal(t, int32(8), numQueries.Load())
+}
+
+func TestFilterMirrorStatesManyMirrors(t *testing.T) {
+	names := make([]string, 0, 3*listMirrorsStateConcurrency)
+	for i := 0; i < cap(names); i++ {
+		names = append(names, fmt.Sprintf("mirror_%03d", i))
+	}
+	running := protos.FlowStatus_STATUS_RUNNING
+	items := filterMirrorStates(context.Background(), testListedMirrors(names...), nil,
+		func(context.Context, string) (*protos.FlowStatus, error) {
+			return &running, nil
+		})
+	// mirrors keep the order of the page
+	require.Equal(t, names, listedMirrorNames(items))
+	for _, item := range items {
+		require.Equal(t, protos.FlowStatus_STATUS_RUNNING, item.CurrentFlowState)
+	}
+}
@@ -65,9 +65,17 @@ func main() {
 		Sources: cli.EnvVars("PEERDB_TEMPORAL_NAMESPACE"),
 	}
 
+	flowServerAddressFlag := &cli.StringFlag{
+		Name:       "flow-server-address",
+		Value:      "localhost:8110",
+		Usage:      "Address of the flow API gRPC server",
+		Sources:    cli.EnvVars("PEERDB_FLOW_SERVER_ADDRESS"),
+		Persistent: true,
+	}
+
 	app := &cli.Command{
 		Name: "PeerDB Flows CLI",
-		Commands: []*cli.Command{
+		Commands: append([]*cli.Command{
 			{
 				Name: "worker",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -140,7 +148,31 @@ func main() {
 					})
 				},
 			},
-		},
+			{
+				Name:  "apply",
+				Usage: "Create the peers and mirrors defined in a YAML file and drop peers that are no longer defined",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "YAML file with peers, cdc_mirrors and qrep_mirrors",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "plan",
+						Usage: "Print the changes without applying them",
+					},
+					flowServerAddressFlag,
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return ApplyMain(appCtx, &ApplyOptions{
+						File:              cmd.String("file"),
+						FlowServerAddress: cmd.String("flow-server-address"),
+						Plan:              cmd.Bool("plan"),
+					})
+				},
+			},
+		}, clientCommands(flowServerAddressFlag)...),
 	}
 
 	if err := app.Run(context.Background(), os.Args); err != nil {
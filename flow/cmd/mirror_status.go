@@ -92,9 +92,35 @@ func (h *FlowRequestHandler) CDCFlowStatus(
 		Clones: cloneStatuses,
 	}
 
+	validationStatus, err := h.lastMirrorValidation(ctx, req.FlowJobName)
+	if err != nil {
+		return nil, err
+	}
+
+	var numDeadLetterRecords int64
+	err = h.pool.QueryRow(ctx, "SELECT COUNT(*) FROM peerdb_stats.dead_letter_records WHERE flow_name=$1",
+		req.FlowJobName).Scan(&numDeadLetterRecords)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query dead letter records of mirror %s: %w", req.FlowJobName, err)
+	}
+
+	// the mirror may not be running, in which case nothing is pending
+	var pendingSchemaDeltas []*protos.TableSchemaDelta
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+	if err == nil {
+		pendingSchemaDeltas, err = h.getPendingSchemaDeltas(ctx, workflowID)
+	}
+	if err != nil {
+		slog.Warn("unable to get pending schema changes", slog.String(string(shared.FlowNameKey), req.FlowJobName),
+			slog.Any("error", err))
+	}
+
 	return &protos.CDCMirrorStatus{
-		Config:         config,
-		SnapshotStatus: initialCopyStatus,
+		Config:               config,
+		SnapshotStatus:       initialCopyStatus,
+		ValidationStatus:     validationStatus,
+		PendingSchemaDeltas:  pendingSchemaDeltas,
+		NumDeadLetterRecords: numDeadLetterRecords,
 	}, nil
 }
 
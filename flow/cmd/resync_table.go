@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// ResyncTable resyncs a single table of a running CDC mirror, without pausing it.
+// The table is snapshotted into a new destination table, which is swapped in once it is loaded,
+// and the changes synced meanwhile are replayed into it from the raw table.
+func (h *FlowRequestHandler) ResyncTable(
+	ctx context.Context,
+	req *protos.ResyncTableRequest,
+) (*protos.ResyncTableResponse, error) {
+	slog.Info("Resync table endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName),
+		slog.String("table", req.SourceTableIdentifier))
+	config, err := h.getFlowConfigFromCatalog(req.FlowJobName)
+	if err != nil {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get config of mirror %s: %s", req.FlowJobName, err.Error()),
+		}, nil
+	}
+	// resynced tables are swapped in with RenameTables, which only these destinations support
+	if config.Destination.Type != protos.DBType_SNOWFLAKE && config.Destination.Type != protos.DBType_BIGQUERY {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: "resyncing tables is only supported for mirrors to Snowflake and BigQuery",
+		}, nil
+	}
+
+	// the resync workflow only snapshots into the destination peer
+	if len(config.FanOutDestinations) > 0 {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: "resyncing tables is not supported for mirrors with fan-out destinations",
+		}, nil
+	}
+
+	// the resynced table replaces the destination table, which also holds the rows of the other sources
+	if config.SourceColName != "" {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: "resyncing tables is not supported for mirrors that share their tables with other sources",
+		}, nil
+	}
+
+	// the resynced table only has the current versions of rows, and replaying changes into it
+	// would insert versions again that are already part of the history
+	if config.HistoryMode {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: "resyncing tables is not supported for mirrors in history mode",
+		}, nil
+	}
+
+	found := false
+	for _, tableMapping := range config.TableMappings {
+		if tableMapping.SourceTableIdentifier == req.SourceTableIdentifier {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("table %s is not part of mirror %s", req.SourceTableIdentifier, req.FlowJobName),
+		}, nil
+	}
+
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+	if err != nil {
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get workflow of mirror %s: %s", req.FlowJobName, err.Error()),
+		}, nil
+	}
+	err = h.temporalClient.SignalWorkflow(ctx, workflowID, "", shared.ResyncTableSignalName, req.SourceTableIdentifier)
+	if err != nil {
+		slog.Error("unable to signal resync of table", slog.Any("error", err))
+		return &protos.ResyncTableResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &protos.ResyncTableResponse{
+		Ok: true,
+	}, nil
+}
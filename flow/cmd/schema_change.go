@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+)
+
+// ResolveSchemaChange accepts or rejects the schema changes a CDC mirror has been paused for,
+// under the pause schema change policy. Accepted changes are applied to the destination tables.
+func (h *FlowRequestHandler) ResolveSchemaChange(
+	ctx context.Context,
+	req *protos.ResolveSchemaChangeRequest,
+) (*protos.ResolveSchemaChangeResponse, error) {
+	slog.Info("Resolve schema change endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName),
+		slog.Bool("accept", req.Accept))
+	workflowID, err := h.getWorkflowID(ctx, req.FlowJobName)
+	if err != nil {
+		return &protos.ResolveSchemaChangeResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get workflow of mirror %s: %s", req.FlowJobName, err.Error()),
+		}, nil
+	}
+
+	pendingDeltas, err := h.getPendingSchemaDeltas(ctx, workflowID)
+	if err != nil {
+		return &protos.ResolveSchemaChangeResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+	if len(pendingDeltas) == 0 {
+		return &protos.ResolveSchemaChangeResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("mirror %s has no pending schema changes", req.FlowJobName),
+		}, nil
+	}
+
+	err = h.temporalClient.SignalWorkflow(ctx, workflowID, "", shared.SchemaChangeSignalName, req.Accept)
+	if err != nil {
+		slog.Error("unable to signal schema change decision", slog.Any("error", err))
+		return &protos.ResolveSchemaChangeResponse{
+			Ok:           false,
+			ErrorMessage: err.Error(),
+		}, nil
+	}
+
+	return &protos.ResolveSchemaChangeResponse{
+		Ok: true,
+	}, nil
+}
+
+func (h *FlowRequestHandler) getPendingSchemaDeltas(
+	ctx context.Context,
+	workflowID string,
+) ([]*protos.TableSchemaDelta, error) {
+	res, err := h.temporalClient.QueryWorkflow(ctx, workflowID, "", shared.PendingSchemaDeltasQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending schema changes in workflow with ID %s: %w", workflowID, err)
+	}
+	var pendingDeltas []*protos.TableSchemaDelta
+	if err := res.Get(&pendingDeltas); err != nil {
+		return nil, fmt.Errorf("failed to get pending schema changes in workflow with ID %s: %w", workflowID, err)
+	}
+	return pendingDeltas, nil
+}
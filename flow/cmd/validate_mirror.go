@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	peerflow "github.com/PeerDB-io/peer-flow/workflows"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.temporal.io/sdk/client"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ValidateMirror starts comparing the tables of a CDC mirror between source and destination.
+// Row counts and checksums are compared range by range, mismatched ranges are reported by MirrorStatus.
+func (h *FlowRequestHandler) ValidateMirror(
+	ctx context.Context,
+	req *protos.ValidateMirrorRequest,
+) (*protos.ValidateMirrorResponse, error) {
+	slog.Info("Validate mirror endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName))
+	config, err := h.getFlowConfigFromCatalog(req.FlowJobName)
+	if err != nil {
+		return &protos.ValidateMirrorResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to get config of mirror %s: %s", req.FlowJobName, err.Error()),
+		}, nil
+	}
+	if config.Source.Type != protos.DBType_POSTGRES {
+		return &protos.ValidateMirrorResponse{
+			Ok:           false,
+			ErrorMessage: "validation is only supported for mirrors from Postgres",
+		}, nil
+	}
+	switch config.Destination.Type {
+	case protos.DBType_POSTGRES, protos.DBType_SNOWFLAKE, protos.DBType_BIGQUERY:
+	default:
+		return &protos.ValidateMirrorResponse{
+			Ok:           false,
+			ErrorMessage: "validation is only supported for mirrors to Postgres, Snowflake and BigQuery",
+		}, nil
+	}
+
+	mirroredTables := make(map[string]struct{}, len(config.TableMappings))
+	for _, tableMapping := range config.TableMappings {
+		mirroredTables[tableMapping.SourceTableIdentifier] = struct{}{}
+	}
+	for _, table := range req.SourceTableIdentifiers {
+		if _, ok := mirroredTables[table]; !ok {
+			return &protos.ValidateMirrorResponse{
+				Ok:           false,
+				ErrorMessage: fmt.Sprintf("table %s is not part of mirror %s", table, req.FlowJobName),
+			}, nil
+		}
+	}
+
+	validationID := fmt.Sprintf("%s-validate-%s", req.FlowJobName, uuid.New())
+	workflowOptions := client.StartWorkflowOptions{
+		ID:        validationID,
+		TaskQueue: h.peerflowTaskQueueID,
+		SearchAttributes: map[string]interface{}{
+			shared.MirrorNameSearchAttribute: req.FlowJobName,
+		},
+	}
+	_, err = h.temporalClient.ExecuteWorkflow(ctx, workflowOptions, peerflow.ValidateMirrorWorkflow,
+		config, validationID, req.SourceTableIdentifiers)
+	if err != nil {
+		slog.Error("unable to start ValidateMirror workflow", slog.Any("error", err))
+		return &protos.ValidateMirrorResponse{
+			Ok:           false,
+			ErrorMessage: fmt.Sprintf("unable to start validation: %s", err.Error()),
+		}, nil
+	}
+
+	return &protos.ValidateMirrorResponse{
+		Ok:           true,
+		ValidationId: validationID,
+	}, nil
+}
+
+// lastMirrorValidation returns the last validation of a mirror with its mismatched ranges, nil if there is none.
+func (h *FlowRequestHandler) lastMirrorValidation(
+	ctx context.Context,
+	flowJobName string,
+) (*protos.MirrorValidationStatus, error) {
+	var status protos.MirrorValidationStatus
+	var startTime, endTime pgtype.Timestamp
+	var errorMessage pgtype.Text
+	err := h.pool.QueryRow(ctx, `SELECT validation_id,start_time,end_time,num_tables,
+	 (SELECT COALESCE(SUM(t.num_ranges),0)::INT FROM peerdb_stats.mirror_validation_tables t
+	 WHERE t.validation_id=v.validation_id),error_message
+	 FROM peerdb_stats.mirror_validations v WHERE flow_name=$1 ORDER BY start_time DESC LIMIT 1`, flowJobName,
+	).Scan(&status.ValidationId, &startTime, &endTime, &status.NumTables, &status.NumRanges, &errorMessage)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to query validations of mirror %s: %w", flowJobName, err)
+	}
+	if startTime.Valid {
+		status.StartTime = timestamppb.New(startTime.Time)
+	}
+	if endTime.Valid {
+		status.EndTime = timestamppb.New(endTime.Time)
+	}
+	status.ErrorMessage = errorMessage.String
+
+	rows, err := h.pool.Query(ctx, `SELECT table_name,range_start,range_end,source_rows,destination_rows,
+	 keys_match,rows_match FROM peerdb_stats.mirror_validation_mismatches WHERE validation_id=$1
+	 ORDER BY table_name`, status.ValidationId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query mismatches of validation %s: %w", status.ValidationId, err)
+	}
+	status.Mismatches, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (*protos.MirrorValidationMismatch, error) {
+		var mismatch protos.MirrorValidationMismatch
+		err := row.Scan(&mismatch.TableName, &mismatch.RangeStart, &mismatch.RangeEnd, &mismatch.SourceRows,
+			&mismatch.DestinationRows, &mismatch.KeysMatch, &mismatch.RowsMatch)
+		return &mismatch, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan mismatches of validation %s: %w", status.ValidationId, err)
+	}
+	return &status, nil
+}
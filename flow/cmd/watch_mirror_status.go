@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	peerflow "github.com/PeerDB-io/peer-flow/workflows"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.temporal.io/api/serviceerror"
+)
+
+const watchMirrorStatusInterval = 5 * time.Second
+
+// mirrorStatusVersion is a cheap summary of what the status of a mirror is built from,
+// the full status is only rebuilt and sent when it changes.
+type mirrorStatusVersion struct {
+	workflowState          string
+	numBatches             int64
+	numSyncedBatches       int64
+	latestLSNAtTarget      string
+	numPartitions          int64
+	numCompletedPartitions int64
+}
+
+// pollMirrorStatusFunc returns the status of a mirror if it changed since the previous poll, nil otherwise.
+type pollMirrorStatusFunc func(ctx context.Context) (*protos.MirrorStatusResponse, error)
+
+// mirrorStatusPollers shares one poller per mirror between the watchers of its status,
+// so a mirror is polled once per interval however many clients watch it.
+type mirrorStatusPollers struct {
+	// guards the pollers and their watchers
+	mu       sync.Mutex
+	interval time.Duration
+	pollers  map[string]*mirrorStatusPoller
+}
+
+type mirrorStatusPoller struct {
+	cancel   context.CancelFunc
+	watchers map[chan *protos.MirrorStatusResponse]struct{}
+	// the last status sent, sent first to watchers joining later
+	last *protos.MirrorStatusResponse
+	// set when the mirror is not found, before the channels of the watchers are closed
+	err error
+}
+
+// mirrorStatusWatch receives the statuses of a mirror from its poller.
+type mirrorStatusWatch struct {
+	pollers     *mirrorStatusPollers
+	poller      *mirrorStatusPoller
+	flowJobName string
+	// holds the latest status the watcher hasn't received yet,
+	// closed once the mirror is terminated or not found
+	statuses chan *protos.MirrorStatusResponse
+}
+
+func newMirrorStatusPollers(interval time.Duration) *mirrorStatusPollers {
+	return &mirrorStatusPollers{
+		interval: interval,
+		pollers:  make(map[string]*mirrorStatusPoller),
+	}
+}
+
+// watch starts watching the status of a mirror, starting a poller with newPoll if the mirror isn't watched yet.
+func (p *mirrorStatusPollers) watch(flowJobName string, newPoll func() pollMirrorStatusFunc) *mirrorStatusWatch {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	poller, ok := p.pollers[flowJobName]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		poller = &mirrorStatusPoller{
+			cancel:   cancel,
+			watchers: make(map[chan *protos.MirrorStatusResponse]struct{}),
+		}
+		p.pollers[flowJobName] = poller
+		go p.run(ctx, flowJobName, poller, newPoll())
+	}
+
+	w := &mirrorStatusWatch{
+		pollers:     p,
+		poller:      poller,
+		flowJobName: flowJobName,
+		statuses:    make(chan *protos.MirrorStatusResponse, 1),
+	}
+	poller.watchers[w.statuses] = struct{}{}
+	if poller.last != nil {
+		w.statuses <- poller.last
+	}
+	return w
+}
+
+// mirrorNotFound reports whether polling failed because the mirror or its workflow doesn't exist,
+// other failures are retried on the next poll.
+func mirrorNotFound(err error) bool {
+	var notFound *serviceerror.NotFound
+	return errors.Is(err, pgx.ErrNoRows) || errors.As(err, &notFound)
+}
+
+// run polls the status of a mirror until it is terminated or not found, or its last watcher stops.
+func (p *mirrorStatusPollers) run(
+	ctx context.Context,
+	flowJobName string,
+	poller *mirrorStatusPoller,
+	poll pollMirrorStatusFunc,
+) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := poll(ctx)
+
+		p.mu.Lock()
+		// the poller is cancelled with the lock held once its last watcher stops
+		if ctx.Err() != nil {
+			p.mu.Unlock()
+			return
+		}
+		if status != nil {
+			poller.last = status
+			for statuses := range poller.watchers {
+				// watchers that fall behind only get the latest status
+				select {
+				case <-statuses:
+				default:
+				}
+				statuses <- status
+			}
+		}
+		if err != nil && !mirrorNotFound(err) {
+			slog.Warn("failed to poll mirror status, retrying", slog.Any("error", err),
+				slog.String(string(shared.FlowNameKey), flowJobName))
+		} else if err != nil || (status != nil && status.CurrentFlowState == protos.FlowStatus_STATUS_TERMINATED) {
+			poller.err = err
+			for statuses := range poller.watchers {
+				close(statuses)
+			}
+			poller.watchers = nil
+			delete(p.pollers, flowJobName)
+			poller.cancel()
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop stops watching, the poller of the mirror stops with its last watcher.
+func (w *mirrorStatusWatch) stop() {
+	w.pollers.mu.Lock()
+	defer w.pollers.mu.Unlock()
+
+	if _, ok := w.poller.watchers[w.statuses]; !ok {
+		return
+	}
+	delete(w.poller.watchers, w.statuses)
+	if len(w.poller.watchers) == 0 {
+		w.poller.cancel()
+		delete(w.pollers.pollers, w.flowJobName)
+	}
+}
+
+// err returns why polling stopped once the statuses are closed, nil if the mirror was terminated.
+func (w *mirrorStatusWatch) err() error {
+	w.pollers.mu.Lock()
+	defer w.pollers.mu.Unlock()
+	return w.poller.err
+}
+
+// WatchMirrorStatus sends the status of a mirror, and then sends it again whenever the workflow state changes,
+// a sync or normalize batch completes or a snapshot partition finishes. The stream ends once the mirror is terminated.
+func (h *FlowRequestHandler) WatchMirrorStatus(
+	req *protos.MirrorStatusRequest,
+	stream protos.FlowService_WatchMirrorStatusServer,
+) error {
+	ctx := stream.Context()
+	slog.Info("Watch mirror status endpoint called", slog.String(string(shared.FlowNameKey), req.FlowJobName))
+
+	watch := h.mirrorStatusPollers.watch(req.FlowJobName, func() pollMirrorStatusFunc {
+		return h.pollMirrorStatus(req.FlowJobName)
+	})
+	defer watch.stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case status, ok := <-watch.statuses:
+			if !ok {
+				return watch.err()
+			}
+			if err := stream.Send(status); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// pollMirrorStatus returns a poll of the status of a mirror, which only rebuilds the status
+// when the cheaper version of it changed.
+func (h *FlowRequestHandler) pollMirrorStatus(flowJobName string) pollMirrorStatusFunc {
+	var config *protos.FlowConnectionConfigs
+	var workflowID string
+	var lastVersion *mirrorStatusVersion
+	return func(ctx context.Context) (*protos.MirrorStatusResponse, error) {
+		if workflowID == "" {
+			cdcFlow, err := h.isCDCFlow(ctx, flowJobName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to query flow: %w", err)
+			}
+			if cdcFlow {
+				// the clones of the tables of the mirror are found from its table mappings
+				config, err = h.getFlowConfigFromCatalog(flowJobName)
+				if err != nil {
+					return nil, err
+				}
+			}
+			workflowID, err = h.getWorkflowID(ctx, flowJobName)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		version, err := h.getMirrorStatusVersion(ctx, flowJobName, workflowID, config)
+		if err != nil {
+			return nil, err
+		}
+		if lastVersion != nil && *version == *lastVersion {
+			return nil, nil
+		}
+		status, err := h.MirrorStatus(ctx, &protos.MirrorStatusRequest{FlowJobName: flowJobName})
+		if err != nil {
+			return nil, err
+		}
+		lastVersion = version
+		return status, nil
+	}
+}
+
+func (h *FlowRequestHandler) getMirrorStatusVersion(
+	ctx context.Context,
+	flowJobName string,
+	workflowID string,
+	config *protos.FlowConnectionConfigs,
+) (*mirrorStatusVersion, error) {
+	version := &mirrorStatusVersion{}
+	// only CDC mirrors clone tables, their configs are nil for other mirrors
+	cloneFlowNames := []string{}
+	if config != nil {
+		res, err := h.temporalClient.QueryWorkflow(ctx, workflowID, "", shared.CDCFlowStateQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state in workflow with ID %s: %w", workflowID, err)
+		}
+		var state peerflow.CDCFlowWorkflowState
+		if err := res.Get(&state); err != nil {
+			return nil, fmt.Errorf("failed to get state in workflow with ID %s: %w", workflowID, err)
+		}
+		stateBytes, err := json.Marshal(state)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal state of workflow with ID %s: %w", workflowID, err)
+		}
+		version.workflowState = string(stateBytes)
+		cloneFlowNames = state.CloneFlowNames(config)
+	} else {
+		state, err := h.getWorkflowStatus(ctx, workflowID)
+		if err != nil {
+			return nil, err
+		}
+		version.workflowState = state.String()
+	}
+
+	var latestLSNAtTarget pgtype.Text
+	err := h.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM peerdb_stats.cdc_batches WHERE flow_name = $1),
+			(SELECT COUNT(end_time) FROM peerdb_stats.cdc_batches WHERE flow_name = $1),
+			(SELECT latest_lsn_at_target::TEXT FROM peerdb_stats.cdc_flows WHERE flow_name = $1),
+			(SELECT COUNT(*) FROM peerdb_stats.qrep_partitions WHERE flow_name = $1 OR flow_name = ANY($2)),
+			(SELECT COUNT(end_time) FROM peerdb_stats.qrep_partitions WHERE flow_name = $1 OR flow_name = ANY($2))`,
+		flowJobName, cloneFlowNames,
+	).Scan(&version.numBatches, &version.numSyncedBatches, &latestLSNAtTarget,
+		&version.numPartitions, &version.numCompletedPartitions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query progress of mirror %s: %w", flowJobName, err)
+	}
+	version.latestLSNAtTarget = latestLSNAtTarget.String
+	return version, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/serviceerror"
+)
+
+func receiveMirrorStatus(t *testing.T, watch *mirrorStatusWatch) (*protos.MirrorStatusResponse, bool) {
+	t.Helper()
+
+	select {
+	case status, ok := <-watch.statuses:
+		return status, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for mirror status")
+		return nil, false
+	}
+}
+
+func TestMirrorStatusWatchersSharePoller(t *testing.T) {
+	pollers := newMirrorStatusPollers(time.Millisecond)
+	var numPollers, numPolls atomic.Int32
+	newPoll := func() pollMirrorStatusFunc {
+		numPollers.Add(1)
+		return func(context.Context) (*protos.MirrorStatusResponse, error) {
+			// the status only changes on the first poll
+			if numPolls.Add(1) == 1 {
+				return &protos.MirrorStatusResponse{
+					FlowJobName:      "users",
+					CurrentFlowState: protos.FlowStatus_STATUS_RUNNING,
+				}, nil
+			}
+			return nil, nil
+		}
+	}
+
+	first := pollers.watch("users", newPoll)
+	status, ok := receiveMirrorStatus(t, first)
+	require.True(t, ok)
+	require.Equal(t, protos.FlowStatus_STATUS_RUNNING, status.CurrentFlowState)
+
+	// watchers joining later get the last status without polling again
+	second := pollers.watch("users", newPoll)
+	status, ok = receiveMirrorStatus(t, second)
+	require.True(t, ok)
+	require.Equal(t, "users", status.FlowJobName)
+	require.Equal(t, int32(1), numPollers.Load())
+
+	first.stop()
+	pollers.mu.Lock()
+	require.Len(t, pollers.pollers, 1)
+	pollers.mu.Unlock()
+
+	// the poller stops with its last watcher
+	second.stop()
+	pollers.mu.Lock()
+	require.Empty(t, pollers.pollers)
+	pollers.mu.Unlock()
+}
+
+func TestMirrorStatusWatchEndsWhenTerminated(t *testing.T) {
+	pollers := newMirrorStatusPollers(time.Millisecond)
+	var numPolls atomic.Int32
+	watch := pollers.watch("users", func() pollMirrorStatusFunc {
+		return func(context.Context) (*protos.MirrorStatusResponse, error) {
+			state := protos.FlowStatus_STATUS_RUNNING
+			if numPolls.Add(1) > 2 {
+				state = protos.FlowStatus_STATUS_TERMINATED
+			}
+			return &protos.MirrorStatusResponse{FlowJobName: "users", CurrentFlowState: state}, nil
+		}
+	})
+	defer watch.stop()
+
+	// the watcher may fall behind and only get the latest status, which is the terminated one
+	var lastStatus *protos.MirrorStatusResponse
+	for {
+		status, ok := receiveMirrorStatus(t, watch)
+		if !ok {
+			break
+		}
+		lastStatus = status
+	}
+	require.Equal(t, protos.FlowStatus_STATUS_TERMINATED, lastStatus.CurrentFlowState)
+	require.NoError(t, watch.err())
+}
+
+func TestMirrorStatusWatchRetriesFailedPolls(t *testing.T) {
+	pollers := newMirrorStatusPollers(time.Millisecond)
+	var numPolls atomic.Int32
+	watch := pollers.watch("users", func() pollMirrorStatusFunc {
+		return func(context.Context) (*protos.MirrorStatusResponse, error) {
+			if numPolls.Add(1) <= 2 {
+				return nil, errors.New("catalog unavailable")
+			}
+			return &protos.MirrorStatusResponse{FlowJobName: "users", CurrentFlowState: protos.FlowStatus_STATUS_RUNNING}, nil
+		}
+	})
+	defer watch.stop()
+
+	status, ok := receiveMirrorStatus(t, watch)
+	require.True(t, ok)
+	require.Equal(t, protos.FlowStatus_STATUS_RUNNING, status.CurrentFlowState)
+	require.NoError(t, watch.err())
+}
+
+func TestMirrorStatusWatchEndsWhenMirrorNotFound(t *testing.T) {
+	pollers := newMirrorStatusPollers(time.Millisecond)
+	watch := pollers.watch("users", func() pollMirrorStatusFunc {
+		return func(context.Context) (*protos.MirrorStatusResponse, error) {
+			return nil, fmt.Errorf("unable to query flow: %w", pgx.ErrNoRows)
+		}
+	})
+	defer watch.stop()
+
+	_, ok := receiveMirrorStatus(t, watch)
+	require.False(t, ok)
+	require.ErrorIs(t, watch.err(), pgx.ErrNoRows)
+}
+
+func TestMirrorNotFound(t *testing.T) {
+	require.True(t, mirrorNotFound(fmt.Errorf("unable to get workflowID: %w", pgx.ErrNoRows)))
+	require.True(t, mirrorNotFound(fmt.Errorf("failed to get state in workflow: %w",
+		serviceerror.NewNotFound("workflow not found"))))
+	require.False(t, mirrorNotFound(errors.New("catalog unavailable")))
+}
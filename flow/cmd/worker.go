@@ -135,6 +135,8 @@ func WorkerMain(opts *WorkerOptions) error {
 	w.RegisterWorkflow(peerflow.XminFlowWorkflow)
 	w.RegisterWorkflow(peerflow.DropFlowWorkflow)
 	w.RegisterWorkflow(peerflow.HeartbeatFlowWorkflow)
+	w.RegisterWorkflow(peerflow.ResyncTableWorkflow)
+	w.RegisterWorkflow(peerflow.ValidateMirrorWorkflow)
 
 	alerter, err := alerting.NewAlerter(conn)
 	if err != nil {
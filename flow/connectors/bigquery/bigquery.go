@@ -256,7 +256,7 @@ func (c *BigQueryConnector) ReplayTableSchemaDeltas(flowJobName string,
 			_, err := c.client.Query(fmt.Sprintf(
 				"ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS `%s` %s", dstDatasetTable.dataset,
 				dstDatasetTable.table, addedColumn.ColumnName,
-				qValueKindToBigQueryType(addedColumn.ColumnType))).Read(c.ctx)
+				qValueKindToBigQueryTypeString(addedColumn.ColumnType, addedColumn.ColumnTypmod))).Read(c.ctx)
 			if err != nil {
 				return fmt.Errorf("failed to add column %s for table %s: %w", addedColumn.ColumnName,
 					schemaDelta.DstTableName, err)
@@ -549,6 +549,7 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get batch for the current mirror: %v", err)
 	}
+	batchIDs = req.ReplayBatchIDs(batchIDs)
 
 	hasJob, err := c.metadataHasJob(req.FlowJobName)
 	if err != nil {
@@ -572,6 +573,7 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get distinct table names to normalize: %w", err)
 	}
+	distinctTableNames = req.ReplayTableNames(distinctTableNames)
 
 	tableNametoUnchangedToastCols, err := c.getTableNametoUnchangedCols(
 		req.FlowJobName,
@@ -600,12 +602,24 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 			syncBatchID:           batchIDs.SyncBatchID,
 			normalizeBatchID:      batchIDs.NormalizeBatchID,
 			peerdbCols: &protos.PeerDBColumns{
-				SoftDeleteColName: req.SoftDeleteColName,
-				SyncedAtColName:   req.SyncedAtColName,
-				SoftDelete:        req.SoftDelete,
+				SoftDeleteColName:   req.SoftDeleteColName,
+				SyncedAtColName:     req.SyncedAtColName,
+				SoftDelete:          req.SoftDelete,
+				BeforeImageColName:  req.BeforeImageColName,
+				HistoryMode:         req.HistoryMode,
+				TransactionMetadata: req.TransactionMetadata,
 			},
 			shortColumn: map[string]string{},
 		}
+		if req.HistoryMode {
+			c.logger.Info(fmt.Sprintf("running history statement for table %s..", tableName))
+			historyStmt := mergeGen.generateHistoryStmt(unchangedToastColumns)
+			_, err = c.client.Query(historyStmt).Read(c.ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to execute history statement %s: %v", historyStmt, err)
+			}
+			continue
+		}
 		// normalize anything between last normalized batch id to last sync batchid
 		mergeStmts := mergeGen.generateMergeStmts(unchangedToastColumns)
 		for i, mergeStmt := range mergeStmts {
@@ -619,13 +633,16 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 		}
 	}
 	// update metadata to make the last normalized batch id to the recent last sync batch id.
-	updateMetadataStmt := fmt.Sprintf(
-		"UPDATE %s.%s SET normalize_batch_id=%d WHERE mirror_job_name='%s';",
-		c.datasetID, MirrorJobsTable, batchIDs.SyncBatchID, req.FlowJobName)
+	// replays don't normalize new batches
+	if len(req.ReplayTables) == 0 {
+		updateMetadataStmt := fmt.Sprintf(
+			"UPDATE %s.%s SET normalize_batch_id=%d WHERE mirror_job_name='%s';",
+			c.datasetID, MirrorJobsTable, batchIDs.SyncBatchID, req.FlowJobName)
 
-	_, err = c.client.Query(updateMetadataStmt).Read(c.ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute update metadata statements %s: %v", updateMetadataStmt, err)
+		_, err = c.client.Query(updateMetadataStmt).Read(c.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute update metadata statements %s: %v", updateMetadataStmt, err)
+		}
 	}
 
 	return &model.NormalizeResponse{
@@ -635,6 +652,33 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	}, nil
 }
 
+// rawTableSchema returns the schema of raw tables, see CreateRawTable
+func rawTableSchema() bigquery.Schema {
+	return bigquery.Schema{
+		{Name: "_peerdb_uid", Type: bigquery.StringFieldType},
+		{Name: "_peerdb_timestamp", Type: bigquery.IntegerFieldType},
+		{Name: "_peerdb_destination_table_name", Type: bigquery.StringFieldType},
+		{Name: "_peerdb_data", Type: bigquery.StringFieldType},
+		{Name: "_peerdb_record_type", Type: bigquery.IntegerFieldType},
+		{Name: "_peerdb_match_data", Type: bigquery.StringFieldType},
+		{Name: "_peerdb_batch_id", Type: bigquery.IntegerFieldType},
+		{Name: "_peerdb_unchanged_toast_columns", Type: bigquery.StringFieldType},
+		{Name: "_peerdb_commit_ts", Type: bigquery.IntegerFieldType},
+		{Name: "_peerdb_lsn", Type: bigquery.IntegerFieldType},
+		{Name: "_peerdb_xid", Type: bigquery.IntegerFieldType},
+	}
+}
+
+// migrateRawTableSchema returns the schema an existing raw table should be updated to,
+// raw tables created before transaction metadata was recorded are missing the trailing columns.
+// Returns false if the existing schema is not a prefix of the expected schema.
+func migrateRawTableSchema(existing bigquery.Schema, expected bigquery.Schema) (bigquery.Schema, bool) {
+	if len(existing) > len(expected) || !reflect.DeepEqual(existing, expected[:len(existing)]) {
+		return nil, false
+	}
+	return expected, true
+}
+
 // CreateRawTable creates a raw table, implementing the Connector interface.
 // create a table with the following schema
 // _peerdb_uid STRING
@@ -642,19 +686,13 @@ func (c *BigQueryConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 // _peerdb_data STRING
 // _peerdb_record_type INT - 0 for insert, 1 for update, 2 for delete
 // _peerdb_match_data STRING - json of the match data (only for update and delete)
+// _peerdb_commit_ts INT - commit time of the source transaction in nanoseconds
+// _peerdb_lsn INT - LSN of the change
+// _peerdb_xid INT - ID of the source transaction
 func (c *BigQueryConnector) CreateRawTable(req *protos.CreateRawTableInput) (*protos.CreateRawTableOutput, error) {
 	rawTableName := c.getRawTableName(req.FlowJobName)
 
-	schema := bigquery.Schema{
-		{Name: "_peerdb_uid", Type: bigquery.StringFieldType},
-		{Name: "_peerdb_timestamp", Type: bigquery.IntegerFieldType},
-		{Name: "_peerdb_destination_table_name", Type: bigquery.StringFieldType},
-		{Name: "_peerdb_data", Type: bigquery.StringFieldType},
-		{Name: "_peerdb_record_type", Type: bigquery.IntegerFieldType},
-		{Name: "_peerdb_match_data", Type: bigquery.StringFieldType},
-		{Name: "_peerdb_batch_id", Type: bigquery.IntegerFieldType},
-		{Name: "_peerdb_unchanged_toast_columns", Type: bigquery.StringFieldType},
-	}
+	schema := rawTableSchema()
 
 	// create the table
 	table := c.client.Dataset(c.datasetID).Table(rawTableName)
@@ -663,13 +701,13 @@ func (c *BigQueryConnector) CreateRawTable(req *protos.CreateRawTableInput) (*pr
 	tableRef, err := table.Metadata(c.ctx)
 	if err == nil {
 		// table exists, check if the schema matches
-		if !reflect.DeepEqual(tableRef.Schema, schema) {
-			return nil, fmt.Errorf("table %s.%s already exists with different schema", c.datasetID, rawTableName)
-		} else {
-			return &protos.CreateRawTableOutput{
-				TableIdentifier: rawTableName,
-			}, nil
+		err = c.migrateRawTable(table, tableRef)
+		if err != nil {
+			return nil, err
 		}
+		return &protos.CreateRawTableOutput{
+			TableIdentifier: rawTableName,
+		}, nil
 	}
 
 	partitioning := &bigquery.RangePartitioning{
@@ -707,6 +745,34 @@ func (c *BigQueryConnector) CreateRawTable(req *protos.CreateRawTableInput) (*pr
 	}, nil
 }
 
+// MigrateRawTable adds the columns raw tables created before transaction metadata was recorded are missing,
+// mirrors that are already running don't create their raw table again.
+func (c *BigQueryConnector) MigrateRawTable(flowJobName string) error {
+	rawTableName := c.getRawTableName(flowJobName)
+	table := c.client.Dataset(c.datasetID).Table(rawTableName)
+	tableRef, err := table.Metadata(c.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get metadata of table %s.%s: %w", c.datasetID, rawTableName, err)
+	}
+	return c.migrateRawTable(table, tableRef)
+}
+
+func (c *BigQueryConnector) migrateRawTable(table *bigquery.Table, tableRef *bigquery.TableMetadata) error {
+	schema := rawTableSchema()
+	if reflect.DeepEqual(tableRef.Schema, schema) {
+		return nil
+	}
+	migratedSchema, ok := migrateRawTableSchema(tableRef.Schema, schema)
+	if !ok {
+		return fmt.Errorf("table %s.%s already exists with different schema", c.datasetID, table.TableID)
+	}
+	_, err := table.Update(c.ctx, bigquery.TableMetadataToUpdate{Schema: migratedSchema}, tableRef.ETag)
+	if err != nil {
+		return fmt.Errorf("failed to migrate schema of table %s.%s: %w", c.datasetID, table.TableID, err)
+	}
+	return nil
+}
+
 // getUpdateMetadataStmt updates the metadata tables for a given job.
 func (c *BigQueryConnector) getUpdateMetadataStmt(jobName string, lastSyncedCheckpointID int64,
 	batchID int64,
@@ -800,13 +866,10 @@ func (c *BigQueryConnector) SetupNormalizedTables(
 		}
 
 		// convert the column names and types to bigquery types
-		columns := make([]*bigquery.FieldSchema, 0, len(tableSchema.Columns)+2)
+		columns := make([]*bigquery.FieldSchema, 0, len(tableSchema.Columns)+9)
 		utils.IterColumns(tableSchema, func(colName, genericColType string) {
-			columns = append(columns, &bigquery.FieldSchema{
-				Name:     colName,
-				Type:     qValueKindToBigQueryType(genericColType),
-				Repeated: qvalue.QValueKind(genericColType).IsArray(),
-			})
+			columns = append(columns, qValueKindToBigQueryFieldSchema(colName, genericColType,
+				utils.TableSchemaColumnTypmod(tableSchema, colName)))
 		})
 
 		if req.SoftDeleteColName != "" {
@@ -825,13 +888,61 @@ func (c *BigQueryConnector) SetupNormalizedTables(
 			})
 		}
 
+		if req.BeforeImageColName != "" {
+			columns = append(columns, &bigquery.FieldSchema{
+				Name:     req.BeforeImageColName,
+				Type:     bigquery.JSONFieldType,
+				Repeated: false,
+			})
+		}
+
+		// rows from the initial load have no start of validity
+		if req.HistoryMode {
+			columns = append(columns,
+				&bigquery.FieldSchema{
+					Name: shared.HistoryValidFromColName,
+					Type: bigquery.TimestampFieldType,
+				},
+				&bigquery.FieldSchema{
+					Name: shared.HistoryValidToColName,
+					Type: bigquery.TimestampFieldType,
+				},
+				&bigquery.FieldSchema{
+					Name:                   shared.HistoryIsCurrentColName,
+					Type:                   bigquery.BooleanFieldType,
+					DefaultValueExpression: "TRUE",
+				})
+		}
+
+		// commit time, LSN and transaction ID of the last change to the row
+		if req.TransactionMetadata {
+			columns = append(columns,
+				&bigquery.FieldSchema{
+					Name: shared.CommitTimestampColName,
+					Type: bigquery.TimestampFieldType,
+				},
+				&bigquery.FieldSchema{
+					Name: shared.LSNColName,
+					Type: bigquery.IntegerFieldType,
+				},
+				&bigquery.FieldSchema{
+					Name: shared.TransactionIDColName,
+					Type: bigquery.IntegerFieldType,
+				})
+		}
+
 		// create the table using the columns
 		schema := bigquery.Schema(columns)
 
-		// cluster by the primary key if < 4 columns.
+		// cluster by the primary key if < 4 columns, unless clustering columns are configured.
+		layout := req.TableLayoutMapping[tableIdentifier]
 		var clustering *bigquery.Clustering
 		numPkeyCols := len(tableSchema.PrimaryKeyColumns)
-		if numPkeyCols > 0 && numPkeyCols < 4 {
+		if len(layout.GetClusterColumns()) > 0 {
+			clustering = &bigquery.Clustering{
+				Fields: layout.ClusterColumns,
+			}
+		} else if numPkeyCols > 0 && numPkeyCols < 4 {
 			clustering = &bigquery.Clustering{
 				Fields: tableSchema.PrimaryKeyColumns,
 			}
@@ -842,6 +953,12 @@ func (c *BigQueryConnector) SetupNormalizedTables(
 			Name:       datasetTable.table,
 			Clustering: clustering,
 		}
+		if layout.GetPartitionColumn() != "" {
+			metadata.TimePartitioning, metadata.RangePartitioning, err = tablePartitioning(tableSchema, layout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid partitioning for table %s: %w", tableIdentifier, err)
+			}
+		}
 
 		err = table.Create(c.ctx, metadata)
 		if err != nil {
@@ -986,6 +1103,50 @@ func (c *BigQueryConnector) CreateTablesFromExisting(req *protos.CreateTablesFro
 	}, nil
 }
 
+// tablePartitioning returns the partitioning of a table by the partition column of its layout,
+// time partitioning for date and timestamp columns and integer range partitioning for integer columns.
+func tablePartitioning(
+	tableSchema *protos.TableSchema,
+	layout *protos.DestinationTableLayout,
+) (*bigquery.TimePartitioning, *bigquery.RangePartitioning, error) {
+	var columnType string
+	utils.IterColumns(tableSchema, func(columnName, genericColumnType string) {
+		if columnName == layout.PartitionColumn {
+			columnType = genericColumnType
+		}
+	})
+
+	switch qvalue.QValueKind(columnType) {
+	case qvalue.QValueKindDate, qvalue.QValueKindTimestamp, qvalue.QValueKindTimestampTZ:
+		partitioningType := bigquery.DayPartitioningType
+		if layout.TimePartitioningType != "" {
+			partitioningType = bigquery.TimePartitioningType(strings.ToUpper(layout.TimePartitioningType))
+		}
+		return &bigquery.TimePartitioning{
+			Type:  partitioningType,
+			Field: layout.PartitionColumn,
+		}, nil, nil
+	case qvalue.QValueKindInt16, qvalue.QValueKindInt32, qvalue.QValueKindInt64:
+		if layout.RangeInterval <= 0 || layout.RangeEnd <= layout.RangeStart {
+			return nil, nil, fmt.Errorf("integer range partitioning of column %s needs a positive interval "+
+				"and an end after the start", layout.PartitionColumn)
+		}
+		return nil, &bigquery.RangePartitioning{
+			Field: layout.PartitionColumn,
+			Range: &bigquery.RangePartitioningRange{
+				Start:    layout.RangeStart,
+				End:      layout.RangeEnd,
+				Interval: layout.RangeInterval,
+			},
+		}, nil
+	case "":
+		return nil, nil, fmt.Errorf("partition column %s not found", layout.PartitionColumn)
+	default:
+		return nil, nil, fmt.Errorf("partition column %s of type %s is not a date, timestamp or integer column",
+			layout.PartitionColumn, columnType)
+	}
+}
+
 type datasetTable struct {
 	dataset string
 	table   string
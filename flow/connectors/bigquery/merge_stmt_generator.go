@@ -8,6 +8,7 @@ import (
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/shared"
 )
 
 type mergeStmtGenerator struct {
@@ -23,17 +24,33 @@ type mergeStmtGenerator struct {
 	normalizeBatchID int64
 	// the schema of the table to merge into
 	normalizedTableSchema *protos.TableSchema
-	// _PEERDB_IS_DELETED and _SYNCED_AT columns
+	// _PEERDB_IS_DELETED, _SYNCED_AT and before image columns
 	peerdbCols *protos.PeerDBColumns
 	// map for shorter columns
 	shortColumn map[string]string
 }
 
+// before image of the row, taken from the match data of updates and deletes
+const beforeImageValueSQL = "IF(_d._rt!=0,PARSE_JSON(_d._md,wide_number_mode=>'round'),NULL)"
+
+// transaction metadata columns, and their values taken from the raw table
+func transactionMetadataColumnsSQL(tableAlias string) ([]string, []string) {
+	return []string{
+		fmt.Sprintf("`%s`", shared.CommitTimestampColName),
+		fmt.Sprintf("`%s`", shared.LSNColName),
+		fmt.Sprintf("`%s`", shared.TransactionIDColName),
+	}, []string{
+		fmt.Sprintf("TIMESTAMP_MICROS(DIV(%s._ct,1000))", tableAlias),
+		tableAlias + "._lsn",
+		tableAlias + "._xid",
+	}
+}
+
 // generateFlattenedCTE generates a flattened CTE.
 func (m *mergeStmtGenerator) generateFlattenedCTE() string {
 	// for each column in the normalized table, generate CAST + JSON_EXTRACT_SCALAR
 	// statement.
-	flattenedProjs := make([]string, 0, utils.TableSchemaColumns(m.normalizedTableSchema)+3)
+	flattenedProjs := make([]string, 0, utils.TableSchemaColumns(m.normalizedTableSchema)+7)
 
 	for i, colName := range m.normalizedTableSchema.ColumnNames {
 		colType := m.normalizedTableSchema.ColumnTypes[i]
@@ -41,6 +58,9 @@ func (m *mergeStmtGenerator) generateFlattenedCTE() string {
 		// CAST doesn't work for FLOAT, so rewrite it to FLOAT64.
 		if bqType == bigquery.FloatFieldType {
 			bqType = "FLOAT64"
+		} else if bqType == bigquery.NumericFieldType {
+			// cast to BIGNUMERIC if needed, so that the scale isn't lost
+			bqType, _, _ = bigQueryNumericType(utils.TableSchemaColumnTypmod(m.normalizedTableSchema, colName))
 		}
 		var castStmt string
 		shortCol := m.shortColumn[colName]
@@ -87,6 +107,10 @@ func (m *mergeStmtGenerator) generateFlattenedCTE() string {
 		"_peerdb_timestamp",
 		"_peerdb_record_type AS _rt",
 		"_peerdb_unchanged_toast_columns AS _ut",
+		"_peerdb_match_data AS _md",
+		"_peerdb_commit_ts AS _ct",
+		"_peerdb_lsn AS _lsn",
+		"_peerdb_xid AS _xid",
 	)
 
 	// normalize anything between last normalized batch id to last sync batchid
@@ -136,6 +160,15 @@ func (m *mergeStmtGenerator) generateMergeStmt(unchangedToastColumns []string) s
 	shortCsep := strings.Join(shortBacktickColNames, ", ")
 	insertColumnsSQL := csep + fmt.Sprintf(", `%s`", m.peerdbCols.SyncedAtColName)
 	insertValuesSQL := shortCsep + ",CURRENT_TIMESTAMP"
+	if m.peerdbCols.BeforeImageColName != "" {
+		insertColumnsSQL += fmt.Sprintf(", `%s`", m.peerdbCols.BeforeImageColName)
+		insertValuesSQL += "," + beforeImageValueSQL
+	}
+	txnCols, txnValues := transactionMetadataColumnsSQL("_d")
+	if m.peerdbCols.TransactionMetadata {
+		insertColumnsSQL += "," + strings.Join(txnCols, ",")
+		insertValuesSQL += "," + strings.Join(txnValues, ",")
+	}
 
 	updateStatementsforToastCols := m.generateUpdateStatements(pureColNames, unchangedToastColumns)
 	if m.peerdbCols.SoftDelete {
@@ -164,6 +197,15 @@ func (m *mergeStmtGenerator) generateMergeStmt(unchangedToastColumns []string) s
 			deletePart = fmt.Sprintf("%s,%s=CURRENT_TIMESTAMP",
 				deletePart, m.peerdbCols.SyncedAtColName)
 		}
+		if m.peerdbCols.BeforeImageColName != "" {
+			deletePart = fmt.Sprintf("%s,%s=PARSE_JSON(_d._md,wide_number_mode=>'round')",
+				deletePart, m.peerdbCols.BeforeImageColName)
+		}
+		if m.peerdbCols.TransactionMetadata {
+			for i, txnCol := range txnCols {
+				deletePart = fmt.Sprintf("%s,%s=%s", deletePart, txnCol, txnValues[i])
+			}
+		}
 	}
 
 	return fmt.Sprintf(`
@@ -230,6 +272,16 @@ func (m *mergeStmtGenerator) generateUpdateStatements(allCols []string, unchange
 			tmpArray = append(tmpArray, fmt.Sprintf("`%s`=CURRENT_TIMESTAMP",
 				m.peerdbCols.SyncedAtColName))
 		}
+		if m.peerdbCols.BeforeImageColName != "" {
+			tmpArray = append(tmpArray, fmt.Sprintf("`%s`=%s",
+				m.peerdbCols.BeforeImageColName, beforeImageValueSQL))
+		}
+		if m.peerdbCols.TransactionMetadata {
+			txnCols, txnValues := transactionMetadataColumnsSQL("_d")
+			for i, txnCol := range txnCols {
+				tmpArray = append(tmpArray, fmt.Sprintf("%s=%s", txnCol, txnValues[i]))
+			}
+		}
 		// set soft-deleted to false, tackles insert after soft-delete
 		if handleSoftDelete {
 			tmpArray = append(tmpArray, fmt.Sprintf("`%s`=FALSE",
@@ -257,3 +309,102 @@ func (m *mergeStmtGenerator) generateUpdateStatements(allCols []string, unchange
 	}
 	return updateStmts
 }
+
+// generateHistoryStmt generates a script that inserts every change as a new version of the row,
+// then closes the version that was current before this batch. Versions are valid from the commit time
+// of the transaction that made the change, falling back to the time it was read for raw rows synced without
+// commit times. Only the last change to a row within a transaction is kept, deletes only close the current version.
+// Unchanged TOAST columns take the value of the last change in the batch that had them,
+// or of the version that was current before this batch.
+func (m *mergeStmtGenerator) generateHistoryStmt(unchangedToastColumns []string) string {
+	columnCount := utils.TableSchemaColumns(m.normalizedTableSchema)
+	for i, colName := range m.normalizedTableSchema.ColumnNames {
+		m.shortColumn[colName] = fmt.Sprintf("_c%d", i)
+	}
+	pkeyCount := len(m.normalizedTableSchema.PrimaryKeyColumns)
+	shortPkeys := make([]string, 0, pkeyCount)
+	for _, pkeyCol := range m.normalizedTableSchema.PrimaryKeyColumns {
+		shortPkeys = append(shortPkeys, m.shortColumn[pkeyCol])
+	}
+	shortPkeysSQL := strings.Join(shortPkeys, ",")
+	versionOrderSQL := "ORDER BY COALESCE(_ct,_peerdb_timestamp),_lsn,_peerdb_timestamp"
+
+	toastColumns := utils.UnchangedToastColumnNames(unchangedToastColumns)
+	groupSQLArray := make([]string, 0, len(toastColumns)+2)
+	groupSQLArray = append(groupSQLArray, "COALESCE(_ct,_peerdb_timestamp) AS _vt",
+		fmt.Sprintf("LEAD(COALESCE(_ct,_peerdb_timestamp)) OVER (PARTITION BY %s %s) AS _nt", shortPkeysSQL, versionOrderSQL))
+	carrySQLArray := make([]string, 0, len(toastColumns)+1)
+	carrySQLArray = append(carrySQLArray, "*")
+	insertColumnsSQLArray := make([]string, 0, columnCount+4)
+	selectValuesSQLArray := make([]string, 0, columnCount+4)
+	for _, colName := range m.normalizedTableSchema.ColumnNames {
+		shortCol := m.shortColumn[colName]
+		insertColumnsSQLArray = append(insertColumnsSQLArray, fmt.Sprintf("`%s`", colName))
+		if _, ok := toastColumns[colName]; !ok {
+			selectValuesSQLArray = append(selectValuesSQLArray, "_v."+shortCol)
+			continue
+		}
+		// unchanged toast columns take the value of the last change in this batch that had them,
+		// or of the version that was current before this batch if there was none
+		toastIdx := len(carrySQLArray) - 1
+		groupSQLArray = append(groupSQLArray, fmt.Sprintf(
+			"COUNTIF(NOT '%s' IN UNNEST(SPLIT(_ut,','))) OVER (PARTITION BY %s %s "+
+				"ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS _tg%d",
+			colName, shortPkeysSQL, versionOrderSQL, toastIdx))
+		carrySQLArray = append(carrySQLArray, fmt.Sprintf(
+			"FIRST_VALUE(%s) OVER (PARTITION BY %s,_tg%d %s) AS _tv%d",
+			shortCol, shortPkeysSQL, toastIdx, versionOrderSQL, toastIdx))
+		selectValuesSQLArray = append(selectValuesSQLArray,
+			fmt.Sprintf("IF(_v._tg%d=0,_c.`%s`,_v._tv%d)", toastIdx, colName, toastIdx))
+	}
+	insertColumnsSQLArray = append(insertColumnsSQLArray, fmt.Sprintf("`%s`", shared.HistoryValidFromColName),
+		fmt.Sprintf("`%s`", shared.HistoryValidToColName), fmt.Sprintf("`%s`", shared.HistoryIsCurrentColName))
+	selectValuesSQLArray = append(selectValuesSQLArray,
+		"TIMESTAMP_MICROS(DIV(_v._vt,1000))",
+		"TIMESTAMP_MICROS(DIV(_v._nt,1000))",
+		"_v._nt IS NULL")
+	if m.peerdbCols.SyncedAtColName != "" {
+		insertColumnsSQLArray = append(insertColumnsSQLArray, fmt.Sprintf("`%s`", m.peerdbCols.SyncedAtColName))
+		selectValuesSQLArray = append(selectValuesSQLArray, "CURRENT_TIMESTAMP")
+	}
+	if m.peerdbCols.TransactionMetadata {
+		txnCols, txnValues := transactionMetadataColumnsSQL("_v")
+		insertColumnsSQLArray = append(insertColumnsSQLArray, txnCols...)
+		selectValuesSQLArray = append(selectValuesSQLArray, txnValues...)
+	}
+
+	curJoinSQLArray := make([]string, 0, pkeyCount)
+	verExistsSQLArray := make([]string, 0, pkeyCount)
+	firstVerJoinSQLArray := make([]string, 0, pkeyCount)
+	for _, pkeyCol := range m.normalizedTableSchema.PrimaryKeyColumns {
+		shortCol := m.shortColumn[pkeyCol]
+		curJoinSQLArray = append(curJoinSQLArray, fmt.Sprintf("_c.`%s`=_v.%s", pkeyCol, shortCol))
+		verExistsSQLArray = append(verExistsSQLArray, fmt.Sprintf("_e.`%s`=_v.%s", pkeyCol, shortCol))
+		firstVerJoinSQLArray = append(firstVerJoinSQLArray, fmt.Sprintf("_t.`%s`=_fv.%s", pkeyCol, shortCol))
+	}
+	flattenedCTE := m.generateFlattenedCTE()
+	dstTable := m.dstDatasetTable.string()
+
+	// new versions are inserted first, so that unchanged toast columns can be read from the current version
+	return fmt.Sprintf(`
+	BEGIN TRANSACTION;
+	INSERT INTO %s (%s)
+	%s,_g AS (SELECT *,%s FROM _f),_v AS (SELECT %s FROM _g)
+	SELECT %s FROM _v LEFT JOIN %s _c ON %s AND _c.`+"`%s`"+`
+	WHERE _v._rt!=2 AND _v._nt IS DISTINCT FROM _v._vt AND NOT EXISTS (SELECT 1 FROM %s _e WHERE %s
+		AND _e.`+"`%s`"+`=TIMESTAMP_MICROS(DIV(_v._vt,1000)));
+	UPDATE %s _t SET `+"`%s`"+`=TIMESTAMP_MICROS(DIV(_fv._ft,1000)),`+"`%s`"+`=FALSE
+	FROM (%s SELECT %s,MIN(COALESCE(_ct,_peerdb_timestamp)) AS _ft FROM _f GROUP BY %s) _fv
+	WHERE %s AND _t.`+"`%s`"+`
+		AND (_t.`+"`%s`"+` IS NULL OR _t.`+"`%s`"+`<TIMESTAMP_MICROS(DIV(_fv._ft,1000)));
+	COMMIT TRANSACTION;
+	`, dstTable, strings.Join(insertColumnsSQLArray, ","),
+		flattenedCTE, strings.Join(groupSQLArray, ","), strings.Join(carrySQLArray, ","),
+		strings.Join(selectValuesSQLArray, ","), dstTable, strings.Join(curJoinSQLArray, " AND "),
+		shared.HistoryIsCurrentColName,
+		dstTable, strings.Join(verExistsSQLArray, " AND "), shared.HistoryValidFromColName,
+		dstTable, shared.HistoryValidToColName, shared.HistoryIsCurrentColName,
+		flattenedCTE, shortPkeysSQL, shortPkeysSQL,
+		strings.Join(firstVerJoinSQLArray, " AND "), shared.HistoryIsCurrentColName,
+		shared.HistoryValidFromColName, shared.HistoryValidFromColName)
+}
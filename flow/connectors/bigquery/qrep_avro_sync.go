@@ -329,12 +329,21 @@ func GetAvroType(bqField *bigquery.FieldSchema) (interface{}, error) {
 				},
 			},
 		}, nil
-	case bigquery.NumericFieldType:
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		precision, scale := bqField.Precision, bqField.Scale
+		if precision == 0 {
+			// unparameterized column, use the maximum the type supports
+			if bqField.Type == bigquery.BigNumericFieldType {
+				precision, scale = qvalue.BigQueryBigNumericPrecision, qvalue.BigQueryBigNumericScale
+			} else {
+				precision, scale = qvalue.PeerDBNumericPrecision, qvalue.PeerDBNumericScale
+			}
+		}
 		return map[string]interface{}{
 			"type":        "bytes",
 			"logicalType": "decimal",
-			"precision":   38,
-			"scale":       9,
+			"precision":   precision,
+			"scale":       scale,
 		}, nil
 	case bigquery.RecordFieldType:
 		avroFields := []map[string]interface{}{}
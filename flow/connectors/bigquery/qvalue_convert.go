@@ -54,6 +54,49 @@ func qValueKindToBigQueryType(colType string) bigquery.FieldType {
 	}
 }
 
+// qValueKindToBigQueryFieldSchema returns the field schema for a column,
+// numerics get NUMERIC or BIGNUMERIC with the precision and scale from the typmod.
+func qValueKindToBigQueryFieldSchema(colName string, colType string, typmod int32) *bigquery.FieldSchema {
+	fieldSchema := &bigquery.FieldSchema{
+		Name:     colName,
+		Type:     qValueKindToBigQueryType(colType),
+		Repeated: qvalue.QValueKind(colType).IsArray(),
+	}
+	if fieldSchema.Type == bigquery.NumericFieldType {
+		fieldSchema.Type, fieldSchema.Precision, fieldSchema.Scale = bigQueryNumericType(typmod)
+	}
+	return fieldSchema
+}
+
+// qValueKindToBigQueryTypeString returns the type of a column as it is written in DDL.
+func qValueKindToBigQueryTypeString(colType string, typmod int32) string {
+	bqType := qValueKindToBigQueryType(colType)
+	if bqType != bigquery.NumericFieldType {
+		return string(bqType)
+	}
+
+	bqType, precision, scale := bigQueryNumericType(typmod)
+	if precision == 0 {
+		return string(bqType)
+	}
+	return fmt.Sprintf("%s(%d,%d)", bqType, precision, scale)
+}
+
+// bigQueryNumericType picks NUMERIC if the precision and scale from the typmod fit, BIGNUMERIC otherwise.
+// Unconstrained numerics map to BIGNUMERIC without parameters, reported as a precision of 0.
+func bigQueryNumericType(typmod int32) (bigquery.FieldType, int64, int64) {
+	if precision, _ := qvalue.ParseNumericTypmod(typmod); precision == 0 {
+		return bigquery.BigNumericFieldType, 0, 0
+	}
+
+	precision, scale := qvalue.DetermineNumericSettingForDWH(typmod, qvalue.QDWHTypeBigQuery)
+	// NUMERIC supports a scale of 9 and 29 digits before the decimal point
+	if scale <= 9 && precision-scale <= 29 {
+		return bigquery.NumericFieldType, int64(precision), int64(scale)
+	}
+	return bigquery.BigNumericFieldType, int64(precision), int64(scale)
+}
+
 // bigqueryTypeToQValueKind converts a bigquery FieldType to a QValueKind.
 func BigQueryTypeToQValueKind(fieldType bigquery.FieldType) (qvalue.QValueKind, error) {
 	switch fieldType {
@@ -75,7 +118,7 @@ func BigQueryTypeToQValueKind(fieldType bigquery.FieldType) (qvalue.QValueKind,
 		return qvalue.QValueKindTime, nil
 	case bigquery.RecordFieldType:
 		return qvalue.QValueKindStruct, nil
-	case bigquery.NumericFieldType:
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
 		return qvalue.QValueKindNumeric, nil
 	case bigquery.GeographyFieldType:
 		return qvalue.QValueKindGeography, nil
@@ -0,0 +1,26 @@
+package connbigquery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMigrateRawTableSchema(t *testing.T) {
+	expected := rawTableSchema()
+
+	// raw tables created before transaction metadata was recorded end at _peerdb_unchanged_toast_columns
+	oldSchema := rawTableSchema()[:8]
+	migrated, ok := migrateRawTableSchema(oldSchema, expected)
+	if !ok {
+		t.Fatal("expected raw table without transaction metadata columns to be migrated")
+	}
+	if !reflect.DeepEqual(migrated, expected) {
+		t.Errorf("unexpected migrated schema: %v", migrated)
+	}
+
+	mismatched := rawTableSchema()[:8]
+	mismatched[2], mismatched[3] = mismatched[3], mismatched[2]
+	if _, ok := migrateRawTableSchema(mismatched, expected); ok {
+		t.Error("expected raw table with a different schema not to be migrated")
+	}
+}
@@ -0,0 +1,56 @@
+package connbigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestTablePartitioning(t *testing.T) {
+	tableSchema := &protos.TableSchema{
+		ColumnNames: []string{"id", "created_at", "name"},
+		ColumnTypes: []string{
+			string(qvalue.QValueKindInt64),
+			string(qvalue.QValueKindTimestampTZ),
+			string(qvalue.QValueKindString),
+		},
+	}
+
+	timePartitioning, rangePartitioning, err := tablePartitioning(tableSchema, &protos.DestinationTableLayout{
+		PartitionColumn:      "created_at",
+		TimePartitioningType: "month",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rangePartitioning != nil || timePartitioning == nil ||
+		timePartitioning.Field != "created_at" || timePartitioning.Type != bigquery.MonthPartitioningType {
+		t.Errorf("unexpected time partitioning: %v, %v", timePartitioning, rangePartitioning)
+	}
+
+	timePartitioning, rangePartitioning, err = tablePartitioning(tableSchema, &protos.DestinationTableLayout{
+		PartitionColumn: "id",
+		RangeStart:      0,
+		RangeEnd:        1000000,
+		RangeInterval:   1000,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timePartitioning != nil || rangePartitioning == nil ||
+		rangePartitioning.Field != "id" || rangePartitioning.Range.Interval != 1000 {
+		t.Errorf("unexpected range partitioning: %v, %v", timePartitioning, rangePartitioning)
+	}
+
+	for _, layout := range []*protos.DestinationTableLayout{
+		{PartitionColumn: "name"},
+		{PartitionColumn: "missing"},
+		{PartitionColumn: "id"},
+	} {
+		if _, _, err := tablePartitioning(tableSchema, layout); err == nil {
+			t.Errorf("expected an error partitioning by %s", layout.PartitionColumn)
+		}
+	}
+}
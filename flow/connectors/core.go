@@ -41,7 +41,7 @@ type CDCPullConnector interface {
 	PullRecords(catalogPool *pgxpool.Pool, req *model.PullRecordsRequest) error
 
 	// PullFlowCleanup drops both the Postgres publication and replication slot, as a part of DROP MIRROR
-	PullFlowCleanup(jobName string) error
+	PullFlowCleanup(jobName string, pullFromStandby bool) error
 
 	// GetSlotInfo returns the WAL (or equivalent) info of a slot for the connector.
 	GetSlotInfo(slotName string) ([]*protos.SlotInfo, error)
@@ -133,6 +133,14 @@ type QRepConsolidateConnector interface {
 	CleanupQRepFlow(config *protos.QRepConfig) error
 }
 
+type ValidationConnector interface {
+	Connector
+
+	// ChecksumTableRange counts and checksums the rows of a table in a range of its key,
+	// the checksums of a source and a destination table match when their rows do.
+	ChecksumTableRange(req *model.TableRangeRequest) (*model.TableRangeChecksum, error)
+}
+
 func GetCDCPullConnector(ctx context.Context, config *protos.Peer) (CDCPullConnector, error) {
 	inner := config.Config
 	switch inner.(type) {
@@ -207,6 +215,20 @@ func GetQRepSyncConnector(ctx context.Context, config *protos.Peer) (QRepSyncCon
 	}
 }
 
+func GetValidationConnector(ctx context.Context, config *protos.Peer) (ValidationConnector, error) {
+	inner := config.Config
+	switch inner.(type) {
+	case *protos.Peer_PostgresConfig:
+		return connpostgres.NewPostgresConnector(ctx, config.GetPostgresConfig())
+	case *protos.Peer_BigqueryConfig:
+		return connbigquery.NewBigQueryConnector(ctx, config.GetBigqueryConfig())
+	case *protos.Peer_SnowflakeConfig:
+		return connsnowflake.NewSnowflakeConnector(ctx, config.GetSnowflakeConfig())
+	default:
+		return nil, ErrUnsupportedFunctionality
+	}
+}
+
 func GetConnector(ctx context.Context, peer *protos.Peer) (Connector, error) {
 	inner := peer.Type
 	switch inner {
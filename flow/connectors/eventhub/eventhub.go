@@ -124,10 +124,12 @@ func (c *EventHubConnector) SetLastOffset(jobName string, offset int64) error {
 func (c *EventHubConnector) processBatch(
 	flowJobName string,
 	batch *model.CDCRecordStream,
+	beforeImageKey string,
 ) (uint32, error) {
 	ctx := context.Background()
 	batchPerTopic := NewHubBatches(c.hubManager)
 	toJSONOpts := model.NewToJSONOptions(c.config.UnnestColumns)
+	toJSONOpts.BeforeImageKey = beforeImageKey
 
 	eventHubFlushTimeout := peerdbenv.PeerDBEventhubFlushTimeoutSeconds()
 
@@ -169,7 +171,7 @@ func (c *EventHubConnector) processBatch(
 				lastSeenLSN = recordLSN
 			}
 
-			json, err := record.GetItems().ToJSONWithOpts(toJSONOpts)
+			json, err := model.RecordToJSONWithOpts(record, toJSONOpts)
 			if err != nil {
 				c.logger.Info("failed to convert record to json: %v", err)
 				return 0, err
@@ -228,7 +230,7 @@ func (c *EventHubConnector) processBatch(
 func (c *EventHubConnector) SyncRecords(req *model.SyncRecordsRequest) (*model.SyncResponse, error) {
 	batch := req.Records
 
-	numRecords, err := c.processBatch(req.FlowJobName, batch)
+	numRecords, err := c.processBatch(req.FlowJobName, batch, req.BeforeImageColName)
 	if err != nil {
 		c.logger.Error("failed to process batch", slog.Any("error", err))
 		return nil, err
@@ -3,14 +3,18 @@ package connpostgres
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
 	"time"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/connectors/utils/cdc_records"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/geo"
 	"github.com/PeerDB-io/peer-flow/model"
@@ -41,7 +45,25 @@ type PostgresCDCSource struct {
 	commitLock             bool
 	customTypeMapping      map[uint32]string
 
-	// for partitioned tables, maps child relid to parent relid
+	// transaction ID and commit time of the transaction being decoded, from its BeginMessage
+	currentXid        uint32
+	currentCommitTime time.Time
+
+	// with protocol version 2, large transactions are streamed before they commit.
+	// inStream is set between the start and stop of a block of changes of streamed transaction streamXid.
+	streamTransactions bool
+	inStream           bool
+	streamXid          uint32
+
+	// logical decoding messages with these prefixes are captured into logicalMessageTable
+	logicalMessagePrefixes []string
+	logicalMessageTable    string
+
+	// only pull changes made on the source itself, not ones applied to it by replication
+	skipReplicatedChanges bool
+
+	// for partitioned tables, maps child relid to parent relid.
+	// partitions created or attached later are added when their relation message is seen.
 	childToParentRelIDMapping map[uint32]uint32
 	logger                    slog.Logger
 
@@ -49,6 +71,23 @@ type PostgresCDCSource struct {
 	catalogPool *pgxpool.Pool
 	flowJobName string
 
+	// non-replication connections to the source, for backfilling unchanged TOAST columns
+	// and reading incremental snapshots
+	queryPool *pgxpool.Pool
+
+	// tables being snapshotted incrementally and the chunk being read, see cdc_snapshot.go
+	snapshotTables    []*monitoring.IncrementalSnapshotProgress
+	snapshotChunkSize int
+	snapshotChunk     *snapshotChunk
+	snapshotPending   map[string]*pendingSnapshotChunk
+
+	// records whose values fail to convert are stored in the dead letter table and skipped
+	deadLetterFailedRecords bool
+
+	// column the source of records is stored in, for mirrors from several sources into the same tables
+	sourceColName  string
+	sourceColValue string
+
 	walSegmentRemovedRegex *regexp.Regexp
 }
 
@@ -63,6 +102,29 @@ type PostgresCDCConfig struct {
 	CatalogPool            *pgxpool.Pool
 	FlowJobName            string
 	SetLastOffset          func(int64) error
+	QueryPool              *pgxpool.Pool
+	// stream large transactions before they commit, requires Postgres 14 or later
+	StreamTransactions bool
+	// capture logical decoding messages with these prefixes into LogicalMessageTable
+	LogicalMessagePrefixes []string
+	LogicalMessageTable    string
+	// skip changes with a replication origin, requires Postgres 16 or later
+	SkipReplicatedChanges bool
+	// tables to snapshot incrementally while pulling changes, requires Postgres 14 or later
+	IncrementalSnapshotTables    []*monitoring.IncrementalSnapshotProgress
+	IncrementalSnapshotChunkSize int
+	// store records whose values fail to convert in the dead letter table of the catalog and skip them
+	DeadLetterFailedRecords bool
+	// column the source of records is stored in, for mirrors from several sources into the same tables
+	SourceColName  string
+	SourceColValue string
+}
+
+// streamedTxnStore buffers the changes of streamed transactions until they commit or abort
+type streamedTxnStore interface {
+	AddStreamedRecord(xid uint32, subXid uint32, rec model.Record) error
+	CommitStreamedTxn(xid uint32, processRecord func(model.Record) error) error
+	AbortStreamedTxn(xid uint32, subXid uint32) error
 }
 
 type startReplicationOpts struct {
@@ -81,6 +143,22 @@ func NewPostgresCDCSource(cdcConfig *PostgresCDCConfig, customTypeMap map[uint32
 	pattern := "requested WAL segment .* has already been removed.*"
 	regex := regexp.MustCompile(pattern)
 
+	// only partitions of mirrored tables are mapped, they are looked up as their parent.
+	// partitions of nested partitioned tables are resolved once their relation message is seen.
+	for childRelID, parentRelID := range childToParentRelIDMap {
+		parentName, ok := cdcConfig.SrcTableIDNameMapping[parentRelID]
+		if !ok {
+			delete(childToParentRelIDMap, childRelID)
+			continue
+		}
+		cdcConfig.SrcTableIDNameMapping[childRelID] = parentName
+	}
+
+	snapshotChunkSize := cdcConfig.IncrementalSnapshotChunkSize
+	if snapshotChunkSize <= 0 {
+		snapshotChunkSize = DefaultIncrementalSnapshotChunkSize
+	}
+
 	flowName, _ := cdcConfig.AppContext.Value(shared.FlowNameKey).(string)
 	return &PostgresCDCSource{
 		ctx:                       cdcConfig.AppContext,
@@ -98,6 +176,17 @@ func NewPostgresCDCSource(cdcConfig *PostgresCDCConfig, customTypeMap map[uint32
 		logger:                    *slog.With(slog.String(string(shared.FlowNameKey), flowName)),
 		catalogPool:               cdcConfig.CatalogPool,
 		flowJobName:               cdcConfig.FlowJobName,
+		queryPool:                 cdcConfig.QueryPool,
+		streamTransactions:        cdcConfig.StreamTransactions,
+		logicalMessagePrefixes:    cdcConfig.LogicalMessagePrefixes,
+		logicalMessageTable:       cdcConfig.LogicalMessageTable,
+		skipReplicatedChanges:     cdcConfig.SkipReplicatedChanges,
+		snapshotTables:            cdcConfig.IncrementalSnapshotTables,
+		snapshotChunkSize:         snapshotChunkSize,
+		snapshotPending:           make(map[string]*pendingSnapshotChunk),
+		deadLetterFailedRecords:   cdcConfig.DeadLetterFailedRecords,
+		sourceColName:             cdcConfig.SourceColName,
+		sourceColValue:            cdcConfig.SourceColValue,
 		walSegmentRemovedRegex:    regex,
 	}, nil
 }
@@ -134,6 +223,64 @@ func getChildToParentRelIDMap(ctx context.Context, pool *pgxpool.Pool) (map[uint
 	return childToParentRelIDMap, nil
 }
 
+// getMirroredAncestorRelID looks up the closest partitioned ancestor of a relation that is mirrored.
+// It runs on the non-replication connection, as the replication connection is busy streaming.
+func (p *PostgresCDCSource) getMirroredAncestorRelID(relID uint32) (uint32, bool, error) {
+	rows, err := p.queryPool.Query(p.ctx, `WITH RECURSIVE ancestors(relid,depth) AS (
+			SELECT inhparent,1 FROM pg_inherits WHERE inhrelid=$1
+			UNION ALL
+			SELECT i.inhparent,a.depth+1 FROM pg_inherits i JOIN ancestors a ON i.inhrelid=a.relid
+		)
+		SELECT a.relid FROM ancestors a JOIN pg_class c ON c.oid=a.relid
+		WHERE c.relkind='p' ORDER BY a.depth`, relID)
+	if err != nil {
+		return 0, false, fmt.Errorf("error querying ancestors of relation %d: %w", relID, err)
+	}
+
+	ancestorRelIDs, err := pgx.CollectRows(rows, pgx.RowTo[uint32])
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading ancestors of relation %d: %w", relID, err)
+	}
+	for _, ancestorRelID := range ancestorRelIDs {
+		if _, isPartition := p.childToParentRelIDMapping[ancestorRelID]; isPartition {
+			continue
+		}
+		if _, ok := p.SrcTableIDNameMapping[ancestorRelID]; ok {
+			return ancestorRelID, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// resolvePartition keeps the partition mappings of a relation in sync with pg_inherits.
+// Postgres sends a relation message before the first change of a relation in a session, and again
+// after it was attached or detached, so this runs before any change of a new partition is decoded.
+func (p *PostgresCDCSource) resolvePartition(relID uint32) error {
+	_, isPartition := p.childToParentRelIDMapping[relID]
+	if _, ok := p.SrcTableIDNameMapping[relID]; ok && !isPartition {
+		return nil
+	}
+
+	parentRelID, found, err := p.getMirroredAncestorRelID(relID)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		if p.childToParentRelIDMapping[relID] != parentRelID {
+			p.logger.Info(fmt.Sprintf("relation %d is a partition of %s, mapping its changes to it",
+				relID, p.SrcTableIDNameMapping[parentRelID]))
+		}
+		p.childToParentRelIDMapping[relID] = parentRelID
+		p.SrcTableIDNameMapping[relID] = p.SrcTableIDNameMapping[parentRelID]
+	} else if isPartition {
+		p.logger.Info(fmt.Sprintf("relation %d is no longer a partition of a mirrored table", relID))
+		delete(p.childToParentRelIDMapping, relID)
+		delete(p.SrcTableIDNameMapping, relID)
+	}
+	return nil
+}
+
 // PullRecords pulls records from the cdc stream
 func (p *PostgresCDCSource) PullRecords(req *model.PullRecordsRequest) error {
 	replicationOpts, err := p.replicationOptions()
@@ -173,7 +320,11 @@ func (p *PostgresCDCSource) PullRecords(req *model.PullRecordsRequest) error {
 
 	p.logger.Info(fmt.Sprintf("started replication on slot %s at startLSN: %d", p.slot, startLSN))
 
-	return p.consumeStream(pgConn, req, clientXLogPos, req.RecordStream)
+	err = p.consumeStream(pgConn, req, clientXLogPos, req.RecordStream)
+	if err != nil {
+		return err
+	}
+	return p.savePendingSnapshotChunks()
 }
 
 func (p *PostgresCDCSource) startReplication(opts startReplicationOpts) error {
@@ -191,6 +342,18 @@ func (p *PostgresCDCSource) replicationOptions() (*pglogrepl.StartReplicationOpt
 	pluginArguments := []string{
 		"proto_version '1'",
 	}
+	if p.streamTransactions {
+		pluginArguments = []string{
+			"proto_version '2'",
+			"streaming 'on'",
+		}
+	}
+	if len(p.logicalMessagePrefixes) > 0 || len(p.snapshotTables) > 0 {
+		pluginArguments = append(pluginArguments, "messages 'true'")
+	}
+	if p.skipReplicatedChanges {
+		pluginArguments = append(pluginArguments, "origin 'none'")
+	}
 
 	if p.publication != "" {
 		pubOpt := fmt.Sprintf("publication_names '%s'", p.publication)
@@ -208,7 +371,7 @@ func (p *PostgresCDCSource) consumeStream(
 	req *model.PullRecordsRequest,
 	clientXLogPos pglogrepl.LSN,
 	records *model.CDCRecordStream,
-) error {
+) (retErr error) {
 	defer func() {
 		err := conn.Close(p.ctx)
 		if err != nil {
@@ -254,8 +417,40 @@ func (p *PostgresCDCSource) consumeStream(
 	standbyMessageTimeout := req.IdleTimeout
 	nextStandbyMessageDeadline := time.Now().Add(standbyMessageTimeout)
 
+	// records with unchanged TOAST columns are held back until their values are fetched from the source,
+	// records after them are held back as well to preserve ordering.
+	var pendingToastRecords []model.Record
+	flushPendingToastRecords := func() error {
+		if len(pendingToastRecords) == 0 {
+			return nil
+		}
+		err := p.backfillUnchangedToastColumns(req, pendingToastRecords)
+		if err != nil {
+			return fmt.Errorf("failed to backfill unchanged toast columns: %w", err)
+		}
+		for _, rec := range pendingToastRecords {
+			records.AddRecord(rec)
+		}
+		pendingToastRecords = nil
+		return nil
+	}
+	defer func() {
+		if retErr == nil {
+			retErr = flushPendingToastRecords()
+		}
+	}()
+
 	addRecordWithKey := func(key model.TableWithPkey, rec model.Record) error {
-		records.AddRecord(rec)
+		if req.BackfillUnchangedToastColumns && (len(pendingToastRecords) > 0 || hasUnchangedToastColumns(rec)) {
+			pendingToastRecords = append(pendingToastRecords, rec)
+			if len(pendingToastRecords) >= maxPendingToastRecords {
+				if err := flushPendingToastRecords(); err != nil {
+					return err
+				}
+			}
+		} else {
+			records.AddRecord(rec)
+		}
 		err := cdcRecordsStorage.Set(key, rec)
 		if err != nil {
 			return err
@@ -277,6 +472,126 @@ func (p *PostgresCDCSource) consumeStream(
 		return addRecordWithKey(*key, rec)
 	}
 
+	// processRecord adds a decoded change to the batch, merging it with earlier changes to the same row
+	processRecord := func(rec model.Record) error {
+		var err error
+		tableName := rec.GetDestinationTableName()
+		p.dropSnapshotChunkRows(req, rec)
+		switch r := rec.(type) {
+		case *model.UpdateRecord:
+			// tableName here is destination tableName.
+			// should be ideally sourceTableName as we are in PullRecords.
+			// will change in future
+			isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
+			if isFullReplica {
+				err = addRecord(rec)
+				if err != nil {
+					return err
+				}
+			} else {
+				tablePkeyVal, err := p.recToTablePKey(req, rec)
+				if err != nil {
+					return err
+				}
+
+				latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					err = addRecordWithKey(*tablePkeyVal, rec)
+				} else {
+					// iterate through unchanged toast cols and set them in new record
+					updatedCols := r.NewItems.UpdateIfNotExists(latestRecord.GetItems())
+					for _, col := range updatedCols {
+						delete(r.UnchangedToastColumns, col)
+					}
+					// without REPLICA IDENTITY FULL the old tuple only has the key,
+					// the latest record of this row in the batch holds the rest of the before image
+					if req.CaptureBeforeImages {
+						r.OldItems.UpdateIfNotExists(latestRecord.GetItems())
+					}
+					err = addRecordWithKey(*tablePkeyVal, rec)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+		case *model.InsertRecord:
+			isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
+			if isFullReplica {
+				err = addRecord(rec)
+				if err != nil {
+					return err
+				}
+			} else {
+				tablePkeyVal, err := p.recToTablePKey(req, rec)
+				if err != nil {
+					return err
+				}
+
+				err = addRecordWithKey(*tablePkeyVal, rec)
+				if err != nil {
+					return err
+				}
+			}
+		case *model.DeleteRecord:
+			tablePkeyVal, err := p.recToTablePKey(req, rec)
+			if err != nil {
+				return err
+			}
+
+			latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
+			if err != nil {
+				return err
+			}
+			if ok {
+				deleteRecord := rec.(*model.DeleteRecord)
+				deleteRecord.Items = latestRecord.GetItems()
+				updateRecord, ok := latestRecord.(*model.UpdateRecord)
+				if ok {
+					deleteRecord.UnchangedToastColumns = updateRecord.UnchangedToastColumns
+				}
+			} else {
+				deleteRecord := rec.(*model.DeleteRecord)
+				// there is nothing to backfill the items in the delete record with,
+				// so don't update the row with this record
+				// add sentinel value to prevent update statements from selecting
+				deleteRecord.UnchangedToastColumns = map[string]struct{}{
+					"_peerdb_not_backfilled_delete": {},
+				}
+			}
+
+			err = addRecord(rec)
+			if err != nil {
+				return err
+			}
+		case *model.MessageRecord:
+			// every message is a new row, keyed by its LSN
+			err = addRecordWithKey(model.TableWithPkey{
+				TableName:  tableName,
+				PkeyColVal: sha256.Sum256([]byte(fmt.Sprintf("%d", r.CheckPointID))),
+			}, rec)
+			if err != nil {
+				return err
+			}
+		case *snapshotWatermark:
+			err = p.processSnapshotWatermark(records, r, addRecordWithKey)
+			if err != nil {
+				return err
+			}
+		case *model.RelationRecord:
+			tableSchemaDelta := r.TableSchemaDelta
+			if len(tableSchemaDelta.AddedColumns) > 0 {
+				p.logger.Info(fmt.Sprintf("Detected schema change for table %s, addedColumns: %v",
+					tableSchemaDelta.SrcTableName, tableSchemaDelta.AddedColumns))
+				records.SchemaDeltas <- tableSchemaDelta
+			}
+		}
+		return err
+	}
+
 	pkmRequiresResponse := false
 	waitingForCommit := false
 	retryAttemptForWALSegmentRemoved := 0
@@ -297,7 +612,9 @@ func (p *PostgresCDCSource) consumeStream(
 			}
 		}
 
-		if !p.commitLock {
+		// the batch is held back during transactions and while a chunk of an incremental snapshot is pending
+		holdBatch := p.commitLock || p.snapshotChunkPending()
+		if !holdBatch {
 			if cdcRecordsStorage.Len() >= int(req.MaxBatchSize) {
 				return nil
 			}
@@ -310,6 +627,16 @@ func (p *PostgresCDCSource) consumeStream(
 				)
 				return nil
 			}
+
+			// read the next chunk of an incremental snapshot if the batch has room for it
+			if len(p.snapshotTables) > 0 && (cdcRecordsStorage.IsEmpty() ||
+				cdcRecordsStorage.Len()+p.snapshotChunkSize <= int(req.MaxBatchSize)) {
+				err := p.readSnapshotChunk(req)
+				if err != nil {
+					return err
+				}
+				holdBatch = p.snapshotChunkPending()
+			}
 		}
 
 		// if we are past the next standby deadline (?)
@@ -320,7 +647,7 @@ func (p *PostgresCDCSource) consumeStream(
 					cdcRecordsStorage.Len()),
 				)
 
-				if !p.commitLock {
+				if !holdBatch {
 					// immediate return if we are not waiting for a commit
 					return nil
 				}
@@ -351,7 +678,7 @@ func (p *PostgresCDCSource) consumeStream(
 			return fmt.Errorf("consumeStream preempted: %w", ctxErr)
 		}
 
-		if err != nil && !p.commitLock {
+		if err != nil && !holdBatch {
 			if pgconn.Timeout(err) {
 				p.logger.Info(fmt.Sprintf("Stand-by deadline reached, returning currently accumulated records - %d",
 					cdcRecordsStorage.Len()))
@@ -411,105 +738,24 @@ func (p *PostgresCDCSource) consumeStream(
 
 			p.logger.Debug(fmt.Sprintf("XLogData => WALStart %s ServerWALEnd %s ServerTime %s\n",
 				xld.WALStart, xld.ServerWALEnd, xld.ServerTime))
-			rec, err := p.processMessage(records, xld, clientXLogPos)
+			logicalMsg, err := p.parseLogicalMessage(xld.WALData)
+			if err != nil {
+				return fmt.Errorf("error parsing logical message: %w", err)
+			}
+
+			var rec model.Record
+			handled, err := p.processStreamMessage(records, xld.WALStart, logicalMsg, cdcRecordsStorage, processRecord)
+			if err == nil && !handled {
+				rec, err = p.processMessage(records, xld, logicalMsg, clientXLogPos)
+			}
 			if err != nil {
 				return fmt.Errorf("error processing message: %w", err)
 			}
 
 			if rec != nil {
-				tableName := rec.GetDestinationTableName()
-				switch r := rec.(type) {
-				case *model.UpdateRecord:
-					// tableName here is destination tableName.
-					// should be ideally sourceTableName as we are in PullRecords.
-					// will change in future
-					isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
-					if isFullReplica {
-						err = addRecord(rec)
-						if err != nil {
-							return err
-						}
-					} else {
-						tablePkeyVal, err := p.recToTablePKey(req, rec)
-						if err != nil {
-							return err
-						}
-
-						latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
-						if err != nil {
-							return err
-						}
-						if !ok {
-							err = addRecordWithKey(*tablePkeyVal, rec)
-						} else {
-							// iterate through unchanged toast cols and set them in new record
-							updatedCols := r.NewItems.UpdateIfNotExists(latestRecord.GetItems())
-							for _, col := range updatedCols {
-								delete(r.UnchangedToastColumns, col)
-							}
-							err = addRecordWithKey(*tablePkeyVal, rec)
-						}
-						if err != nil {
-							return err
-						}
-					}
-
-				case *model.InsertRecord:
-					isFullReplica := req.TableNameSchemaMapping[tableName].IsReplicaIdentityFull
-					if isFullReplica {
-						err = addRecord(rec)
-						if err != nil {
-							return err
-						}
-					} else {
-						tablePkeyVal, err := p.recToTablePKey(req, rec)
-						if err != nil {
-							return err
-						}
-
-						err = addRecordWithKey(*tablePkeyVal, rec)
-						if err != nil {
-							return err
-						}
-					}
-				case *model.DeleteRecord:
-					tablePkeyVal, err := p.recToTablePKey(req, rec)
-					if err != nil {
-						return err
-					}
-
-					latestRecord, ok, err := cdcRecordsStorage.Get(*tablePkeyVal)
-					if err != nil {
-						return err
-					}
-					if ok {
-						deleteRecord := rec.(*model.DeleteRecord)
-						deleteRecord.Items = latestRecord.GetItems()
-						updateRecord, ok := latestRecord.(*model.UpdateRecord)
-						if ok {
-							deleteRecord.UnchangedToastColumns = updateRecord.UnchangedToastColumns
-						}
-					} else {
-						deleteRecord := rec.(*model.DeleteRecord)
-						// there is nothing to backfill the items in the delete record with,
-						// so don't update the row with this record
-						// add sentinel value to prevent update statements from selecting
-						deleteRecord.UnchangedToastColumns = map[string]struct{}{
-							"_peerdb_not_backfilled_delete": {},
-						}
-					}
-
-					err = addRecord(rec)
-					if err != nil {
-						return err
-					}
-				case *model.RelationRecord:
-					tableSchemaDelta := r.TableSchemaDelta
-					if len(tableSchemaDelta.AddedColumns) > 0 {
-						p.logger.Info(fmt.Sprintf("Detected schema change for table %s, addedColumns: %v",
-							tableSchemaDelta.SrcTableName, tableSchemaDelta.AddedColumns))
-						records.SchemaDeltas <- tableSchemaDelta
-					}
+				err = processRecord(rec)
+				if err != nil {
+					return err
 				}
 			}
 
@@ -520,19 +766,145 @@ func (p *PostgresCDCSource) consumeStream(
 	}
 }
 
-func (p *PostgresCDCSource) processMessage(batch *model.CDCRecordStream, xld pglogrepl.XLogData,
-	currentClientXlogPos pglogrepl.LSN,
-) (model.Record, error) {
-	logicalMsg, err := pglogrepl.Parse(xld.WALData)
+// parseLogicalMessage parses a pgoutput message. Outside of streamed transactions,
+// messages of protocol version 2 are unwrapped to their version 1 equivalents.
+func (p *PostgresCDCSource) parseLogicalMessage(data []byte) (pglogrepl.Message, error) {
+	if !p.streamTransactions {
+		return pglogrepl.Parse(data)
+	}
+
+	logicalMsg, err := pglogrepl.ParseV2(data, p.inStream)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing logical message: %w", err)
+		return nil, err
+	}
+	switch msg := logicalMsg.(type) {
+	case *pglogrepl.InsertMessageV2:
+		if !p.inStream {
+			return &msg.InsertMessage, nil
+		}
+	case *pglogrepl.UpdateMessageV2:
+		if !p.inStream {
+			return &msg.UpdateMessage, nil
+		}
+	case *pglogrepl.DeleteMessageV2:
+		if !p.inStream {
+			return &msg.DeleteMessage, nil
+		}
+	case *pglogrepl.RelationMessageV2:
+		return &msg.RelationMessage, nil
+	case *pglogrepl.TypeMessageV2:
+		return &msg.TypeMessage, nil
+	case *pglogrepl.TruncateMessageV2:
+		return &msg.TruncateMessage, nil
+	case *pglogrepl.LogicalDecodingMessageV2:
+		if !p.inStream {
+			return &msg.LogicalDecodingMessage, nil
+		}
+	}
+	return logicalMsg, nil
+}
+
+// processStreamMessage handles the messages of transactions streamed before their commit.
+// Their changes are buffered until the transaction commits, then passed to processRecord.
+// Returns false for messages that are not part of a streamed transaction.
+func (p *PostgresCDCSource) processStreamMessage(
+	batch *model.CDCRecordStream,
+	lsn pglogrepl.LSN,
+	logicalMsg pglogrepl.Message,
+	store streamedTxnStore,
+	processRecord func(model.Record) error,
+) (bool, error) {
+	var rec model.Record
+	var subXid uint32
+	var err error
+
+	switch msg := logicalMsg.(type) {
+	case *pglogrepl.StreamStartMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamStartMessage => XID: %v, FirstSegment: %v", msg.Xid, msg.FirstSegment))
+		p.inStream = true
+		p.streamXid = msg.Xid
+		return true, nil
+	case *pglogrepl.StreamStopMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamStopMessage => XID: %v", p.streamXid))
+		p.inStream = false
+		return true, nil
+	case *pglogrepl.StreamCommitMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamCommitMessage => XID: %v, CommitLSN: %v, TransactionEndLSN: %v",
+			msg.Xid, msg.CommitLSN, msg.TransactionEndLSN))
+		err := store.CommitStreamedTxn(msg.Xid, func(rec model.Record) error {
+			setTransactionMetadata(rec, msg.Xid, msg.CommitTime)
+			return processRecord(rec)
+		})
+		if err != nil {
+			return true, fmt.Errorf("error committing streamed transaction %d: %w", msg.Xid, err)
+		}
+		batch.UpdateLatestCheckpoint(int64(msg.CommitLSN))
+		return true, nil
+	case *pglogrepl.StreamAbortMessageV2:
+		p.logger.Debug(fmt.Sprintf("StreamAbortMessage => XID: %v, SubXID: %v", msg.Xid, msg.SubXid))
+		err := store.AbortStreamedTxn(msg.Xid, msg.SubXid)
+		if err != nil {
+			return true, fmt.Errorf("error aborting streamed transaction %d: %w", msg.Xid, err)
+		}
+		return true, nil
+	case *pglogrepl.InsertMessageV2:
+		rec, err = p.processInsertMessage(lsn, &msg.InsertMessage)
+		subXid = msg.Xid
+	case *pglogrepl.UpdateMessageV2:
+		rec, err = p.processUpdateMessage(lsn, &msg.UpdateMessage)
+		subXid = msg.Xid
+	case *pglogrepl.DeleteMessageV2:
+		rec, err = p.processDeleteMessage(lsn, &msg.DeleteMessage)
+		subXid = msg.Xid
+	case *pglogrepl.LogicalDecodingMessageV2:
+		rec, err = p.processLogicalDecodingMessage(&msg.LogicalDecodingMessage)
+		if !msg.Transactional {
+			// non-transactional messages are not part of the streamed transaction
+			if err != nil || rec == nil {
+				return true, err
+			}
+			return true, processRecord(rec)
+		}
+		subXid = msg.Xid
+	default:
+		return false, nil
+	}
+
+	if err != nil || rec == nil {
+		return true, err
 	}
+	return true, store.AddStreamedRecord(p.streamXid, subXid, rec)
+}
+
+// setTransactionMetadata sets the transaction ID and commit time of a change,
+// for streamed transactions these are only known once the transaction commits.
+func setTransactionMetadata(rec model.Record, xid uint32, commitTime time.Time) {
+	switch r := rec.(type) {
+	case *model.InsertRecord:
+		r.TransactionID = xid
+		r.CommitTime = commitTime
+	case *model.UpdateRecord:
+		r.TransactionID = xid
+		r.CommitTime = commitTime
+	case *model.DeleteRecord:
+		r.TransactionID = xid
+		r.CommitTime = commitTime
+	case *model.MessageRecord:
+		r.TransactionID = xid
+		r.CommitTime = commitTime
+	}
+}
 
+func (p *PostgresCDCSource) processMessage(batch *model.CDCRecordStream, xld pglogrepl.XLogData,
+	logicalMsg pglogrepl.Message, currentClientXlogPos pglogrepl.LSN,
+) (model.Record, error) {
 	switch msg := logicalMsg.(type) {
 	case *pglogrepl.BeginMessage:
 		p.logger.Debug(fmt.Sprintf("BeginMessage => FinalLSN: %v, XID: %v", msg.FinalLSN, msg.Xid))
 		p.logger.Debug("Locking PullRecords at BeginMessage, awaiting CommitMessage")
 		p.commitLock = true
+		p.currentXid = msg.Xid
+		p.currentCommitTime = msg.CommitTime
 	case *pglogrepl.InsertMessage:
 		return p.processInsertMessage(xld.WALStart, msg)
 	case *pglogrepl.UpdateMessage:
@@ -546,6 +918,10 @@ func (p *PostgresCDCSource) processMessage(batch *model.CDCRecordStream, xld pgl
 		batch.UpdateLatestCheckpoint(int64(msg.CommitLSN))
 		p.commitLock = false
 	case *pglogrepl.RelationMessage:
+		if err := p.resolvePartition(msg.RelationID); err != nil {
+			return nil, err
+		}
+
 		// treat all relation messages as corresponding to parent if partitioned.
 		msg.RelationID = p.getParentRelIDIfPartitioned(msg.RelationID)
 
@@ -568,11 +944,42 @@ func (p *PostgresCDCSource) processMessage(batch *model.CDCRecordStream, xld pgl
 
 	case *pglogrepl.TruncateMessage:
 		p.logger.Warn("TruncateMessage not supported")
+	case *pglogrepl.LogicalDecodingMessage:
+		return p.processLogicalDecodingMessage(msg)
 	}
 
 	return nil, nil
 }
 
+// processLogicalDecodingMessage converts a message emitted with pg_logical_emit_message to a record,
+// messages whose prefix is not captured are skipped.
+func (p *PostgresCDCSource) processLogicalDecodingMessage(msg *pglogrepl.LogicalDecodingMessage) (model.Record, error) {
+	if msg.Prefix == incrementalSnapshotMessagePrefix && !msg.Transactional {
+		if wm := parseSnapshotWatermark(msg); wm != nil {
+			return wm, nil
+		}
+		return nil, nil
+	}
+	if p.logicalMessageTable == "" || !slices.Contains(p.logicalMessagePrefixes, msg.Prefix) {
+		return nil, nil
+	}
+
+	p.logger.Debug(fmt.Sprintf("LogicalDecodingMessage => LSN: %v, Prefix: %s, Transactional: %v",
+		msg.LSN, msg.Prefix, msg.Transactional))
+	rec := &model.MessageRecord{
+		DestinationTableName: p.logicalMessageTable,
+		CheckPointID:         int64(msg.LSN),
+		Prefix:               msg.Prefix,
+		Content:              msg.Content,
+		Transactional:        msg.Transactional,
+	}
+	if msg.Transactional {
+		rec.TransactionID = p.currentXid
+		rec.CommitTime = p.currentCommitTime
+	}
+	return rec, nil
+}
+
 func (p *PostgresCDCSource) processInsertMessage(
 	lsn pglogrepl.LSN,
 	msg *pglogrepl.InsertMessage,
@@ -596,11 +1003,15 @@ func (p *PostgresCDCSource) processInsertMessage(
 	// create empty map of string to interface{}
 	items, _, err := p.convertTupleToMap(msg.Tuple, rel, p.TableNameMapping[tableName].Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("error converting tuple to map: %w", err)
+		return nil, p.deadLetterRecord(lsn, tableName, msg.Tuple, rel,
+			fmt.Errorf("error converting tuple to map: %w", err))
 	}
+	p.addSourceColumn(items)
 
 	return &model.InsertRecord{
 		CheckPointID:         int64(lsn),
+		TransactionID:        p.currentXid,
+		CommitTime:           p.currentCommitTime,
 		Items:                items,
 		DestinationTableName: p.TableNameMapping[tableName].Name,
 		SourceTableName:      tableName,
@@ -631,17 +1042,25 @@ func (p *PostgresCDCSource) processUpdateMessage(
 	// create empty map of string to interface{}
 	oldItems, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName].Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("error converting old tuple to map: %w", err)
+		return nil, p.deadLetterRecord(lsn, tableName, msg.OldTuple, rel,
+			fmt.Errorf("error converting old tuple to map: %w", err))
 	}
 
 	newItems, unchangedToastColumns, err := p.convertTupleToMap(msg.NewTuple,
 		rel, p.TableNameMapping[tableName].Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("error converting new tuple to map: %w", err)
+		return nil, p.deadLetterRecord(lsn, tableName, msg.NewTuple, rel,
+			fmt.Errorf("error converting new tuple to map: %w", err))
+	}
+	p.addSourceColumn(newItems)
+	if msg.OldTuple != nil {
+		p.addSourceColumn(oldItems)
 	}
 
 	return &model.UpdateRecord{
 		CheckPointID:          int64(lsn),
+		TransactionID:         p.currentXid,
+		CommitTime:            p.currentCommitTime,
 		OldItems:              oldItems,
 		NewItems:              newItems,
 		DestinationTableName:  p.TableNameMapping[tableName].Name,
@@ -674,11 +1093,15 @@ func (p *PostgresCDCSource) processDeleteMessage(
 	// create empty map of string to interface{}
 	items, _, err := p.convertTupleToMap(msg.OldTuple, rel, p.TableNameMapping[tableName].Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("error converting tuple to map: %w", err)
+		return nil, p.deadLetterRecord(lsn, tableName, msg.OldTuple, rel,
+			fmt.Errorf("error converting tuple to map: %w", err))
 	}
+	p.addSourceColumn(items)
 
 	return &model.DeleteRecord{
 		CheckPointID:         int64(lsn),
+		TransactionID:        p.currentXid,
+		CommitTime:           p.currentCommitTime,
 		Items:                items,
 		DestinationTableName: p.TableNameMapping[tableName].Name,
 		SourceTableName:      tableName,
@@ -718,14 +1141,22 @@ func (p *PostgresCDCSource) convertTupleToMap(
 		case 't': // text
 			/* bytea also appears here as a hex */
 			data, err := p.decodeColumnData(col.Data, rel.Columns[idx].DataType, pgtype.TextFormatCode)
+			if err == nil {
+				err = p.checkGeoColumnData(col.Data, data)
+			}
 			if err != nil {
-				return nil, nil, fmt.Errorf("error decoding text column data: %w", err)
+				return nil, nil, fmt.Errorf("error decoding text column data: %w",
+					&columnConversionError{column: colName, rawValue: rawTupleColumnValue(col), err: err})
 			}
 			items.AddColumn(colName, data)
 		case 'b': // binary
 			data, err := p.decodeColumnData(col.Data, rel.Columns[idx].DataType, pgtype.BinaryFormatCode)
+			if err == nil {
+				err = p.checkGeoColumnData(col.Data, data)
+			}
 			if err != nil {
-				return nil, nil, fmt.Errorf("error decoding binary column data: %w", err)
+				return nil, nil, fmt.Errorf("error decoding binary column data: %w",
+					&columnConversionError{column: colName, rawValue: rawTupleColumnValue(col), err: err})
 			}
 			items.AddColumn(colName, data)
 		case 'u': // unchanged toast
@@ -737,6 +1168,105 @@ func (p *PostgresCDCSource) convertTupleToMap(
 	return items, unchangedToastColumns, nil
 }
 
+// addSourceColumn stores the source of a record in the source column, for mirrors from several sources
+// into the same tables, where it is part of the primary key.
+func (p *PostgresCDCSource) addSourceColumn(items *model.RecordItems) {
+	if p.sourceColName != "" {
+		items.AddColumn(p.sourceColName, qvalue.QValue{Kind: qvalue.QValueKindString, Value: p.sourceColValue})
+	}
+}
+
+// sourceKeyColumns returns the primary key columns of a table on the source,
+// the source column is only part of the key on the destination.
+func (p *PostgresCDCSource) sourceKeyColumns(tableSchema *protos.TableSchema) []string {
+	if p.sourceColName == "" {
+		return tableSchema.PrimaryKeyColumns
+	}
+	return slices.DeleteFunc(slices.Clone(tableSchema.PrimaryKeyColumns), func(col string) bool {
+		return col == p.sourceColName
+	})
+}
+
+// columnConversionError is returned when the value of a column of a record fails to convert
+type columnConversionError struct {
+	column   string
+	rawValue *string
+	err      error
+}
+
+func (e *columnConversionError) Error() string {
+	return fmt.Sprintf("column %s: %v", e.column, e.err)
+}
+
+func (e *columnConversionError) Unwrap() error {
+	return e.err
+}
+
+// checkGeoColumnData returns why a geometry failed to convert, as such values are synced as NULL
+// unless records that fail to convert are dead-lettered.
+func (p *PostgresCDCSource) checkGeoColumnData(data []byte, val qvalue.QValue) error {
+	if !p.deadLetterFailedRecords || val.Value != nil ||
+		(val.Kind != qvalue.QValueKindGeography && val.Kind != qvalue.QValueKindGeometry) {
+		return nil
+	}
+	_, err := geo.GeoValidate(string(data))
+	if err == nil {
+		return errors.New("invalid geometry")
+	}
+	return err
+}
+
+func rawTupleColumnValue(col *pglogrepl.TupleDataColumn) *string {
+	var val string
+	switch col.DataType {
+	case 't':
+		val = string(col.Data)
+	case 'b':
+		val = hex.EncodeToString(col.Data)
+	default:
+		return nil
+	}
+	return &val
+}
+
+// deadLetterRecord stores a record that failed to convert in the dead letter table of the catalog,
+// so that it is skipped. The error is returned as is if the record can't be skipped.
+func (p *PostgresCDCSource) deadLetterRecord(
+	lsn pglogrepl.LSN,
+	tableName string,
+	tuple *pglogrepl.TupleData,
+	rel *protos.RelationMessage,
+	err error,
+) error {
+	var convErr *columnConversionError
+	if !p.deadLetterFailedRecords || !errors.As(err, &convErr) {
+		return err
+	}
+
+	// unchanged TOAST columns are left out, as their values are not part of the record
+	rawRecord := make(map[string]*string, len(tuple.Columns))
+	for idx, col := range tuple.Columns {
+		if col.DataType != 'u' {
+			rawRecord[rel.Columns[idx].Name] = rawTupleColumnValue(col)
+		}
+	}
+	rawRecordJSON, jsonErr := json.Marshal(rawRecord)
+	if jsonErr != nil {
+		return fmt.Errorf("failed to marshal dead letter record to JSON: %w", jsonErr)
+	}
+
+	_, dbErr := p.catalogPool.Exec(p.ctx,
+		`INSERT INTO peerdb_stats.dead_letter_records(flow_name,table_name,lsn,column_name,raw_value,raw_record,error_message)
+		 VALUES($1,$2,$3,$4,$5,$6,$7) ON CONFLICT (flow_name,lsn,table_name) DO NOTHING`,
+		p.flowJobName, tableName, int64(lsn), convErr.column, convErr.rawValue, rawRecordJSON, err.Error())
+	if dbErr != nil {
+		return fmt.Errorf("failed to store dead letter record: %w", dbErr)
+	}
+	p.logger.Warn("record failed to convert, stored in dead letter table",
+		slog.String("table", tableName), slog.Any("lsn", lsn), slog.Any("error", err))
+	return nil
+}
+
 func (p *PostgresCDCSource) decodeColumnData(data []byte, dataType uint32, formatCode int16) (qvalue.QValue, error) {
 	var parsedData any
 	var err error
@@ -794,9 +1324,10 @@ func convertRelationMessageToProto(msg *pglogrepl.RelationMessage) *protos.Relat
 	protoColArray := make([]*protos.RelationMessageColumn, 0)
 	for _, column := range msg.Columns {
 		protoColArray = append(protoColArray, &protos.RelationMessageColumn{
-			Name:     column.Name,
-			Flags:    uint32(column.Flags),
-			DataType: column.DataType,
+			Name:         column.Name,
+			Flags:        uint32(column.Flags),
+			DataType:     column.DataType,
+			TypeModifier: column.TypeModifier,
 		})
 	}
 	return &protos.RelationMessage{
@@ -865,8 +1396,9 @@ func (p *PostgresCDCSource) processRelationMessage(
 				}
 			}
 			schemaDelta.AddedColumns = append(schemaDelta.AddedColumns, &protos.DeltaAddedColumn{
-				ColumnName: column.Name,
-				ColumnType: string(qKind),
+				ColumnName:   column.Name,
+				ColumnType:   string(qKind),
+				ColumnTypmod: column.TypeModifier,
 			})
 			// present in previous and current relation messages, but data types have changed.
 			// so we add it to AddedColumns and DroppedColumns, knowing that we process DroppedColumns first.
@@ -894,11 +1426,17 @@ func (p *PostgresCDCSource) processRelationMessage(
 func (p *PostgresCDCSource) recToTablePKey(req *model.PullRecordsRequest,
 	rec model.Record,
 ) (*model.TableWithPkey, error) {
-	tableName := rec.GetDestinationTableName()
+	return p.itemsToTablePKey(req, rec.GetDestinationTableName(), rec.GetItems())
+}
+
+func (p *PostgresCDCSource) itemsToTablePKey(req *model.PullRecordsRequest,
+	tableName string,
+	items *model.RecordItems,
+) (*model.TableWithPkey, error) {
 	pkeyColsMerged := make([]byte, 0)
 
 	for _, pkeyCol := range req.TableNameSchemaMapping[tableName].PrimaryKeyColumns {
-		pkeyColVal, err := rec.GetItems().GetValueByColName(pkeyCol)
+		pkeyColVal, err := items.GetValueByColName(pkeyCol)
 		if err != nil {
 			return nil, fmt.Errorf("error getting pkey column value: %w", err)
 		}
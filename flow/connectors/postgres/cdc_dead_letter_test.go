@@ -0,0 +1,41 @@
+package connpostgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/lib/pq/oid"
+)
+
+func TestConvertTupleToMapConversionError(t *testing.T) {
+	p := &PostgresCDCSource{typeMap: pgtype.NewMap()}
+	rel := &protos.RelationMessage{
+		Columns: []*protos.RelationMessageColumn{
+			{Name: "id", DataType: uint32(oid.T_int8)},
+			{Name: "amount", DataType: uint32(oid.T_numeric)},
+		},
+	}
+	tuple := &pglogrepl.TupleData{
+		Columns: []*pglogrepl.TupleDataColumn{
+			{DataType: 't', Data: []byte("1")},
+			{DataType: 't', Data: []byte("NaN")},
+		},
+	}
+
+	_, _, err := p.convertTupleToMap(tuple, rel, nil)
+	var convErr *columnConversionError
+	if !errors.As(err, &convErr) {
+		t.Fatalf("Expected column conversion error, got %v", err)
+	}
+	if convErr.column != "amount" || convErr.rawValue == nil || *convErr.rawValue != "NaN" {
+		t.Errorf("Unexpected conversion error: %+v", convErr)
+	}
+
+	// records are only skipped when they are dead-lettered
+	if dlErr := p.deadLetterRecord(0, "public.t", tuple, rel, err); !errors.Is(dlErr, err) {
+		t.Errorf("Expected error to be returned as is, got %v", dlErr)
+	}
+}
@@ -0,0 +1,76 @@
+package connpostgres
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+)
+
+func TestSourceKeyColumns(t *testing.T) {
+	p := &PostgresCDCSource{sourceColName: "_PEERDB_SOURCE", sourceColValue: "pg_eu"}
+	tableSchema := utils.AddSourceColumn(&protos.TableSchema{
+		ColumnNames:       []string{"id", "val"},
+		ColumnTypes:       []string{"int64", "string"},
+		PrimaryKeyColumns: []string{"id"},
+	}, p.sourceColName)
+
+	if !slices.Equal(tableSchema.PrimaryKeyColumns, []string{"id", "_PEERDB_SOURCE"}) {
+		t.Errorf("Expected source column in destination key, got %v", tableSchema.PrimaryKeyColumns)
+	}
+	if keyColumns := p.sourceKeyColumns(tableSchema); !slices.Equal(keyColumns, []string{"id"}) {
+		t.Errorf("Expected source column to be stripped from source key, got %v", keyColumns)
+	}
+}
+
+func TestSourceColumnWithoutPrimaryKey(t *testing.T) {
+	tableSchema := utils.AddSourceColumn(&protos.TableSchema{
+		ColumnNames:           []string{"id", "val"},
+		ColumnTypes:           []string{"int64", "string"},
+		IsReplicaIdentityFull: true,
+	}, "_PEERDB_SOURCE")
+
+	if len(tableSchema.PrimaryKeyColumns) != 0 {
+		t.Errorf("Expected tables without a primary key to keep an empty key, got %v", tableSchema.PrimaryKeyColumns)
+	}
+	if !slices.Equal(tableSchema.ColumnNames, []string{"id", "val", "_PEERDB_SOURCE"}) {
+		t.Errorf("Expected source column in columns, got %v", tableSchema.ColumnNames)
+	}
+}
+
+func TestNormalizeKeysOnSourceColumn(t *testing.T) {
+	normalizeGen := &normalizeStmtGenerator{
+		rawTableName: "_peerdb_raw_test",
+		dstTableName: "public.users",
+		normalizedTableSchema: utils.AddSourceColumn(&protos.TableSchema{
+			ColumnNames:       []string{"id", "val"},
+			ColumnTypes:       []string{"int64", "string"},
+			PrimaryKeyColumns: []string{"id"},
+		}, "_PEERDB_SOURCE"),
+		unchangedToastColumns: []string{""},
+		peerdbCols: &protos.PeerDBColumns{
+			SyncedAtColName:   "_peerdb_synced_at",
+			SoftDeleteColName: "_peerdb_soft_delete",
+		},
+		metadataSchema: "_peerdb_internal",
+	}
+
+	mergeStmt := utils.RemoveSpacesTabsNewlines(normalizeGen.generateMergeStatement())
+	for _, expected := range []string{
+		`(_peerdb_data->>'_PEERDB_SOURCE')::TEXTAS"_PEERDB_SOURCE"`,
+		`src.id=dst.idANDsrc._PEERDB_SOURCE=dst._PEERDB_SOURCE`,
+	} {
+		if !strings.Contains(mergeStmt, expected) {
+			t.Errorf("Expected merge statement to contain %s, got %s", expected, mergeStmt)
+		}
+	}
+
+	// rows of each source are upserted and deleted separately
+	for _, stmt := range normalizeGen.generateFallbackStatements() {
+		if !strings.Contains(utils.RemoveSpacesTabsNewlines(stmt), `(_peerdb_data->>'_PEERDB_SOURCE')::TEXT`) {
+			t.Errorf("Expected fallback statement to key on the source column, got %s", stmt)
+		}
+	}
+}
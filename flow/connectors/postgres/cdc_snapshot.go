@@ -0,0 +1,327 @@
+package connpostgres
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/google/uuid"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Incremental snapshots read tables in chunks by primary key while changes are pulled.
+// Before and after reading a chunk, a low and a high watermark message are emitted on the source.
+// Changes decoded between the watermarks may be newer than the rows of the chunk, so rows they touch
+// are dropped from it, the remaining rows are added to the batch as inserts at the high watermark.
+
+const (
+	// prefix of the watermark messages, they are captured regardless of logical_message_prefixes
+	incrementalSnapshotMessagePrefix    = "peerdb_incremental_snapshot"
+	DefaultIncrementalSnapshotChunkSize = 10000
+)
+
+// snapshotWatermark is decoded from a watermark message, it is handled while pulling and never synced.
+type snapshotWatermark struct {
+	lsn     pglogrepl.LSN
+	chunkID string
+	high    bool
+}
+
+func (w *snapshotWatermark) GetCheckPointID() int64 {
+	return int64(w.lsn)
+}
+
+func (w *snapshotWatermark) GetTransactionID() uint32 {
+	return 0
+}
+
+func (w *snapshotWatermark) GetCommitTime() time.Time {
+	return time.Time{}
+}
+
+func (w *snapshotWatermark) GetDestinationTableName() string {
+	return ""
+}
+
+func (w *snapshotWatermark) GetItems() *model.RecordItems {
+	return model.NewRecordItems(0)
+}
+
+// snapshotChunk holds the rows of a table read between a low and a high watermark.
+type snapshotChunk struct {
+	id                   string
+	progress             *monitoring.IncrementalSnapshotProgress
+	destinationTableName string
+	// set once the low watermark is decoded, changes decoded after it drop their rows from the chunk
+	open bool
+	rows map[model.TableWithPkey]*model.InsertRecord
+	// keys of the rows in primary key order
+	keys    []model.TableWithPkey
+	lastKey []string
+	// fewer rows than the chunk size were read, the table is done after this chunk
+	last bool
+}
+
+// pendingSnapshotChunk is the last chunk of a table added to the batch by this pull,
+// it is recorded once the batch is synced up to its high watermark.
+type pendingSnapshotChunk struct {
+	progress *monitoring.IncrementalSnapshotProgress
+	lsn      pglogrepl.LSN
+	done     bool
+}
+
+// snapshotChunkPending returns true between reading a chunk and decoding its high watermark,
+// the batch can't be returned until then as the rows of the chunk would be lost.
+func (p *PostgresCDCSource) snapshotChunkPending() bool {
+	return p.snapshotChunk != nil
+}
+
+func (p *PostgresCDCSource) emitSnapshotWatermark(chunkID string, high bool) error {
+	content := "low:" + chunkID
+	if high {
+		content = "high:" + chunkID
+	}
+	_, err := p.queryPool.Exec(p.ctx, "SELECT pg_logical_emit_message(false,$1,$2)",
+		incrementalSnapshotMessagePrefix, content)
+	if err != nil {
+		return fmt.Errorf("error emitting incremental snapshot watermark: %w", err)
+	}
+	return nil
+}
+
+func parseSnapshotWatermark(msg *pglogrepl.LogicalDecodingMessage) *snapshotWatermark {
+	high, chunkID, ok := strings.Cut(string(msg.Content), ":")
+	if !ok {
+		return nil
+	}
+	return &snapshotWatermark{
+		lsn:     msg.LSN,
+		chunkID: chunkID,
+		high:    high == "high",
+	}
+}
+
+// skipSnapshotTable stops the incremental snapshot of the first table, which can't be snapshotted.
+func (p *PostgresCDCSource) skipSnapshotTable(reason string) {
+	progress := p.snapshotTables[0]
+	p.logger.Warn(fmt.Sprintf("skipping incremental snapshot of table %s: %s", progress.TableName, reason))
+	p.snapshotPending[progress.TableName] = &pendingSnapshotChunk{progress: progress, done: true}
+	p.snapshotTables = p.snapshotTables[1:]
+}
+
+// readSnapshotChunk reads the next chunk of the first table being snapshotted, between two watermarks.
+// Rows are read in text format and decoded like the tuples of changes, so that they have the same keys.
+func (p *PostgresCDCSource) readSnapshotChunk(req *model.PullRecordsRequest) error {
+	progress := p.snapshotTables[0]
+	nameAndExclude, ok := p.TableNameMapping[progress.TableName]
+	if !ok {
+		p.skipSnapshotTable("table is not part of the mirror")
+		return nil
+	}
+	tableSchema, ok := req.TableNameSchemaMapping[nameAndExclude.Name]
+	if !ok || len(tableSchema.PrimaryKeyColumns) == 0 {
+		p.skipSnapshotTable("table has no primary key")
+		return nil
+	}
+	keyColumns := p.sourceKeyColumns(tableSchema)
+	schemaTable, err := utils.ParseSchemaTable(progress.TableName)
+	if err != nil {
+		return fmt.Errorf("error parsing table %s: %w", progress.TableName, err)
+	}
+
+	quotedKeyCols := make([]string, 0, len(keyColumns))
+	for _, col := range keyColumns {
+		quotedKeyCols = append(quotedKeyCols, utils.QuoteIdentifier(col))
+	}
+	keyCols := strings.Join(quotedKeyCols, ",")
+
+	// with the simple protocol, the key values are sent as literals and cast to the types of the key columns
+	args := []any{pgx.QueryExecModeSimpleProtocol}
+	query := "SELECT * FROM " + schemaTable.String()
+	if progress.LastKey != nil {
+		placeholders := make([]string, 0, len(progress.LastKey))
+		for i, val := range progress.LastKey {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+			args = append(args, val)
+		}
+		query += fmt.Sprintf(" WHERE (%s)>(%s)", keyCols, strings.Join(placeholders, ","))
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", keyCols, p.snapshotChunkSize)
+
+	chunk := &snapshotChunk{
+		id:                   uuid.NewString(),
+		progress:             progress,
+		destinationTableName: nameAndExclude.Name,
+		rows:                 make(map[model.TableWithPkey]*model.InsertRecord),
+		lastKey:              progress.LastKey,
+	}
+	err = p.emitSnapshotWatermark(chunk.id, false)
+	if err != nil {
+		return err
+	}
+
+	rows, err := p.queryPool.Query(p.ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error reading chunk of table %s: %w", progress.TableName, err)
+	}
+	defer rows.Close()
+
+	fds := rows.FieldDescriptions()
+	keyIdx := make([]int, 0, len(keyColumns))
+	for _, col := range keyColumns {
+		for i, fd := range fds {
+			if fd.Name == col {
+				keyIdx = append(keyIdx, i)
+				break
+			}
+		}
+	}
+	if len(keyIdx) != len(keyColumns) {
+		return fmt.Errorf("primary key columns of table %s not found", progress.TableName)
+	}
+
+	for rows.Next() {
+		rawValues := rows.RawValues()
+		items := model.NewRecordItems(len(fds))
+		for i, fd := range fds {
+			if _, ok := nameAndExclude.Exclude[fd.Name]; ok {
+				continue
+			}
+			if rawValues[i] == nil {
+				items.AddColumn(fd.Name, qvalue.QValue{Kind: qvalue.QValueKindInvalid, Value: nil})
+				continue
+			}
+			val, err := p.decodeColumnData(rawValues[i], fd.DataTypeOID, pgtype.TextFormatCode)
+			if err != nil {
+				return fmt.Errorf("error decoding column %s of table %s: %w", fd.Name, progress.TableName, err)
+			}
+			items.AddColumn(fd.Name, val)
+		}
+		p.addSourceColumn(items)
+
+		rec := &model.InsertRecord{
+			Items:                items,
+			DestinationTableName: nameAndExclude.Name,
+			SourceTableName:      progress.TableName,
+		}
+		key, err := p.itemsToTablePKey(req, nameAndExclude.Name, items)
+		if err != nil {
+			return err
+		}
+		chunk.rows[*key] = rec
+		chunk.keys = append(chunk.keys, *key)
+
+		chunk.lastKey = make([]string, 0, len(keyIdx))
+		for _, idx := range keyIdx {
+			chunk.lastKey = append(chunk.lastKey, string(rawValues[idx]))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading chunk of table %s: %w", progress.TableName, err)
+	}
+	chunk.last = len(chunk.keys) < p.snapshotChunkSize
+
+	err = p.emitSnapshotWatermark(chunk.id, true)
+	if err != nil {
+		return err
+	}
+	p.snapshotChunk = chunk
+	p.logger.Info(fmt.Sprintf("read chunk of %d rows of table %s for incremental snapshot",
+		len(chunk.keys), progress.TableName))
+	return nil
+}
+
+// dropSnapshotChunkRows drops the rows touched by a change decoded between the watermarks of the chunk.
+func (p *PostgresCDCSource) dropSnapshotChunkRows(req *model.PullRecordsRequest, rec model.Record) {
+	chunk := p.snapshotChunk
+	if chunk == nil || !chunk.open || rec.GetDestinationTableName() != chunk.destinationTableName {
+		return
+	}
+
+	var items []*model.RecordItems
+	switch r := rec.(type) {
+	case *model.InsertRecord:
+		items = []*model.RecordItems{r.Items}
+	case *model.UpdateRecord:
+		// the old tuple has the previous key when it changed
+		items = []*model.RecordItems{r.NewItems, r.OldItems}
+	case *model.DeleteRecord:
+		items = []*model.RecordItems{r.Items}
+	}
+	for _, it := range items {
+		key, err := p.itemsToTablePKey(req, chunk.destinationTableName, it)
+		if err != nil {
+			continue
+		}
+		delete(chunk.rows, *key)
+	}
+}
+
+// processSnapshotWatermark opens the chunk at its low watermark, and adds its remaining rows at its high watermark.
+// Watermarks of chunks read by earlier pulls or by other mirrors of the source are skipped.
+func (p *PostgresCDCSource) processSnapshotWatermark(
+	batch *model.CDCRecordStream,
+	wm *snapshotWatermark,
+	addRecordWithKey func(model.TableWithPkey, model.Record) error,
+) error {
+	chunk := p.snapshotChunk
+	if chunk == nil || chunk.id != wm.chunkID {
+		return nil
+	}
+	if !wm.high {
+		chunk.open = true
+		return nil
+	}
+
+	numRows := 0
+	for _, key := range chunk.keys {
+		rec, ok := chunk.rows[key]
+		if !ok {
+			continue
+		}
+		rec.CheckPointID = int64(wm.lsn)
+		err := addRecordWithKey(key, rec)
+		if err != nil {
+			return err
+		}
+		numRows++
+	}
+	batch.UpdateLatestCheckpoint(int64(wm.lsn))
+
+	chunk.progress.LastKey = chunk.lastKey
+	p.snapshotPending[chunk.progress.TableName] = &pendingSnapshotChunk{
+		progress: chunk.progress,
+		lsn:      wm.lsn,
+		done:     chunk.last,
+	}
+	if chunk.last {
+		p.logger.Info(fmt.Sprintf("read last chunk of table %s for incremental snapshot", chunk.progress.TableName))
+		p.snapshotTables = p.snapshotTables[1:]
+	}
+	p.snapshotChunk = nil
+	p.logger.Debug(fmt.Sprintf("added %d of %d rows of incremental snapshot chunk of table %s",
+		numRows, len(chunk.keys), chunk.progress.TableName))
+	return nil
+}
+
+// savePendingSnapshotChunks records the chunks read by this pull as pending in the catalog.
+func (p *PostgresCDCSource) savePendingSnapshotChunks() error {
+	for _, pending := range p.snapshotPending {
+		err := monitoring.UpdateIncrementalSnapshotPending(p.ctx, p.catalogPool, p.flowJobName,
+			pending.progress, int64(pending.lsn), pending.done)
+		if err != nil {
+			return err
+		}
+	}
+	if len(p.snapshotPending) > 0 {
+		p.logger.Info("saved incremental snapshot progress", slog.Int("tables", len(p.snapshotPending)))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils/monitoring"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/jackc/pglogrepl"
+)
+
+func TestParseSnapshotWatermark(t *testing.T) {
+	wm := parseSnapshotWatermark(&pglogrepl.LogicalDecodingMessage{
+		LSN:     pglogrepl.LSN(42),
+		Prefix:  incrementalSnapshotMessagePrefix,
+		Content: []byte("high:0b7e4b4e-5bb4-4b0d-a8b2-3e1c4d5f6a7b"),
+	})
+	if wm == nil || !wm.high || wm.chunkID != "0b7e4b4e-5bb4-4b0d-a8b2-3e1c4d5f6a7b" || wm.lsn != 42 {
+		t.Errorf("Unexpected watermark: %+v", wm)
+	}
+
+	wm = parseSnapshotWatermark(&pglogrepl.LogicalDecodingMessage{
+		Prefix:  incrementalSnapshotMessagePrefix,
+		Content: []byte("low:chunk"),
+	})
+	if wm == nil || wm.high || wm.chunkID != "chunk" {
+		t.Errorf("Unexpected watermark: %+v", wm)
+	}
+
+	if parseSnapshotWatermark(&pglogrepl.LogicalDecodingMessage{Content: []byte("chunk")}) != nil {
+		t.Errorf("Expected malformed watermark to be skipped")
+	}
+}
+
+func snapshotTestItems(id int64) *model.RecordItems {
+	items := model.NewRecordItems(2)
+	items.AddColumn("id", qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: id})
+	items.AddColumn("val", qvalue.QValue{Kind: qvalue.QValueKindString, Value: "v"})
+	return items
+}
+
+func TestDropSnapshotChunkRows(t *testing.T) {
+	req := &model.PullRecordsRequest{
+		TableNameSchemaMapping: map[string]*protos.TableSchema{
+			"dst": {PrimaryKeyColumns: []string{"id"}},
+		},
+	}
+	p := &PostgresCDCSource{}
+	p.snapshotChunk = &snapshotChunk{
+		id:                   "chunk",
+		progress:             &monitoring.IncrementalSnapshotProgress{TableName: "public.src"},
+		destinationTableName: "dst",
+		rows:                 make(map[model.TableWithPkey]*model.InsertRecord),
+	}
+	for id := int64(1); id <= 4; id++ {
+		key, err := p.itemsToTablePKey(req, "dst", snapshotTestItems(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.snapshotChunk.rows[*key] = &model.InsertRecord{Items: snapshotTestItems(id), DestinationTableName: "dst"}
+		p.snapshotChunk.keys = append(p.snapshotChunk.keys, *key)
+	}
+
+	// changes decoded before the low watermark don't drop rows
+	p.dropSnapshotChunkRows(req, &model.DeleteRecord{Items: snapshotTestItems(1), DestinationTableName: "dst"})
+	if len(p.snapshotChunk.rows) != 4 {
+		t.Fatalf("Expected 4 rows, got %d", len(p.snapshotChunk.rows))
+	}
+
+	p.snapshotChunk.open = true
+	p.dropSnapshotChunkRows(req, &model.DeleteRecord{Items: snapshotTestItems(1), DestinationTableName: "dst"})
+	// an update changing the key drops the rows of the old and the new key
+	p.dropSnapshotChunkRows(req, &model.UpdateRecord{
+		OldItems:             snapshotTestItems(2),
+		NewItems:             snapshotTestItems(3),
+		DestinationTableName: "dst",
+	})
+	// changes of other tables don't drop rows
+	p.dropSnapshotChunkRows(req, &model.DeleteRecord{Items: snapshotTestItems(4), DestinationTableName: "other"})
+
+	if len(p.snapshotChunk.rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(p.snapshotChunk.rows))
+	}
+	if _, ok := p.snapshotChunk.rows[p.snapshotChunk.keys[3]]; !ok {
+		t.Errorf("Expected row 4 to be kept")
+	}
+}
@@ -0,0 +1,134 @@
+package connpostgres
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/jackc/pgx/v5"
+)
+
+// number of records held back for backfilling unchanged TOAST columns before they are fetched
+const maxPendingToastRecords = 1024
+
+func hasUnchangedToastColumns(rec model.Record) bool {
+	switch r := rec.(type) {
+	case *model.UpdateRecord:
+		return len(r.UnchangedToastColumns) > 0
+	default:
+		return false
+	}
+}
+
+// backfillUnchangedToastColumns fetches the current values of unchanged TOAST columns of update records
+// from the source table by primary key, all lookups are sent to the source in one batch.
+// The values are read as of now rather than as of the update, so a row updated again since
+// gets the TOAST columns of its latest version. Rows that have been deleted since are left
+// with their unchanged TOAST columns.
+func (p *PostgresCDCSource) backfillUnchangedToastColumns(
+	req *model.PullRecordsRequest,
+	recs []model.Record,
+) error {
+	batch := &pgx.Batch{}
+	queued := make([]*model.UpdateRecord, 0, len(recs))
+	for _, rec := range recs {
+		r, ok := rec.(*model.UpdateRecord)
+		if !ok || len(r.UnchangedToastColumns) == 0 {
+			continue
+		}
+
+		query, args, err := p.toastBackfillQuery(req, r)
+		if err != nil {
+			return err
+		}
+		if query == "" {
+			continue
+		}
+		batch.Queue(query, args...)
+		queued = append(queued, r)
+	}
+
+	if len(queued) == 0 {
+		return nil
+	}
+	p.logger.Info(fmt.Sprintf("backfilling unchanged toast columns for %d records", len(queued)))
+
+	results := p.queryPool.SendBatch(p.ctx, batch)
+	for _, r := range queued {
+		rows, err := results.Query()
+		if err != nil {
+			results.Close()
+			return fmt.Errorf("failed to query unchanged toast columns of %s: %w", r.SourceTableName, err)
+		}
+
+		fds := rows.FieldDescriptions()
+		if rows.Next() {
+			record, err := mapRowToQRecord(rows, fds, p.customTypeMapping)
+			if err != nil {
+				rows.Close()
+				results.Close()
+				return err
+			}
+			for i, fd := range fds {
+				r.NewItems.AddColumn(fd.Name, record.Entries[i])
+				delete(r.UnchangedToastColumns, fd.Name)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			results.Close()
+			return fmt.Errorf("failed to read unchanged toast columns of %s: %w", r.SourceTableName, err)
+		}
+	}
+
+	return results.Close()
+}
+
+// toastBackfillQuery builds the query selecting the unchanged TOAST columns of a record by its primary key.
+// An empty query is returned if the table has no primary key. Updates always carry their primary key,
+// so a key column missing from the record is an error.
+func (p *PostgresCDCSource) toastBackfillQuery(
+	req *model.PullRecordsRequest,
+	r *model.UpdateRecord,
+) (string, []interface{}, error) {
+	tableSchema, ok := req.TableNameSchemaMapping[r.DestinationTableName]
+	if !ok || len(tableSchema.PrimaryKeyColumns) == 0 {
+		return "", nil, nil
+	}
+
+	schemaTable, err := utils.ParseSchemaTable(r.SourceTableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	selectCols := make([]string, 0, len(r.UnchangedToastColumns))
+	for col := range r.UnchangedToastColumns {
+		selectCols = append(selectCols, utils.QuoteIdentifier(col))
+	}
+
+	keyColumns := p.sourceKeyColumns(tableSchema)
+	whereClauses := make([]string, 0, len(keyColumns))
+	args := make([]interface{}, 0, len(keyColumns))
+	for i, pkeyCol := range keyColumns {
+		pkeyVal, err := r.NewItems.GetValueByColName(pkeyCol)
+		if err != nil {
+			return "", nil, fmt.Errorf("primary key column %s missing from update of %s: %w",
+				pkeyCol, r.SourceTableName, err)
+		}
+		whereClauses = append(whereClauses, fmt.Sprintf("%s=$%d", utils.QuoteIdentifier(pkeyCol), i+1))
+		args = append(args, toastBackfillArg(pkeyVal))
+	}
+
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(selectCols, ","),
+		schemaTable.String(), strings.Join(whereClauses, " AND ")), args, nil
+}
+
+func toastBackfillArg(val qvalue.QValue) interface{} {
+	if num, ok := val.Value.(*big.Rat); ok {
+		return qvalue.NumericToString(num)
+	}
+	return val.Value
+}
@@ -0,0 +1,44 @@
+package connpostgres
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestToastBackfillQuery(t *testing.T) {
+	req := &model.PullRecordsRequest{
+		TableNameSchemaMapping: map[string]*protos.TableSchema{
+			"public.dst": {PrimaryKeyColumns: []string{"id"}},
+		},
+	}
+	p := &PostgresCDCSource{}
+
+	newItems := model.NewRecordItems(1)
+	newItems.AddColumn("id", qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: int64(1)})
+	query, args, err := p.toastBackfillQuery(req, &model.UpdateRecord{
+		SourceTableName:       "public.src",
+		DestinationTableName:  "public.dst",
+		NewItems:              newItems,
+		UnchangedToastColumns: map[string]struct{}{"doc": {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `SELECT "doc" FROM "public"."src" WHERE "id"=$1`
+	if query != expected || len(args) != 1 || args[0] != int64(1) {
+		t.Errorf("expected %s with [1], got %s with %v", expected, query, args)
+	}
+
+	_, _, err = p.toastBackfillQuery(req, &model.UpdateRecord{
+		SourceTableName:       "public.src",
+		DestinationTableName:  "public.dst",
+		NewItems:              model.NewRecordItems(0),
+		UnchangedToastColumns: map[string]struct{}{"doc": {}},
+	})
+	if err == nil {
+		t.Error("expected an error for an update without its primary key")
+	}
+}
@@ -1,6 +1,7 @@
 package connpostgres
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
 	"github.com/jackc/pglogrepl"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -25,7 +27,10 @@ const (
 	createRawTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s(_peerdb_uid TEXT NOT NULL,
 		_peerdb_timestamp BIGINT NOT NULL,_peerdb_destination_table_name TEXT NOT NULL,_peerdb_data JSONB NOT NULL,
 		_peerdb_record_type INTEGER NOT NULL, _peerdb_match_data JSONB,_peerdb_batch_id INTEGER,
-		_peerdb_unchanged_toast_columns TEXT)`
+		_peerdb_unchanged_toast_columns TEXT,_peerdb_commit_ts BIGINT,_peerdb_lsn BIGINT,_peerdb_xid BIGINT)`
+	// raw tables created before transaction metadata was recorded lack its columns
+	migrateRawTableSQL = `ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS _peerdb_commit_ts BIGINT,
+		ADD COLUMN IF NOT EXISTS _peerdb_lsn BIGINT,ADD COLUMN IF NOT EXISTS _peerdb_xid BIGINT`
 	createRawTableBatchIDIndexSQL  = "CREATE INDEX IF NOT EXISTS %s_batchid_idx ON %s.%s(_peerdb_batch_id)"
 	createRawTableDstTableIndexSQL = "CREATE INDEX IF NOT EXISTS %s_dst_table_idx ON %s.%s(_peerdb_destination_table_name)"
 
@@ -47,31 +52,59 @@ const (
 	_peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_record_type!=2 GROUP BY _peerdb_destination_table_name`
 	srcTableName      = "src"
 	mergeStatementSQL = `WITH src_rank AS (
-		SELECT _peerdb_data,_peerdb_record_type,_peerdb_unchanged_toast_columns,
-		RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
+		SELECT _peerdb_data,_peerdb_timestamp,_peerdb_record_type,_peerdb_match_data,_peerdb_unchanged_toast_columns,
+		_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid,RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
 		FROM %s.%s WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
 	)
 	MERGE INTO %s dst
-	USING (SELECT %s,_peerdb_record_type,_peerdb_unchanged_toast_columns FROM src_rank WHERE _peerdb_rank=1) src
+	USING (SELECT %s,_peerdb_timestamp,_peerdb_record_type,_peerdb_match_data,_peerdb_unchanged_toast_columns,
+		_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid FROM src_rank WHERE _peerdb_rank=1) src
 	ON %s
 	WHEN NOT MATCHED AND src._peerdb_record_type!=2 THEN
 	INSERT (%s) VALUES (%s)
 	%s
-	WHEN MATCHED AND src._peerdb_record_type=2 THEN
+	WHEN MATCHED AND src._peerdb_record_type=2%s THEN
 	%s`
 	fallbackUpsertStatementSQL = `WITH src_rank AS (
-		SELECT _peerdb_data,_peerdb_record_type,_peerdb_unchanged_toast_columns,
-		RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
+		SELECT _peerdb_data,_peerdb_record_type,_peerdb_match_data,_peerdb_unchanged_toast_columns,
+		_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid,RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
 		FROM %s.%s WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
 	)
 	INSERT INTO %s (%s) SELECT %s FROM src_rank WHERE _peerdb_rank=1 AND _peerdb_record_type!=2
 	ON CONFLICT (%s) DO UPDATE SET %s`
 	fallbackDeleteStatementSQL = `WITH src_rank AS (
-		SELECT _peerdb_data,_peerdb_record_type,_peerdb_unchanged_toast_columns,
-		RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
+		SELECT _peerdb_data,_peerdb_record_type,_peerdb_match_data,_peerdb_unchanged_toast_columns,
+		_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid,RANK() OVER (PARTITION BY %s ORDER BY _peerdb_timestamp DESC) AS _peerdb_rank
 		FROM %s.%s WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
 	)
 	%s src_rank WHERE %s AND src_rank._peerdb_rank=1 AND src_rank._peerdb_record_type=2`
+	// versions are valid from the commit time of their transaction, raw rows synced before commit times were
+	// recorded fall back to the time they were read. Only the last change to a row within a transaction is kept.
+	// Unchanged TOAST columns are carried forward from the last change to the row that had them, counting
+	// changes that had them per column in toast groups, the first change of a group is the one carrying them.
+	historyInsertStatementSQL = `WITH src AS (
+		SELECT %s,COALESCE(_peerdb_commit_ts,_peerdb_timestamp) AS _peerdb_version_ts,_peerdb_timestamp,
+		_peerdb_record_type,_peerdb_unchanged_toast_columns,_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid
+		FROM %s.%s WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
+	), grp AS (
+		SELECT *,%s FROM src WINDOW w AS (PARTITION BY %s ORDER BY _peerdb_version_ts,_peerdb_lsn,_peerdb_timestamp
+			ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+	), ver AS (
+		SELECT %s FROM grp
+	)
+	INSERT INTO %s (%s) SELECT %s FROM ver
+	LEFT JOIN %s cur ON %s AND cur."%s"
+	WHERE ver._peerdb_record_type!=2 AND ver._peerdb_next_timestamp IS DISTINCT FROM ver._peerdb_version_ts
+	AND NOT EXISTS (
+		SELECT 1 FROM %s dst WHERE %s AND dst."%s"=to_timestamp(ver._peerdb_version_ts/1000000000.0))`
+	historyCloseStatementSQL = `WITH first_ver AS (
+		SELECT %s,MIN(COALESCE(_peerdb_commit_ts,_peerdb_timestamp)) AS _peerdb_first_timestamp
+		FROM %s.%s WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
+		GROUP BY %s
+	)
+	UPDATE %s dst SET "%s"=to_timestamp(first_ver._peerdb_first_timestamp/1000000000.0),"%s"=FALSE
+	FROM first_ver WHERE %s AND dst."%s"
+	AND (dst."%s" IS NULL OR dst."%s"<to_timestamp(first_ver._peerdb_first_timestamp/1000000000.0))`
 
 	dropTableIfExistsSQL     = "DROP TABLE IF EXISTS %s.%s"
 	deleteJobMetadataSQL     = "DELETE FROM %s.%s WHERE mirror_job_name=$1"
@@ -245,12 +278,33 @@ func (c *PostgresConnector) checkSlotAndPublication(slot string, publication str
 // GetSlotInfo gets the information about the replication slot size and LSNs
 // If slotName input is empty, all slot info rows are returned - this is for UI.
 // Else, only the row pertaining to that slotName will be returned.
+// Slots of mirrors pulling from the standby are on the standby, so it is queried as well if the peer has one.
 func (c *PostgresConnector) GetSlotInfo(slotName string) ([]*protos.SlotInfo, error) {
+	slotInfoRows, err := querySlotInfo(c.ctx, c.pool, slotName)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.Standby == nil || (slotName != "" && len(slotInfoRows) > 0) {
+		return slotInfoRows, nil
+	}
+
+	standbyPool, err := c.getStandbyPool()
+	if err != nil {
+		return nil, err
+	}
+	standbySlotInfoRows, err := querySlotInfo(c.ctx, standbyPool, slotName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot info from standby: %w", err)
+	}
+	return append(slotInfoRows, standbySlotInfoRows...), nil
+}
+
+func querySlotInfo(ctx context.Context, pool *SSHWrappedPostgresPool, slotName string) ([]*protos.SlotInfo, error) {
 	specificSlotClause := ""
 	if slotName != "" {
 		specificSlotClause = fmt.Sprintf(" WHERE slot_name = '%s'", slotName)
 	}
-	rows, err := c.pool.Query(c.ctx, "SELECT slot_name, redo_lsn::Text,restart_lsn::text,wal_status,"+
+	rows, err := pool.Query(ctx, "SELECT slot_name, redo_lsn::Text,restart_lsn::text,wal_status,"+
 		"confirmed_flush_lsn::text,active,"+
 		"round((CASE WHEN pg_is_in_recovery() THEN pg_last_wal_receive_lsn() ELSE pg_current_wal_lsn() END"+
 		" - confirmed_flush_lsn) / 1024 / 1024) AS MB_Behind"+
@@ -294,6 +348,7 @@ func (c *PostgresConnector) createSlotAndPublication(
 	publication string,
 	tableNameMapping map[string]model.NameAndExclude,
 	doInitialCopy bool,
+	onStandby bool,
 ) error {
 	/*
 		iterating through source tables and creating a publication.
@@ -321,7 +376,13 @@ func (c *PostgresConnector) createSlotAndPublication(
 
 	// create slot only after we succeeded in creating publication.
 	if !s.SlotExists {
-		pool, err := c.GetReplPool(c.ctx)
+		var pool *SSHWrappedPostgresPool
+		var err error
+		if onStandby {
+			pool, err = c.getStandbyReplPool()
+		} else {
+			pool, err = c.GetReplPool(c.ctx)
+		}
 		if err != nil {
 			return fmt.Errorf("[slot] error acquiring pool: %w", err)
 		}
@@ -344,7 +405,15 @@ func (c *PostgresConnector) createSlotAndPublication(
 			Temporary: false,
 			Mode:      pglogrepl.LogicalReplication,
 		}
-		res, err := pglogrepl.CreateReplicationSlot(c.ctx, conn.Conn().PgConn(), slot, "pgoutput", opts)
+		var res pglogrepl.CreateReplicationSlotResult
+		if onStandby {
+			logSnapshotsDone := make(chan struct{})
+			go c.logStandbySnapshots(logSnapshotsDone)
+			res, err = pglogrepl.CreateReplicationSlot(c.ctx, conn.Conn().PgConn(), slot, "pgoutput", opts)
+			close(logSnapshotsDone)
+		} else {
+			res, err = pglogrepl.CreateReplicationSlot(c.ctx, conn.Conn().PgConn(), slot, "pgoutput", opts)
+		}
 		if err != nil {
 			return fmt.Errorf("[slot] error creating replication slot: %w", err)
 		}
@@ -394,11 +463,15 @@ func generateCreateTableSQLForNormalizedTable(
 	sourceTableSchema *protos.TableSchema,
 	softDeleteColName string,
 	syncedAtColName string,
+	beforeImageColName string,
+	historyMode bool,
+	transactionMetadata bool,
 ) string {
-	createTableSQLArray := make([]string, 0, utils.TableSchemaColumns(sourceTableSchema)+2)
+	createTableSQLArray := make([]string, 0, utils.TableSchemaColumns(sourceTableSchema)+7)
 	utils.IterColumns(sourceTableSchema, func(columnName, genericColumnType string) {
 		createTableSQLArray = append(createTableSQLArray, fmt.Sprintf("\"%s\" %s,", columnName,
-			qValueKindToPostgresType(genericColumnType)))
+			qValueKindToPostgresColumnType(genericColumnType,
+				utils.TableSchemaColumnTypmod(sourceTableSchema, columnName))))
 	})
 
 	if softDeleteColName != "" {
@@ -411,8 +484,27 @@ func generateCreateTableSQLForNormalizedTable(
 			fmt.Sprintf(`"%s" TIMESTAMP DEFAULT CURRENT_TIMESTAMP,`, syncedAtColName))
 	}
 
+	if beforeImageColName != "" {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`"%s" JSONB,`, beforeImageColName))
+	}
+
+	// in history mode a row has many versions, so the primary key is not unique.
+	// rows from the initial load have no start of validity.
+	if historyMode {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`"%s" TIMESTAMP,"%s" TIMESTAMP,"%s" BOOL DEFAULT TRUE,`,
+				shared.HistoryValidFromColName, shared.HistoryValidToColName, shared.HistoryIsCurrentColName))
+	}
+
+	if transactionMetadata {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`"%s" TIMESTAMP,"%s" BIGINT,"%s" BIGINT,`,
+				shared.CommitTimestampColName, shared.LSNColName, shared.TransactionIDColName))
+	}
+
 	// add composite primary key to the table
-	if len(sourceTableSchema.PrimaryKeyColumns) > 0 {
+	if len(sourceTableSchema.PrimaryKeyColumns) > 0 && !historyMode {
 		primaryKeyColsQuoted := make([]string, 0, len(sourceTableSchema.PrimaryKeyColumns))
 		for _, primaryKeyCol := range sourceTableSchema.PrimaryKeyColumns {
 			primaryKeyColsQuoted = append(primaryKeyColsQuoted,
@@ -0,0 +1,63 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/stretchr/testify/require"
+)
+
+func (s PostgresSchemaDeltaTestSuite) TestHistoryCarriesUnchangedToastColumns() {
+	flowJobName := "history_toast"
+	dstTableName := fmt.Sprintf("%s.history_toast", s.schema)
+	metadataSchema := s.connector.metadataSchema
+	s.connector.metadataSchema = s.schema
+	defer func() {
+		s.connector.metadataSchema = metadataSchema
+	}()
+	_, err := s.connector.CreateRawTable(&protos.CreateRawTableInput{FlowJobName: flowJobName})
+	require.NoError(s.t, err)
+	_, err = s.connector.pool.Exec(context.Background(), fmt.Sprintf(`CREATE TABLE %s(id BIGINT,val TEXT,
+		"%s" TIMESTAMP,"%s" TIMESTAMP,"%s" BOOLEAN)`, dstTableName,
+		shared.HistoryValidFromColName, shared.HistoryValidToColName, shared.HistoryIsCurrentColName))
+	require.NoError(s.t, err)
+
+	// an insert, then two updates to the same row in one batch, the second leaving the TOAST column unchanged
+	_, err = s.connector.pool.Exec(context.Background(), fmt.Sprintf(`INSERT INTO %s.%s(_peerdb_uid,_peerdb_timestamp,
+		_peerdb_destination_table_name,_peerdb_data,_peerdb_record_type,_peerdb_batch_id,_peerdb_unchanged_toast_columns)
+		VALUES ('1',1000,$1,'{"id":1,"val":"first"}',0,1,''),('2',2000,$1,'{"id":1,"val":"second"}',1,1,''),
+		('3',3000,$1,'{"id":1,"val":null}',1,1,'val')`, s.schema, getRawTableIdentifier(flowJobName)), dstTableName)
+	require.NoError(s.t, err)
+
+	normalizeStmtGen := &normalizeStmtGenerator{
+		rawTableName: getRawTableIdentifier(flowJobName),
+		dstTableName: dstTableName,
+		normalizedTableSchema: &protos.TableSchema{
+			ColumnNames:       []string{"id", "val"},
+			ColumnTypes:       []string{"int64", "string"},
+			PrimaryKeyColumns: []string{"id"},
+		},
+		unchangedToastColumns: []string{"", "val"},
+		peerdbCols:            &protos.PeerDBColumns{HistoryMode: true},
+		metadataSchema:        s.schema,
+	}
+	for _, stmt := range normalizeStmtGen.generateNormalizeStatements() {
+		_, err = s.connector.pool.Exec(context.Background(), stmt, 0, 1, dstTableName)
+		require.NoError(s.t, err)
+	}
+
+	// the last version carries the value of the update before it, not of the version before the batch
+	var values []string
+	err = s.connector.pool.QueryRow(context.Background(), fmt.Sprintf(`SELECT array_agg(val ORDER BY "%s")
+		FROM %s WHERE id=1`, shared.HistoryValidFromColName, dstTableName)).Scan(&values)
+	require.NoError(s.t, err)
+	require.Equal(s.t, []string{"first", "second", "second"}, values)
+
+	var currentValue string
+	err = s.connector.pool.QueryRow(context.Background(), fmt.Sprintf(`SELECT val FROM %s WHERE id=1 AND "%s"`,
+		dstTableName, shared.HistoryIsCurrentColName)).Scan(&currentValue)
+	require.NoError(s.t, err)
+	require.Equal(s.t, "second", currentValue)
+}
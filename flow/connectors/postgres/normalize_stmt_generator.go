@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/shared"
 	"golang.org/x/exp/maps"
 )
 
@@ -20,17 +22,23 @@ type normalizeStmtGenerator struct {
 	normalizedTableSchema *protos.TableSchema
 	// array of toast column combinations that are unchanged
 	unchangedToastColumns []string
-	// _PEERDB_IS_DELETED and _SYNCED_AT columns
+	// _PEERDB_IS_DELETED, _SYNCED_AT and before image columns
 	peerdbCols *protos.PeerDBColumns
 	// Postgres version 15 introduced MERGE, fallback statements before that
 	supportsMerge bool
 	// Postgres metadata schema
 	metadataSchema string
+	// if set, changes to rows written on the destination after the change was committed on the source
+	// are skipped, rows written by this replication origin are always overwritten
+	lastWriterWinsOrigin string
 	// to log fallback statement selection
 	logger slog.Logger
 }
 
 func (n *normalizeStmtGenerator) generateNormalizeStatements() []string {
+	if n.peerdbCols.HistoryMode {
+		return n.generateHistoryStatements()
+	}
 	if n.supportsMerge {
 		return []string{n.generateMergeStatement()}
 	}
@@ -42,6 +50,32 @@ func (n *normalizeStmtGenerator) generateNormalizeStatements() []string {
 	return n.generateFallbackStatements()
 }
 
+// additional condition for applying changes to matched rows, to resolve conflicts with changes on the destination
+func (n *normalizeStmtGenerator) conflictConditionSQL() string {
+	if n.lastWriterWinsOrigin == "" {
+		return ""
+	}
+	return lastWriterWinsSQL(n.lastWriterWinsOrigin)
+}
+
+// before image of the row, taken from the match data of updates and deletes
+func beforeImageValueSQL(tableAlias string) string {
+	return fmt.Sprintf("CASE WHEN %s_peerdb_record_type!=0 THEN %s_peerdb_match_data END", tableAlias, tableAlias)
+}
+
+// transaction metadata columns, and their values taken from the raw table
+func transactionMetadataColumnsSQL(tableAlias string) ([]string, []string) {
+	return []string{
+		fmt.Sprintf(`"%s"`, shared.CommitTimestampColName),
+		fmt.Sprintf(`"%s"`, shared.LSNColName),
+		fmt.Sprintf(`"%s"`, shared.TransactionIDColName),
+	}, []string{
+		fmt.Sprintf("to_timestamp(%s_peerdb_commit_ts/1000000000.0)", tableAlias),
+		tableAlias + "_peerdb_lsn",
+		tableAlias + "_peerdb_xid",
+	}
+}
+
 func (n *normalizeStmtGenerator) generateFallbackStatements() []string {
 	columnCount := utils.TableSchemaColumns(n.normalizedTableSchema)
 	columnNames := make([]string, 0, columnCount)
@@ -62,14 +96,29 @@ func (n *normalizeStmtGenerator) generateFallbackStatements() []string {
 			primaryKeyColumnCasts[columnName] = fmt.Sprintf("(_peerdb_data->>'%s')::%s", columnName, pgType)
 		}
 	})
+	updateColumnsSQLArray := make([]string, 0, utils.TableSchemaColumns(n.normalizedTableSchema)+1)
+	utils.IterColumns(n.normalizedTableSchema, func(columnName, _ string) {
+		updateColumnsSQLArray = append(updateColumnsSQLArray, fmt.Sprintf(`"%s"=EXCLUDED."%s"`, columnName, columnName))
+	})
+	if n.peerdbCols.BeforeImageColName != "" {
+		columnNames = append(columnNames, fmt.Sprintf(`"%s"`, n.peerdbCols.BeforeImageColName))
+		flattenedCastsSQLArray = append(flattenedCastsSQLArray, fmt.Sprintf(`%s AS "%s"`,
+			beforeImageValueSQL(""), n.peerdbCols.BeforeImageColName))
+		updateColumnsSQLArray = append(updateColumnsSQLArray, fmt.Sprintf(`"%s"=EXCLUDED."%s"`,
+			n.peerdbCols.BeforeImageColName, n.peerdbCols.BeforeImageColName))
+	}
+	if n.peerdbCols.TransactionMetadata {
+		txnCols, txnValues := transactionMetadataColumnsSQL("")
+		for i, txnCol := range txnCols {
+			columnNames = append(columnNames, txnCol)
+			flattenedCastsSQLArray = append(flattenedCastsSQLArray, fmt.Sprintf("%s AS %s", txnValues[i], txnCol))
+			updateColumnsSQLArray = append(updateColumnsSQLArray, fmt.Sprintf("%s=EXCLUDED.%s", txnCol, txnCol))
+		}
+	}
 	flattenedCastsSQL := strings.TrimSuffix(strings.Join(flattenedCastsSQLArray, ","), ",")
 	parsedDstTable, _ := utils.ParseSchemaTable(n.dstTableName)
 
 	insertColumnsSQL := strings.TrimSuffix(strings.Join(columnNames, ","), ",")
-	updateColumnsSQLArray := make([]string, 0, utils.TableSchemaColumns(n.normalizedTableSchema))
-	utils.IterColumns(n.normalizedTableSchema, func(columnName, _ string) {
-		updateColumnsSQLArray = append(updateColumnsSQLArray, fmt.Sprintf(`"%s"=EXCLUDED."%s"`, columnName, columnName))
-	})
 	updateColumnsSQL := strings.TrimSuffix(strings.Join(updateColumnsSQLArray, ","), ",")
 	deleteWhereClauseArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
 	for columnName, columnCast := range primaryKeyColumnCasts {
@@ -88,6 +137,16 @@ func (n *normalizeStmtGenerator) generateFallbackStatements() []string {
 			deletePart = fmt.Sprintf(`%s,"%s"=CURRENT_TIMESTAMP`,
 				deletePart, n.peerdbCols.SyncedAtColName)
 		}
+		if n.peerdbCols.BeforeImageColName != "" {
+			deletePart = fmt.Sprintf(`%s,"%s"=src_rank._peerdb_match_data`,
+				deletePart, n.peerdbCols.BeforeImageColName)
+		}
+		if n.peerdbCols.TransactionMetadata {
+			txnCols, txnValues := transactionMetadataColumnsSQL("src_rank.")
+			for i, txnCol := range txnCols {
+				deletePart = fmt.Sprintf("%s,%s=%s", deletePart, txnCol, txnValues[i])
+			}
+		}
 		deletePart += " FROM"
 	}
 	fallbackUpsertStatement := fmt.Sprintf(fallbackUpsertStatementSQL,
@@ -137,9 +196,18 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 	updateStatementsforToastCols := n.generateUpdateStatements(columnNames)
 	// append synced_at column
 	columnNames = append(columnNames, fmt.Sprintf(`"%s"`, n.peerdbCols.SyncedAtColName))
-	insertColumnsSQL := strings.Join(columnNames, ",")
 	// fill in synced_at column
 	insertValuesSQLArray = append(insertValuesSQLArray, "CURRENT_TIMESTAMP")
+	if n.peerdbCols.BeforeImageColName != "" {
+		columnNames = append(columnNames, fmt.Sprintf(`"%s"`, n.peerdbCols.BeforeImageColName))
+		insertValuesSQLArray = append(insertValuesSQLArray, beforeImageValueSQL("src."))
+	}
+	if n.peerdbCols.TransactionMetadata {
+		txnCols, txnValues := transactionMetadataColumnsSQL("src.")
+		columnNames = append(columnNames, txnCols...)
+		insertValuesSQLArray = append(insertValuesSQLArray, txnValues...)
+	}
+	insertColumnsSQL := strings.Join(columnNames, ",")
 	insertValuesSQL := strings.TrimSuffix(strings.Join(insertValuesSQLArray, ","), ",")
 
 	if n.peerdbCols.SoftDelete {
@@ -161,6 +229,16 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 			deletePart = fmt.Sprintf(`%s,"%s"=CURRENT_TIMESTAMP`,
 				deletePart, n.peerdbCols.SyncedAtColName)
 		}
+		if n.peerdbCols.BeforeImageColName != "" {
+			deletePart = fmt.Sprintf(`%s,"%s"=src._peerdb_match_data`,
+				deletePart, n.peerdbCols.BeforeImageColName)
+		}
+		if n.peerdbCols.TransactionMetadata {
+			txnCols, txnValues := transactionMetadataColumnsSQL("src.")
+			for i, txnCol := range txnCols {
+				deletePart = fmt.Sprintf("%s,%s=%s", deletePart, txnCol, txnValues[i])
+			}
+		}
 	}
 
 	mergeStmt := fmt.Sprintf(
@@ -174,6 +252,7 @@ func (n *normalizeStmtGenerator) generateMergeStatement() string {
 		insertColumnsSQL,
 		insertValuesSQL,
 		updateStringToastCols,
+		n.conflictConditionSQL(),
 		deletePart,
 	)
 
@@ -206,6 +285,16 @@ func (n *normalizeStmtGenerator) generateUpdateStatements(allCols []string) []st
 			tmpArray = append(tmpArray, fmt.Sprintf(`"%s"=CURRENT_TIMESTAMP`,
 				n.peerdbCols.SyncedAtColName))
 		}
+		if n.peerdbCols.BeforeImageColName != "" {
+			tmpArray = append(tmpArray, fmt.Sprintf(`"%s"=%s`,
+				n.peerdbCols.BeforeImageColName, beforeImageValueSQL("src.")))
+		}
+		if n.peerdbCols.TransactionMetadata {
+			txnCols, txnValues := transactionMetadataColumnsSQL("src.")
+			for i, txnCol := range txnCols {
+				tmpArray = append(tmpArray, fmt.Sprintf("%s=%s", txnCol, txnValues[i]))
+			}
+		}
 		// set soft-deleted to false, tackles insert after soft-delete
 		if handleSoftDelete {
 			tmpArray = append(tmpArray, fmt.Sprintf(`"%s"=FALSE`,
@@ -214,8 +303,8 @@ func (n *normalizeStmtGenerator) generateUpdateStatements(allCols []string) []st
 
 		ssep := strings.Join(tmpArray, ",")
 		updateStmt := fmt.Sprintf(`WHEN MATCHED AND
-			src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns='%s'
-			THEN UPDATE SET %s`, cols, ssep)
+			src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns='%s'%s
+			THEN UPDATE SET %s`, cols, n.conflictConditionSQL(), ssep)
 		updateStmts = append(updateStmts, updateStmt)
 
 		// generates update statements for the case where updates and deletes happen in the same branch
@@ -226,10 +315,114 @@ func (n *normalizeStmtGenerator) generateUpdateStatements(allCols []string) []st
 				fmt.Sprintf(`"%s"=TRUE`, n.peerdbCols.SoftDeleteColName))
 			ssep := strings.Join(tmpArray, ", ")
 			updateStmt := fmt.Sprintf(`WHEN MATCHED AND
-			src._peerdb_record_type=2 AND _peerdb_unchanged_toast_columns='%s'
-			THEN UPDATE SET %s `, cols, ssep)
+			src._peerdb_record_type=2 AND _peerdb_unchanged_toast_columns='%s'%s
+			THEN UPDATE SET %s `, cols, n.conflictConditionSQL(), ssep)
 			updateStmts = append(updateStmts, updateStmt)
 		}
 	}
 	return updateStmts
 }
+
+// generateHistoryStatements generates statements that insert every change as a new version of the row,
+// then close the version that was current before this batch. Versions are valid from the commit time
+// of the transaction that made the change, deletes only close the current version.
+// Unchanged TOAST columns take the value of the last change in the batch that had them,
+// or of the version that was current before this batch.
+func (n *normalizeStmtGenerator) generateHistoryStatements() []string {
+	parsedDstTable, _ := utils.ParseSchemaTable(n.dstTableName)
+	columnCount := utils.TableSchemaColumns(n.normalizedTableSchema)
+
+	columnNames := make([]string, 0, columnCount+4)
+	flattenedCastsSQLArray := make([]string, 0, columnCount)
+	selectValuesSQLArray := make([]string, 0, columnCount+4)
+	primaryKeyColumnCasts := make(map[string]string)
+	toastColumns := utils.UnchangedToastColumnNames(n.unchangedToastColumns)
+	toastGroupSQLArray := make([]string, 0, len(toastColumns)+1)
+	toastGroupSQLArray = append(toastGroupSQLArray, "LEAD(_peerdb_version_ts) OVER w AS _peerdb_next_timestamp")
+	toastValueSQLArray := make([]string, 0, len(toastColumns)+1)
+	toastValueSQLArray = append(toastValueSQLArray, "*")
+	quotedPrimaryKeyCols := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	for _, pkeyCol := range n.normalizedTableSchema.PrimaryKeyColumns {
+		quotedPrimaryKeyCols = append(quotedPrimaryKeyCols, fmt.Sprintf(`"%s"`, pkeyCol))
+	}
+	quotedPrimaryKeyColsSQL := strings.Join(quotedPrimaryKeyCols, ",")
+	utils.IterColumns(n.normalizedTableSchema, func(columnName, genericColumnType string) {
+		columnNames = append(columnNames, fmt.Sprintf(`"%s"`, columnName))
+		pgType := qValueKindToPostgresType(genericColumnType)
+		if qvalue.QValueKind(genericColumnType).IsArray() {
+			flattenedCastsSQLArray = append(flattenedCastsSQLArray,
+				fmt.Sprintf("ARRAY(SELECT * FROM JSON_ARRAY_ELEMENTS_TEXT((_peerdb_data->>'%s')::JSON))::%s AS \"%s\"",
+					strings.Trim(columnName, "\""), pgType, columnName))
+		} else {
+			flattenedCastsSQLArray = append(flattenedCastsSQLArray, fmt.Sprintf("(_peerdb_data->>'%s')::%s AS \"%s\"",
+				strings.Trim(columnName, "\""), pgType, columnName))
+		}
+		if slices.Contains(n.normalizedTableSchema.PrimaryKeyColumns, columnName) {
+			primaryKeyColumnCasts[columnName] = fmt.Sprintf("(_peerdb_data->>'%s')::%s", columnName, pgType)
+		}
+		if _, ok := toastColumns[columnName]; !ok {
+			selectValuesSQLArray = append(selectValuesSQLArray, fmt.Sprintf(`ver."%s"`, columnName))
+			return
+		}
+		// unchanged toast columns take the value of the last change in this batch that had them,
+		// or of the version that was current before this batch if there was none
+		toastIdx := len(toastGroupSQLArray) - 1
+		toastGroupSQLArray = append(toastGroupSQLArray, fmt.Sprintf(
+			`COUNT(CASE WHEN '%s'=ANY(string_to_array(_peerdb_unchanged_toast_columns,',')) THEN NULL ELSE 1 END)`+
+				` OVER w AS _peerdb_toast_group_%d`, columnName, toastIdx))
+		toastValueSQLArray = append(toastValueSQLArray, fmt.Sprintf(
+			`FIRST_VALUE("%s") OVER (PARTITION BY %s,_peerdb_toast_group_%d`+
+				` ORDER BY _peerdb_version_ts,_peerdb_lsn,_peerdb_timestamp) AS _peerdb_toast_value_%d`,
+			columnName, quotedPrimaryKeyColsSQL, toastIdx, toastIdx))
+		selectValuesSQLArray = append(selectValuesSQLArray, fmt.Sprintf(
+			`CASE WHEN ver._peerdb_toast_group_%d=0 THEN cur."%s" ELSE ver._peerdb_toast_value_%d END`,
+			toastIdx, columnName, toastIdx))
+	})
+
+	primaryKeySelectSQLArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	primaryKeyGroupBySQLArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	curJoinSQLArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	verExistsSQLArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	firstVerJoinSQLArray := make([]string, 0, len(n.normalizedTableSchema.PrimaryKeyColumns))
+	for i, pkeyCol := range n.normalizedTableSchema.PrimaryKeyColumns {
+		curJoinSQLArray = append(curJoinSQLArray, fmt.Sprintf(`cur."%s"=ver."%s"`, pkeyCol, pkeyCol))
+		verExistsSQLArray = append(verExistsSQLArray, fmt.Sprintf(`dst."%s"=ver."%s"`, pkeyCol, pkeyCol))
+		firstVerJoinSQLArray = append(firstVerJoinSQLArray, fmt.Sprintf(`dst."%s"=first_ver."%s"`, pkeyCol, pkeyCol))
+		primaryKeySelectSQLArray = append(primaryKeySelectSQLArray,
+			fmt.Sprintf(`%s AS "%s"`, primaryKeyColumnCasts[pkeyCol], pkeyCol))
+		primaryKeyGroupBySQLArray = append(primaryKeyGroupBySQLArray, strconv.Itoa(i+1))
+	}
+
+	columnNames = append(columnNames, fmt.Sprintf(`"%s"`, shared.HistoryValidFromColName),
+		fmt.Sprintf(`"%s"`, shared.HistoryValidToColName), fmt.Sprintf(`"%s"`, shared.HistoryIsCurrentColName))
+	selectValuesSQLArray = append(selectValuesSQLArray,
+		"to_timestamp(ver._peerdb_version_ts/1000000000.0)",
+		"to_timestamp(ver._peerdb_next_timestamp/1000000000.0)",
+		"ver._peerdb_next_timestamp IS NULL")
+	if n.peerdbCols.SyncedAtColName != "" {
+		columnNames = append(columnNames, fmt.Sprintf(`"%s"`, n.peerdbCols.SyncedAtColName))
+		selectValuesSQLArray = append(selectValuesSQLArray, "CURRENT_TIMESTAMP")
+	}
+	if n.peerdbCols.TransactionMetadata {
+		txnCols, txnValues := transactionMetadataColumnsSQL("ver.")
+		columnNames = append(columnNames, txnCols...)
+		selectValuesSQLArray = append(selectValuesSQLArray, txnValues...)
+	}
+
+	insertStatement := fmt.Sprintf(historyInsertStatementSQL,
+		strings.Join(flattenedCastsSQLArray, ","), n.metadataSchema, n.rawTableName,
+		strings.Join(toastGroupSQLArray, ","), quotedPrimaryKeyColsSQL, strings.Join(toastValueSQLArray, ","),
+		parsedDstTable.String(), strings.Join(columnNames, ","), strings.Join(selectValuesSQLArray, ","),
+		parsedDstTable.String(), strings.Join(curJoinSQLArray, " AND "), shared.HistoryIsCurrentColName,
+		parsedDstTable.String(), strings.Join(verExistsSQLArray, " AND "), shared.HistoryValidFromColName)
+
+	closeStatement := fmt.Sprintf(historyCloseStatementSQL,
+		strings.Join(primaryKeySelectSQLArray, ","), n.metadataSchema, n.rawTableName,
+		strings.Join(primaryKeyGroupBySQLArray, ","),
+		parsedDstTable.String(), shared.HistoryValidToColName, shared.HistoryIsCurrentColName,
+		strings.Join(firstVerJoinSQLArray, " AND "), shared.HistoryIsCurrentColName,
+		shared.HistoryValidFromColName, shared.HistoryValidFromColName)
+
+	// new versions are inserted first, so that unchanged toast columns can be read from the current version
+	return []string{insertStatement, closeStatement}
+}
@@ -1,11 +1,14 @@
 package connpostgres
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
 )
 
 func TestGenerateMergeUpdateStatement(t *testing.T) {
@@ -146,3 +149,214 @@ func TestGenerateMergeUpdateStatement_WithUnchangedToastColsAndSoftDelete(t *tes
 		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
 	}
 }
+
+func TestGenerateMergeUpdateStatement_WithBeforeImageAndSoftDelete(t *testing.T) {
+	allCols := []string{`"col1"`, `"col2"`, `"col3"`}
+	unchangedToastCols := []string{""}
+
+	expected := []string{
+		`WHEN MATCHED AND src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns=''
+		THEN UPDATE SET "col1"=src."col1","col2"=src."col2","col3"=src."col3",
+		 "_peerdb_synced_at"=CURRENT_TIMESTAMP,
+		 "_peerdb_before"=CASE WHEN src._peerdb_record_type!=0 THEN src._peerdb_match_data END,
+		 "_peerdb_soft_delete"=FALSE`,
+		`WHEN MATCHED AND src._peerdb_record_type=2 AND _peerdb_unchanged_toast_columns=''
+		 THEN UPDATE SET "col1"=src."col1","col2"=src."col2","col3"=src."col3",
+		 "_peerdb_synced_at"=CURRENT_TIMESTAMP,
+		 "_peerdb_before"=CASE WHEN src._peerdb_record_type!=0 THEN src._peerdb_match_data END,
+		 "_peerdb_soft_delete"=TRUE`,
+	}
+	normalizeGen := &normalizeStmtGenerator{
+		unchangedToastColumns: unchangedToastCols,
+		peerdbCols: &protos.PeerDBColumns{
+			SoftDelete:         true,
+			SyncedAtColName:    "_peerdb_synced_at",
+			SoftDeleteColName:  "_peerdb_soft_delete",
+			BeforeImageColName: "_peerdb_before",
+		},
+	}
+	result := normalizeGen.generateUpdateStatements(allCols)
+
+	for i := range expected {
+		expected[i] = utils.RemoveSpacesTabsNewlines(expected[i])
+		result[i] = utils.RemoveSpacesTabsNewlines(result[i])
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func TestGenerateMergeUpdateStatement_WithTransactionMetadata(t *testing.T) {
+	allCols := []string{`"col1"`, `"col2"`}
+	unchangedToastCols := []string{"", "col2"}
+
+	expected := []string{
+		`WHEN MATCHED AND src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns=''
+		THEN UPDATE SET "col1"=src."col1","col2"=src."col2","_peerdb_synced_at"=CURRENT_TIMESTAMP,
+		"_PEERDB_COMMIT_TS"=to_timestamp(src._peerdb_commit_ts/1000000000.0),
+		"_PEERDB_LSN"=src._peerdb_lsn,"_PEERDB_XID"=src._peerdb_xid`,
+		`WHEN MATCHED AND src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns='col2'
+		THEN UPDATE SET "col1"=src."col1","_peerdb_synced_at"=CURRENT_TIMESTAMP,
+		"_PEERDB_COMMIT_TS"=to_timestamp(src._peerdb_commit_ts/1000000000.0),
+		"_PEERDB_LSN"=src._peerdb_lsn,"_PEERDB_XID"=src._peerdb_xid`,
+	}
+	normalizeGen := &normalizeStmtGenerator{
+		unchangedToastColumns: unchangedToastCols,
+		peerdbCols: &protos.PeerDBColumns{
+			SyncedAtColName:     "_peerdb_synced_at",
+			TransactionMetadata: true,
+		},
+	}
+	result := normalizeGen.generateUpdateStatements(allCols)
+
+	for i := range expected {
+		expected[i] = utils.RemoveSpacesTabsNewlines(expected[i])
+		result[i] = utils.RemoveSpacesTabsNewlines(result[i])
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func TestGenerateMergeUpdateStatement_WithLastWriterWins(t *testing.T) {
+	allCols := []string{`"col1"`, `"col2"`}
+	unchangedToastCols := []string{""}
+
+	expected := []string{
+		`WHEN MATCHED AND src._peerdb_record_type!=2 AND _peerdb_unchanged_toast_columns=''
+		AND NOT EXISTS (SELECT 1 FROM pg_xact_commit_timestamp_origin(dst.xmin) o
+		WHERE o.roident IS DISTINCT FROM pg_replication_origin_oid('peerdb_test_flow')
+		AND o.timestamp>to_timestamp(COALESCE(src._peerdb_commit_ts,src._peerdb_timestamp)/1000000000.0))
+		THEN UPDATE SET "col1"=src."col1","col2"=src."col2","_peerdb_synced_at"=CURRENT_TIMESTAMP`,
+	}
+	normalizeGen := &normalizeStmtGenerator{
+		unchangedToastColumns: unchangedToastCols,
+		peerdbCols: &protos.PeerDBColumns{
+			SyncedAtColName: "_peerdb_synced_at",
+		},
+		lastWriterWinsOrigin: replicationOriginName("test_flow"),
+	}
+	result := normalizeGen.generateUpdateStatements(allCols)
+
+	for i := range expected {
+		expected[i] = utils.RemoveSpacesTabsNewlines(expected[i])
+		result[i] = utils.RemoveSpacesTabsNewlines(result[i])
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func TestGenerateHistoryStatements(t *testing.T) {
+	expected := []string{
+		`WITH src AS (
+		SELECT (_peerdb_data->>'id')::BIGINT AS "id",(_peerdb_data->>'val')::TEXT AS "val",
+		COALESCE(_peerdb_commit_ts,_peerdb_timestamp) AS _peerdb_version_ts,_peerdb_timestamp,
+		_peerdb_record_type,_peerdb_unchanged_toast_columns,_peerdb_commit_ts,_peerdb_lsn,_peerdb_xid
+		FROM _peerdb_internal._peerdb_raw_mirror
+		WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
+		), grp AS (
+		SELECT *,LEAD(_peerdb_version_ts) OVER w AS _peerdb_next_timestamp,
+		COUNT(CASE WHEN 'val'=ANY(string_to_array(_peerdb_unchanged_toast_columns,',')) THEN NULL ELSE 1 END)
+		OVER w AS _peerdb_toast_group_0
+		FROM src WINDOW w AS (PARTITION BY "id" ORDER BY _peerdb_version_ts,_peerdb_lsn,_peerdb_timestamp
+		ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)
+		), ver AS (
+		SELECT *,FIRST_VALUE("val") OVER (PARTITION BY "id",_peerdb_toast_group_0
+		ORDER BY _peerdb_version_ts,_peerdb_lsn,_peerdb_timestamp) AS _peerdb_toast_value_0
+		FROM grp
+		)
+		INSERT INTO "public"."dst" ("id","val","_PEERDB_VALID_FROM","_PEERDB_VALID_TO","_PEERDB_IS_CURRENT","_peerdb_synced_at")
+		SELECT ver."id",CASE WHEN ver._peerdb_toast_group_0=0 THEN cur."val" ELSE ver._peerdb_toast_value_0 END,
+		to_timestamp(ver._peerdb_version_ts/1000000000.0),to_timestamp(ver._peerdb_next_timestamp/1000000000.0),
+		ver._peerdb_next_timestamp IS NULL,CURRENT_TIMESTAMP FROM ver
+		LEFT JOIN "public"."dst" cur ON cur."id"=ver."id" AND cur."_PEERDB_IS_CURRENT"
+		WHERE ver._peerdb_record_type!=2 AND ver._peerdb_next_timestamp IS DISTINCT FROM ver._peerdb_version_ts
+		AND NOT EXISTS (
+		SELECT 1 FROM "public"."dst" dst WHERE dst."id"=ver."id"
+		AND dst."_PEERDB_VALID_FROM"=to_timestamp(ver._peerdb_version_ts/1000000000.0))`,
+		`WITH first_ver AS (
+		SELECT (_peerdb_data->>'id')::BIGINT AS "id",MIN(COALESCE(_peerdb_commit_ts,_peerdb_timestamp)) AS _peerdb_first_timestamp
+		FROM _peerdb_internal._peerdb_raw_mirror
+		WHERE _peerdb_batch_id>$1 AND _peerdb_batch_id<=$2 AND _peerdb_destination_table_name=$3
+		GROUP BY 1
+		)
+		UPDATE "public"."dst" dst SET "_PEERDB_VALID_TO"=to_timestamp(first_ver._peerdb_first_timestamp/1000000000.0),
+		"_PEERDB_IS_CURRENT"=FALSE
+		FROM first_ver WHERE dst."id"=first_ver."id" AND dst."_PEERDB_IS_CURRENT"
+		AND (dst."_PEERDB_VALID_FROM" IS NULL
+		OR dst."_PEERDB_VALID_FROM"<to_timestamp(first_ver._peerdb_first_timestamp/1000000000.0))`,
+	}
+	normalizeGen := &normalizeStmtGenerator{
+		rawTableName: "_peerdb_raw_mirror",
+		dstTableName: "public.dst",
+		normalizedTableSchema: &protos.TableSchema{
+			ColumnNames:       []string{"id", "val"},
+			ColumnTypes:       []string{"int64", "string"},
+			PrimaryKeyColumns: []string{"id"},
+		},
+		peerdbCols: &protos.PeerDBColumns{
+			SyncedAtColName: "_peerdb_synced_at",
+			HistoryMode:     true,
+		},
+		unchangedToastColumns: []string{"", "val"},
+		metadataSchema:        "_peerdb_internal",
+	}
+	result := normalizeGen.generateNormalizeStatements()
+
+	for i := range expected {
+		expected[i] = utils.RemoveSpacesTabsNewlines(expected[i])
+		result[i] = utils.RemoveSpacesTabsNewlines(result[i])
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func TestGenerateNormalizeStatements_BytesColumn(t *testing.T) {
+	normalizeGen := &normalizeStmtGenerator{
+		rawTableName: "_peerdb_raw_mirror",
+		dstTableName: "public.dst",
+		normalizedTableSchema: &protos.TableSchema{
+			ColumnNames:       []string{"id", "payload"},
+			ColumnTypes:       []string{"int64", "bytes"},
+			PrimaryKeyColumns: []string{"id"},
+		},
+		peerdbCols: &protos.PeerDBColumns{
+			HistoryMode: true,
+		},
+		metadataSchema: "_peerdb_internal",
+	}
+	result := normalizeGen.generateNormalizeStatements()
+
+	// bytes of existing mirrors are still cast from the raw table
+	expectedCast := utils.RemoveSpacesTabsNewlines(`(_peerdb_data->>'payload')::BYTEA AS "payload"`)
+	if !strings.Contains(utils.RemoveSpacesTabsNewlines(result[0]), expectedCast) {
+		t.Errorf("Expected bytes column to be cast, got: %v", result[0])
+	}
+}
+
+func TestMessageItemsJSON(t *testing.T) {
+	itemsJSON, err := messageItemsJSON(&model.MessageRecord{
+		Prefix:       "outbox",
+		Content:      []byte{0x00, 0xff, 'h', 'i'},
+		CheckPointID: 42,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the content is cast to bytea like any other bytes column, so it is written in its hex format
+	var items map[string]interface{}
+	err = json.Unmarshal([]byte(itemsJSON), &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[model.MessageContentColName] != `\x00ff6869` || items[model.MessagePrefixColName] != "outbox" {
+		t.Errorf("Unexpected message items: %s", itemsJSON)
+	}
+}
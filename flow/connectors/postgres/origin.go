@@ -0,0 +1,69 @@
+package connpostgres
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicationOriginName returns the name of the replication origin changes applied by a mirror are attributed to.
+func replicationOriginName(flowJobName string) string {
+	return "peerdb_" + flowJobName
+}
+
+// setupReplicationOrigin attributes changes made on the connection to the replication origin,
+// creating the origin if needed. Sources filtering with origin 'none' then skip these changes,
+// which keeps bidirectional mirrors from replicating them back. Only normalization sets up the origin,
+// raw tables are never part of a publication and an origin can only be active in one session at a time.
+func (c *PostgresConnector) setupReplicationOrigin(conn *pgxpool.Conn, originName string) error {
+	_, err := conn.Exec(c.ctx, `SELECT pg_replication_origin_create($1)
+		WHERE NOT EXISTS (SELECT 1 FROM pg_replication_origin WHERE roname=$1)`, originName)
+	if err != nil {
+		return fmt.Errorf("error creating replication origin %s: %w", originName, err)
+	}
+
+	_, err = conn.Exec(c.ctx, "SELECT pg_replication_origin_session_setup($1)", originName)
+	if err != nil {
+		return fmt.Errorf("error setting up replication origin %s: %w", originName, err)
+	}
+	return nil
+}
+
+// resetReplicationOrigin detaches the connection from its replication origin before it goes back to the pool,
+// a connection that can't be reset is closed instead.
+func (c *PostgresConnector) resetReplicationOrigin(conn *pgxpool.Conn) {
+	_, err := conn.Exec(c.ctx, "SELECT pg_replication_origin_session_reset()")
+	if err != nil {
+		c.logger.Error("error resetting replication origin, closing connection", slog.Any("error", err))
+		closeErr := conn.Hijack().Close(c.ctx)
+		if closeErr != nil {
+			c.logger.Error("error closing connection", slog.Any("error", closeErr))
+		}
+	}
+}
+
+// checkTrackCommitTimestamp checks that commit timestamps are tracked on the destination,
+// last writer wins can't tell when a row was last written without them.
+func (c *PostgresConnector) checkTrackCommitTimestamp() error {
+	var trackCommitTimestamp string
+	err := c.pool.QueryRow(c.ctx, "SHOW track_commit_timestamp").Scan(&trackCommitTimestamp)
+	if err != nil {
+		return fmt.Errorf("error checking track_commit_timestamp: %w", err)
+	}
+	if trackCommitTimestamp != "on" {
+		return errors.New("last writer wins conflict resolution requires track_commit_timestamp to be on")
+	}
+	return nil
+}
+
+// lastWriterWinsSQL is the condition for applying a change to a matched row of the destination.
+// Changes are skipped for rows last written by another origin after the change was committed on the source.
+// Rows last written by this mirror are always overwritten, changes from the source are applied in commit order.
+// Changes synced before commit times were recorded are compared by the time they were read from the source.
+func lastWriterWinsSQL(originName string) string {
+	return fmt.Sprintf(` AND NOT EXISTS (SELECT 1 FROM pg_xact_commit_timestamp_origin(dst.xmin) o`+
+		` WHERE o.roident IS DISTINCT FROM pg_replication_origin_oid('%s')`+
+		` AND o.timestamp>to_timestamp(COALESCE(src._peerdb_commit_ts,src._peerdb_timestamp)/1000000000.0))`, originName)
+}
@@ -0,0 +1,135 @@
+package connpostgres
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/exp/maps"
+)
+
+const defaultPartitionBound = "DEFAULT"
+
+// getPartitionKey returns the partition key of a partitioned table, empty if the table is not partitioned.
+func (c *PostgresConnector) getPartitionKey(schemaTable *utils.SchemaTable) (string, error) {
+	var partitionKey pgtype.Text
+	err := c.pool.QueryRow(c.ctx,
+		`SELECT pg_get_partkeydef(oid) FROM pg_class WHERE oid=$1::regclass AND relkind='p'`,
+		schemaTable.String()).Scan(&partitionKey)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", fmt.Errorf("error getting partition key of table %s: %w", schemaTable, err)
+	}
+	return partitionKey.String, nil
+}
+
+// getPartitionBounds returns the bounds of the partitions of a partitioned table, by partition name.
+// They are only read when creating destination tables, instead of being kept in table schemas.
+func (c *PostgresConnector) getPartitionBounds(schemaTable *utils.SchemaTable) (map[string]string, error) {
+	rows, err := c.pool.Query(c.ctx, `SELECT c.relname,pg_get_expr(c.relpartbound,c.oid)
+		FROM pg_inherits i JOIN pg_class c ON c.oid=i.inhrelid
+		WHERE i.inhparent=$1::regclass`, schemaTable.String())
+	if err != nil {
+		return nil, fmt.Errorf("error getting partitions of table %s: %w", schemaTable, err)
+	}
+
+	partitionBounds := make(map[string]string)
+	var partitionName, partitionBound pgtype.Text
+	_, err = pgx.ForEachRow(rows, []any{&partitionName, &partitionBound}, func() error {
+		partitionBounds[partitionName.String] = partitionBound.String
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading partitions of table %s: %w", schemaTable, err)
+	}
+	return partitionBounds, nil
+}
+
+// getSourcePartitionBounds reads the bounds of the partitions of the source table of a table schema
+// from the source peer.
+func (c *PostgresConnector) getSourcePartitionBounds(
+	sourcePeer *protos.Peer,
+	tableSchema *protos.TableSchema,
+) (map[string]string, error) {
+	sourceConfig := sourcePeer.GetPostgresConfig()
+	if sourceConfig == nil {
+		return nil, fmt.Errorf("partitioning table %s like its source requires a Postgres source peer",
+			tableSchema.TableIdentifier)
+	}
+	sourceTable, err := utils.ParseSchemaTable(tableSchema.TableIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing source table schema and name: %w", err)
+	}
+
+	sourceConn, err := NewPostgresConnector(c.ctx, sourceConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to source peer to read partitions: %w", err)
+	}
+	defer sourceConn.Close()
+
+	return sourceConn.getPartitionBounds(sourceTable)
+}
+
+// destinationPartitions returns the bounds of the partitions of a destination table partitioned like its source,
+// by partition name. Rows outside of the partitions existing at setup go to a default partition,
+// unless the source has one.
+func destinationPartitions(dstSchemaTable *utils.SchemaTable, partitionBounds map[string]string) map[string]string {
+	partitions := make(map[string]string, len(partitionBounds)+1)
+	hasDefault := false
+	for partitionName, partitionBound := range partitionBounds {
+		partitions[partitionName] = partitionBound
+		hasDefault = hasDefault || partitionBound == defaultPartitionBound
+	}
+	if !hasDefault {
+		partitions[dstSchemaTable.Table+"_default"] = defaultPartitionBound
+	}
+	return partitions
+}
+
+// generateCreatePartitionsSQL returns the statements creating the partitions of a destination table
+// partitioned like its source, in the schema of the destination table and named like on the source.
+func generateCreatePartitionsSQL(dstSchemaTable *utils.SchemaTable, partitionBounds map[string]string) []string {
+	partitions := destinationPartitions(dstSchemaTable, partitionBounds)
+	partitionNames := maps.Keys(partitions)
+	sort.Strings(partitionNames)
+
+	stmts := make([]string, 0, len(partitionNames))
+	for _, partitionName := range partitionNames {
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s PARTITION OF %s %s",
+			utils.QuoteIdentifier(dstSchemaTable.Schema), utils.QuoteIdentifier(partitionName),
+			dstSchemaTable.String(), partitions[partitionName]))
+	}
+	return stmts
+}
+
+// checkPartitionsAttached fails if tables named like the partitions of a destination table are not partitions of it,
+// as creating partitions that already exist as other tables is silently skipped.
+func (c *PostgresConnector) checkPartitionsAttached(
+	tx pgx.Tx,
+	dstSchemaTable *utils.SchemaTable,
+	partitionBounds map[string]string,
+) error {
+	partitionNames := maps.Keys(destinationPartitions(dstSchemaTable, partitionBounds))
+	rows, err := tx.Query(c.ctx, `SELECT c.relname FROM pg_class c JOIN pg_namespace n ON n.oid=c.relnamespace
+		WHERE n.nspname=$1 AND c.relname=ANY($2) AND NOT EXISTS (
+			SELECT 1 FROM pg_inherits i WHERE i.inhrelid=c.oid AND i.inhparent=$3::regclass)`,
+		dstSchemaTable.Schema, partitionNames, dstSchemaTable.String())
+	if err != nil {
+		return fmt.Errorf("error checking partitions of table %s: %w", dstSchemaTable, err)
+	}
+	unattachedPartitions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("error reading partitions of table %s: %w", dstSchemaTable, err)
+	}
+	if len(unattachedPartitions) > 0 {
+		sort.Strings(unattachedPartitions)
+		return fmt.Errorf("tables %v already exist in schema %s and are not partitions of table %s",
+			unattachedPartitions, dstSchemaTable.Schema, dstSchemaTable)
+	}
+	return nil
+}
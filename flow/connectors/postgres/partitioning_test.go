@@ -0,0 +1,68 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateCreatePartitionsSQL(t *testing.T) {
+	dstSchemaTable := &utils.SchemaTable{Schema: "public", Table: "events"}
+
+	expected := []string{
+		`CREATE TABLE IF NOT EXISTS "public"."events_2024_01" PARTITION OF "public"."events" ` +
+			`FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')`,
+		`CREATE TABLE IF NOT EXISTS "public"."events_2024_02" PARTITION OF "public"."events" ` +
+			`FOR VALUES FROM ('2024-02-01') TO ('2024-03-01')`,
+		`CREATE TABLE IF NOT EXISTS "public"."events_default" PARTITION OF "public"."events" DEFAULT`,
+	}
+	result := generateCreatePartitionsSQL(dstSchemaTable, map[string]string{
+		"events_2024_02": "FOR VALUES FROM ('2024-02-01') TO ('2024-03-01')",
+		"events_2024_01": "FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')",
+	})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func TestGenerateCreatePartitionsSQL_WithSourceDefault(t *testing.T) {
+	dstSchemaTable := &utils.SchemaTable{Schema: "public", Table: "events"}
+
+	expected := []string{
+		`CREATE TABLE IF NOT EXISTS "public"."events_other" PARTITION OF "public"."events" DEFAULT`,
+	}
+	result := generateCreatePartitionsSQL(dstSchemaTable, map[string]string{
+		"events_other": "DEFAULT",
+	})
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unexpected result. Expected: %v, but got: %v", expected, result)
+	}
+}
+
+func (s PostgresSchemaDeltaTestSuite) TestCheckPartitionsAttached() {
+	dstSchemaTable := &utils.SchemaTable{Schema: s.schema, Table: "partitioned_events"}
+	partitionBounds := map[string]string{"partitioned_events_2024": "FOR VALUES FROM (2024) TO (2025)"}
+	_, err := s.connector.pool.Exec(context.Background(), fmt.Sprintf(
+		"CREATE TABLE %s(id BIGINT,year INT) PARTITION BY RANGE (year)", dstSchemaTable))
+	require.NoError(s.t, err)
+	// a table of the same name as the default partition, which creating partitions would skip
+	_, err = s.connector.pool.Exec(context.Background(), fmt.Sprintf(
+		"CREATE TABLE %s.partitioned_events_default(id BIGINT,year INT)", s.schema))
+	require.NoError(s.t, err)
+
+	tx, err := s.connector.pool.Begin(context.Background())
+	require.NoError(s.t, err)
+	defer func() {
+		require.NoError(s.t, tx.Rollback(context.Background()))
+	}()
+	for _, createPartitionSQL := range generateCreatePartitionsSQL(dstSchemaTable, partitionBounds) {
+		_, err = tx.Exec(context.Background(), createPartitionSQL)
+		require.NoError(s.t, err)
+	}
+	err = s.connector.checkPartitionsAttached(tx, dstSchemaTable, partitionBounds)
+	require.ErrorContains(s.t, err, "partitioned_events_default")
+}
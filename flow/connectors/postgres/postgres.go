@@ -2,6 +2,8 @@ package connpostgres
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
@@ -32,6 +34,9 @@ type PostgresConnector struct {
 	customTypesMapping map[uint32]string
 	metadataSchema     string
 	logger             slog.Logger
+	// connections to the standby of the peer, only used by mirrors pulling from the standby
+	standbyPool     *SSHWrappedPostgresPool
+	standbyReplPool *SSHWrappedPostgresPool
 }
 
 // NewPostgresConnector creates a new instance of PostgresConnector.
@@ -119,6 +124,14 @@ func (c *PostgresConnector) Close() error {
 		c.replPool.Close()
 	}
 
+	if c.standbyPool != nil {
+		c.standbyPool.Close()
+	}
+
+	if c.standbyReplPool != nil {
+		c.standbyReplPool.Close()
+	}
+
 	return nil
 }
 
@@ -224,6 +237,23 @@ func (c *PostgresConnector) PullRecords(catalogPool *pgxpool.Pool, req *model.Pu
 		publicationName = ""
 	}
 
+	// when pulling from the standby, the slot is on the standby and the publication on the primary
+	var standbyPool *SSHWrappedPostgresPool
+	if req.PullFromStandby {
+		standbyPool, err = c.getStandbyPool()
+		if err != nil {
+			return err
+		}
+		err = c.checkStandby(standbyPool)
+		if err != nil {
+			return err
+		}
+		exists.SlotExists, err = c.checkStandbySlot(standbyPool, slotName)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !exists.SlotExists {
 		c.logger.Warn(fmt.Sprintf("slot %s does not exist", slotName))
 		return fmt.Errorf("replication slot %s does not exist", slotName)
@@ -231,11 +261,44 @@ func (c *PostgresConnector) PullRecords(catalogPool *pgxpool.Pool, req *model.Pu
 
 	c.logger.Info("PullRecords: performed checks for slot and publication")
 
-	replPool, err := c.GetReplPool(c.ctx)
+	var replPool *SSHWrappedPostgresPool
+	if req.PullFromStandby {
+		replPool, err = c.getStandbyReplPool()
+	} else {
+		replPool, err = c.GetReplPool(c.ctx)
+	}
 	if err != nil {
 		return err
 	}
 
+	// in-progress transactions can be streamed since Postgres 14
+	streamTransactions, err := c.majorVersionCheck(140000)
+	if err != nil {
+		return fmt.Errorf("failed to check Postgres version: %w", err)
+	}
+	// the pgoutput messages option is also only available since Postgres 14
+	if len(req.LogicalMessagePrefixes) > 0 && !streamTransactions {
+		return errors.New("capturing logical decoding messages requires Postgres 14 or later")
+	}
+	// tables requested to be snapshotted incrementally, chunks read by the last pull are recorded if they were synced
+	snapshotTables, err := monitoring.GetIncrementalSnapshotProgress(c.ctx, catalogPool, req.FlowJobName, req.LastOffset)
+	if err != nil {
+		return fmt.Errorf("failed to get incremental snapshot progress: %w", err)
+	}
+	if len(snapshotTables) > 0 && !streamTransactions {
+		return errors.New("incremental snapshots require Postgres 14 or later")
+	}
+	// the pgoutput origin option is only available since Postgres 16
+	if req.SkipReplicatedChanges {
+		supportsOrigin, err := c.majorVersionCheck(160000)
+		if err != nil {
+			return fmt.Errorf("failed to check Postgres version: %w", err)
+		}
+		if !supportsOrigin {
+			return errors.New("bidirectional mirrors require Postgres 16 or later on the source")
+		}
+	}
+
 	cdc, err := NewPostgresCDCSource(&PostgresCDCConfig{
 		AppContext:             c.ctx,
 		Connection:             replPool.Pool,
@@ -247,6 +310,17 @@ func (c *PostgresConnector) PullRecords(catalogPool *pgxpool.Pool, req *model.Pu
 		CatalogPool:            catalogPool,
 		FlowJobName:            req.FlowJobName,
 		SetLastOffset:          req.SetLastOffset,
+		QueryPool:              c.pool.Pool,
+		StreamTransactions:     streamTransactions,
+		LogicalMessagePrefixes: req.LogicalMessagePrefixes,
+		LogicalMessageTable:    req.LogicalMessageDestination,
+		SkipReplicatedChanges:  req.SkipReplicatedChanges,
+
+		IncrementalSnapshotTables:    snapshotTables,
+		IncrementalSnapshotChunkSize: int(req.IncrementalSnapshotChunkSize),
+		DeadLetterFailedRecords:      req.DeadLetterFailedRecords,
+		SourceColName:                req.SourceColName,
+		SourceColValue:               req.SourceColValue,
 	}, c.customTypesMapping)
 	if err != nil {
 		return fmt.Errorf("failed to create cdc source: %w", err)
@@ -299,6 +373,9 @@ func (c *PostgresConnector) SyncRecords(req *model.SyncRecordsRequest) (*model.S
 				"{}",
 				syncBatchID,
 				"",
+				model.GetCommitTimestamp(typedRecord),
+				typedRecord.CheckPointID,
+				int64(typedRecord.TransactionID),
 			})
 			tableNameRowsMapping[typedRecord.DestinationTableName] += 1
 		case *model.UpdateRecord:
@@ -320,6 +397,9 @@ func (c *PostgresConnector) SyncRecords(req *model.SyncRecordsRequest) (*model.S
 				oldItemsJSON,
 				syncBatchID,
 				utils.KeysToString(typedRecord.UnchangedToastColumns),
+				model.GetCommitTimestamp(typedRecord),
+				typedRecord.CheckPointID,
+				int64(typedRecord.TransactionID),
 			})
 			tableNameRowsMapping[typedRecord.DestinationTableName] += 1
 		case *model.DeleteRecord:
@@ -337,6 +417,30 @@ func (c *PostgresConnector) SyncRecords(req *model.SyncRecordsRequest) (*model.S
 				itemsJSON,
 				syncBatchID,
 				"",
+				model.GetCommitTimestamp(typedRecord),
+				typedRecord.CheckPointID,
+				int64(typedRecord.TransactionID),
+			})
+			tableNameRowsMapping[typedRecord.DestinationTableName] += 1
+		case *model.MessageRecord:
+			// logical messages are inserted into their destination table
+			itemsJSON, err := messageItemsJSON(typedRecord)
+			if err != nil {
+				return nil, fmt.Errorf("failed to serialize message record items to JSON: %w", err)
+			}
+
+			records = append(records, []interface{}{
+				uuid.New().String(),
+				time.Now().UnixNano(),
+				typedRecord.DestinationTableName,
+				itemsJSON,
+				0,
+				"{}",
+				syncBatchID,
+				"",
+				model.GetCommitTimestamp(typedRecord),
+				typedRecord.CheckPointID,
+				int64(typedRecord.TransactionID),
 			})
 			tableNameRowsMapping[typedRecord.DestinationTableName] += 1
 		default:
@@ -366,6 +470,7 @@ func (c *PostgresConnector) SyncRecords(req *model.SyncRecordsRequest) (*model.S
 		[]string{
 			"_peerdb_uid", "_peerdb_timestamp", "_peerdb_destination_table_name", "_peerdb_data",
 			"_peerdb_record_type", "_peerdb_match_data", "_peerdb_batch_id", "_peerdb_unchanged_toast_columns",
+			"_peerdb_commit_ts", "_peerdb_lsn", "_peerdb_xid",
 		},
 		pgx.CopyFromRows(records))
 	if err != nil {
@@ -422,6 +527,7 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get batch for the current mirror: %v", err)
 	}
+	*batchIDs = req.ReplayBatchIDs(*batchIDs)
 	// normalize has caught up with sync, chill until more records are loaded.
 	if batchIDs.NormalizeBatchID >= batchIDs.SyncBatchID {
 		c.logger.Info(fmt.Sprintf("no records to normalize: syncBatchID %d, normalizeBatchID %d",
@@ -438,13 +544,49 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	if err != nil {
 		return nil, err
 	}
+	destinationTableNames = req.ReplayTableNames(destinationTableNames)
 	unchangedToastColsMap, err := c.getTableNametoUnchangedCols(req.FlowJobName,
 		batchIDs.SyncBatchID, batchIDs.NormalizeBatchID)
 	if err != nil {
 		return nil, err
 	}
 
-	normalizeRecordsTx, err := c.pool.Begin(c.ctx)
+	supportsMerge, err := c.majorVersionCheck(150000)
+	if err != nil {
+		return nil, err
+	}
+	lastWriterWins := req.ConflictResolution == protos.ConflictResolution_CONFLICT_RESOLUTION_LAST_WRITER_WINS
+	if lastWriterWins {
+		if !supportsMerge {
+			return nil, errors.New("last writer wins conflict resolution requires Postgres 15 or later on the destination")
+		}
+		if req.HistoryMode {
+			return nil, errors.New("last writer wins conflict resolution is not supported in history mode")
+		}
+		err = c.checkTrackCommitTimestamp()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the replication origin is a property of the session, so the transaction runs on a connection of its own
+	normalizeConn, err := c.pool.Acquire(c.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error acquiring connection for normalizing records: %w", err)
+	}
+	defer normalizeConn.Release()
+
+	// last writer wins tells changes applied by this mirror apart by their origin
+	originName := replicationOriginName(req.FlowJobName)
+	if req.Bidirectional || lastWriterWins {
+		err = c.setupReplicationOrigin(normalizeConn, originName)
+		if err != nil {
+			return nil, err
+		}
+		defer c.resetReplicationOrigin(normalizeConn)
+	}
+
+	normalizeRecordsTx, err := normalizeConn.Begin(c.ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error starting transaction for normalizing records: %w", err)
 	}
@@ -454,11 +596,6 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 			c.logger.Error("error rolling back transaction for normalizing records", slog.Any("error", err))
 		}
 	}()
-
-	supportsMerge, err := c.majorVersionCheck(150000)
-	if err != nil {
-		return nil, err
-	}
 	mergeStatementsBatch := &pgx.Batch{}
 	totalRowsAffected := 0
 	for _, destinationTableName := range destinationTableNames {
@@ -468,14 +605,20 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 			normalizedTableSchema: c.tableSchemaMapping[destinationTableName],
 			unchangedToastColumns: unchangedToastColsMap[destinationTableName],
 			peerdbCols: &protos.PeerDBColumns{
-				SoftDeleteColName: req.SoftDeleteColName,
-				SyncedAtColName:   req.SyncedAtColName,
-				SoftDelete:        req.SoftDelete,
+				SoftDeleteColName:   req.SoftDeleteColName,
+				SyncedAtColName:     req.SyncedAtColName,
+				SoftDelete:          req.SoftDelete,
+				BeforeImageColName:  req.BeforeImageColName,
+				HistoryMode:         req.HistoryMode,
+				TransactionMetadata: req.TransactionMetadata,
 			},
 			supportsMerge:  supportsMerge,
 			metadataSchema: c.metadataSchema,
 			logger:         c.logger,
 		}
+		if lastWriterWins {
+			normalizeStmtGen.lastWriterWinsOrigin = originName
+		}
 		normalizeStatements := normalizeStmtGen.generateNormalizeStatements()
 		for _, normalizeStatement := range normalizeStatements {
 			mergeStatementsBatch.Queue(normalizeStatement, batchIDs.NormalizeBatchID, batchIDs.SyncBatchID, destinationTableName).Exec(
@@ -494,10 +637,12 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	}
 	c.logger.Info(fmt.Sprintf("normalized %d records", totalRowsAffected))
 
-	// updating metadata with new normalizeBatchID
-	err = c.updateNormalizeMetadata(req.FlowJobName, batchIDs.SyncBatchID, normalizeRecordsTx)
-	if err != nil {
-		return nil, err
+	// updating metadata with new normalizeBatchID, replays don't normalize new batches
+	if len(req.ReplayTables) == 0 {
+		err = c.updateNormalizeMetadata(req.FlowJobName, batchIDs.SyncBatchID, normalizeRecordsTx)
+		if err != nil {
+			return nil, err
+		}
 	}
 	// transaction commits
 	err = normalizeRecordsTx.Commit(c.ctx)
@@ -512,6 +657,17 @@ func (c *PostgresConnector) NormalizeRecords(req *model.NormalizeRecordsRequest)
 	}, nil
 }
 
+// messageItemsJSON serializes a logical message for the raw table. Its content is written in the hex format
+// of bytea, which columns are cast from when normalizing, rather than base64 encoded like other bytes.
+func messageItemsJSON(record *model.MessageRecord) (string, error) {
+	items := record.GetItems()
+	items.AddColumn(model.MessageContentColName, qvalue.QValue{
+		Kind:  qvalue.QValueKindString,
+		Value: `\x` + hex.EncodeToString(record.Content),
+	})
+	return items.ToJSON()
+}
+
 type SlotCheckResult struct {
 	SlotExists        bool
 	PublicationExists bool
@@ -541,6 +697,10 @@ func (c *PostgresConnector) CreateRawTable(req *protos.CreateRawTableInput) (*pr
 	if err != nil {
 		return nil, fmt.Errorf("error creating raw table: %w", err)
 	}
+	_, err = createRawTableTx.Exec(c.ctx, fmt.Sprintf(migrateRawTableSQL, c.metadataSchema, rawTableIdentifier))
+	if err != nil {
+		return nil, fmt.Errorf("error migrating raw table: %w", err)
+	}
 	_, err = createRawTableTx.Exec(c.ctx, fmt.Sprintf(createRawTableBatchIDIndexSQL, rawTableIdentifier,
 		c.metadataSchema, rawTableIdentifier))
 	if err != nil {
@@ -560,6 +720,16 @@ func (c *PostgresConnector) CreateRawTable(req *protos.CreateRawTableInput) (*pr
 	return nil, nil
 }
 
+// MigrateRawTable adds the columns raw tables created before transaction metadata was recorded are missing,
+// mirrors that are already running don't create their raw table again.
+func (c *PostgresConnector) MigrateRawTable(flowJobName string) error {
+	_, err := c.pool.Exec(c.ctx, fmt.Sprintf(migrateRawTableSQL, c.metadataSchema, getRawTableIdentifier(flowJobName)))
+	if err != nil {
+		return fmt.Errorf("error migrating raw table: %w", err)
+	}
+	return nil
+}
+
 // GetTableSchema returns the schema for a table, implementing the Connector interface.
 func (c *PostgresConnector) GetTableSchema(
 	req *protos.GetTableSchemaBatchInput,
@@ -617,6 +787,7 @@ func (c *PostgresConnector) getTableSchemaForTable(
 	fields := rows.FieldDescriptions()
 	columnNames := make([]string, 0, len(fields))
 	columnTypes := make([]string, 0, len(fields))
+	columnTypmods := make([]int32, 0, len(fields))
 	for _, fieldDescription := range fields {
 		genericColType := postgresOIDToQValueKind(fieldDescription.DataTypeOID)
 		if genericColType == qvalue.QValueKindInvalid {
@@ -630,11 +801,18 @@ func (c *PostgresConnector) getTableSchemaForTable(
 
 		columnNames = append(columnNames, fieldDescription.Name)
 		columnTypes = append(columnTypes, string(genericColType))
+		columnTypmods = append(columnTypmods, fieldDescription.TypeModifier)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating over table schema: %w", err)
 	}
+	rows.Close()
+
+	partitionKey, err := c.getPartitionKey(schemaTable)
+	if err != nil {
+		return nil, err
+	}
 
 	return &protos.TableSchema{
 		TableIdentifier:       tableName,
@@ -643,6 +821,8 @@ func (c *PostgresConnector) getTableSchemaForTable(
 		IsReplicaIdentityFull: replicaIdentityType == ReplicaIdentityFull,
 		ColumnNames:           columnNames,
 		ColumnTypes:           columnTypes,
+		ColumnTypmods:         columnTypmods,
+		PartitionKey:          partitionKey,
 	}, nil
 }
 
@@ -650,6 +830,16 @@ func (c *PostgresConnector) getTableSchemaForTable(
 func (c *PostgresConnector) SetupNormalizedTables(req *protos.SetupNormalizedTableBatchInput) (
 	*protos.SetupNormalizedTableBatchOutput, error,
 ) {
+	if req.ConflictResolution == protos.ConflictResolution_CONFLICT_RESOLUTION_LAST_WRITER_WINS {
+		if req.HistoryMode {
+			return nil, errors.New("last writer wins conflict resolution is not supported in history mode")
+		}
+		err := c.checkTrackCommitTimestamp()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tableExistsMapping := make(map[string]bool)
 	// Postgres is cool and supports transactional DDL. So we use a transaction.
 	createNormalizedTablesTx, err := c.pool.Begin(c.ctx)
@@ -680,11 +870,33 @@ func (c *PostgresConnector) SetupNormalizedTables(req *protos.SetupNormalizedTab
 
 		// convert the column names and types to Postgres types
 		normalizedTableCreateSQL := generateCreateTableSQLForNormalizedTable(
-			parsedNormalizedTable.String(), tableSchema, req.SoftDeleteColName, req.SyncedAtColName)
+			parsedNormalizedTable.String(), tableSchema, req.SoftDeleteColName, req.SyncedAtColName,
+			req.BeforeImageColName, req.HistoryMode, req.TransactionMetadata)
+		partitionLikeSource := req.TableLayoutMapping[tableIdentifier].GetPartitionLikeSource() &&
+			tableSchema.PartitionKey != ""
+		if partitionLikeSource {
+			normalizedTableCreateSQL += " PARTITION BY " + tableSchema.PartitionKey
+		}
 		_, err = createNormalizedTablesTx.Exec(c.ctx, normalizedTableCreateSQL)
 		if err != nil {
 			return nil, fmt.Errorf("error while creating normalized table: %w", err)
 		}
+		if partitionLikeSource {
+			partitionBounds, err := c.getSourcePartitionBounds(req.SourcePeerConnectionConfig, tableSchema)
+			if err != nil {
+				return nil, err
+			}
+			for _, createPartitionSQL := range generateCreatePartitionsSQL(parsedNormalizedTable, partitionBounds) {
+				_, err = createNormalizedTablesTx.Exec(c.ctx, createPartitionSQL)
+				if err != nil {
+					return nil, fmt.Errorf("error while creating partition of normalized table: %w", err)
+				}
+			}
+			err = c.checkPartitionsAttached(createNormalizedTablesTx, parsedNormalizedTable, partitionBounds)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		tableExistsMapping[tableIdentifier] = false
 		c.logger.Info(fmt.Sprintf("created table %s", tableIdentifier))
@@ -734,7 +946,7 @@ func (c *PostgresConnector) ReplayTableSchemaDeltas(flowJobName string,
 			_, err = tableSchemaModifyTx.Exec(c.ctx, fmt.Sprintf(
 				"ALTER TABLE %s ADD COLUMN IF NOT EXISTS \"%s\" %s",
 				schemaDelta.DstTableName, addedColumn.ColumnName,
-				qValueKindToPostgresType(addedColumn.ColumnType)))
+				qValueKindToPostgresColumnType(addedColumn.ColumnType, addedColumn.ColumnTypmod)))
 			if err != nil {
 				return fmt.Errorf("failed to add column %s for table %s: %w", addedColumn.ColumnName,
 					schemaDelta.DstTableName, err)
@@ -827,6 +1039,26 @@ func (c *PostgresConnector) SetupReplication(signal SlotSignal, req *protos.Setu
 		return fmt.Errorf("error checking for replication slot and publication: %w", err)
 	}
 
+	if req.PullFromStandby {
+		// the initial copy reads from the primary, which can't import a snapshot exported by the standby,
+		// so the copy wouldn't be consistent with the slot
+		if req.DoInitialCopy {
+			return errors.New("an initial copy is not supported when pulling from the standby, use an incremental snapshot")
+		}
+		standbyPool, err := c.getStandbyPool()
+		if err != nil {
+			return err
+		}
+		err = c.checkStandby(standbyPool)
+		if err != nil {
+			return err
+		}
+		exists.SlotExists, err = c.checkStandbySlot(standbyPool, slotName)
+		if err != nil {
+			return err
+		}
+	}
+
 	tableNameMapping := make(map[string]model.NameAndExclude)
 	for k, v := range req.TableNameMapping {
 		tableNameMapping[k] = model.NameAndExclude{
@@ -836,7 +1068,7 @@ func (c *PostgresConnector) SetupReplication(signal SlotSignal, req *protos.Setu
 	}
 	// Create the replication slot and publication
 	err = c.createSlotAndPublication(signal, exists,
-		slotName, publicationName, tableNameMapping, req.DoInitialCopy)
+		slotName, publicationName, tableNameMapping, req.DoInitialCopy, req.PullFromStandby)
 	if err != nil {
 		return fmt.Errorf("error creating replication slot and publication: %w", err)
 	}
@@ -844,7 +1076,7 @@ func (c *PostgresConnector) SetupReplication(signal SlotSignal, req *protos.Setu
 	return nil
 }
 
-func (c *PostgresConnector) PullFlowCleanup(jobName string) error {
+func (c *PostgresConnector) PullFlowCleanup(jobName string, pullFromStandby bool) error {
 	// Slotname would be the job name prefixed with "peerflow_slot_"
 	slotName := fmt.Sprintf("peerflow_slot_%s", jobName)
 
@@ -878,6 +1110,19 @@ func (c *PostgresConnector) PullFlowCleanup(jobName string) error {
 		return fmt.Errorf("error committing transaction for flow cleanup: %w", err)
 	}
 
+	// the slot of mirrors pulling from the standby is on the standby
+	if pullFromStandby {
+		standbyPool, err := c.getStandbyPool()
+		if err != nil {
+			return err
+		}
+		_, err = standbyPool.Exec(c.ctx, `SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots
+		 WHERE slot_name=$1`, slotName)
+		if err != nil {
+			return fmt.Errorf("error dropping replication slot on standby: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -234,6 +234,66 @@ func (s PostgresSchemaDeltaTestSuite) TestAddDropWhitespaceColumnNames() {
 	require.Equal(s.t, expectedTableSchema, output.TableNameSchemaMapping[tableName])
 }
 
+func (s PostgresSchemaDeltaTestSuite) TestRawTableMigration() {
+	// raw table as created before transaction metadata was recorded
+	flowJobName := "raw_table_migration"
+	_, err := s.connector.pool.Exec(context.Background(), fmt.Sprintf(`CREATE TABLE %s.%s(_peerdb_uid TEXT NOT NULL,
+		_peerdb_timestamp BIGINT NOT NULL,_peerdb_destination_table_name TEXT NOT NULL,_peerdb_data JSONB NOT NULL,
+		_peerdb_record_type INTEGER NOT NULL, _peerdb_match_data JSONB,_peerdb_batch_id INTEGER,
+		_peerdb_unchanged_toast_columns TEXT)`, s.schema, getRawTableIdentifier(flowJobName)))
+	require.NoError(s.t, err)
+
+	metadataSchema := s.connector.metadataSchema
+	s.connector.metadataSchema = s.schema
+	defer func() {
+		s.connector.metadataSchema = metadataSchema
+	}()
+	// running mirrors don't create their raw table again
+	err = s.connector.MigrateRawTable(flowJobName)
+	require.NoError(s.t, err)
+
+	var columns []string
+	err = s.connector.pool.QueryRow(context.Background(), `SELECT array_agg(column_name::TEXT ORDER BY ordinal_position)
+		FROM information_schema.columns WHERE table_schema=$1 AND table_name=$2`,
+		s.schema, getRawTableIdentifier(flowJobName)).Scan(&columns)
+	require.NoError(s.t, err)
+	require.Equal(s.t, []string{"_peerdb_commit_ts", "_peerdb_lsn", "_peerdb_xid"}, columns[len(columns)-3:])
+
+	// migrating again is a no-op
+	_, err = s.connector.CreateRawTable(&protos.CreateRawTableInput{FlowJobName: flowJobName})
+	require.NoError(s.t, err)
+}
+
+func (s PostgresSchemaDeltaTestSuite) TestSyncSequenceValues() {
+	// destination tables created by PeerDB have no sequences
+	tableName := fmt.Sprintf("%s.sync_sequence_values", s.schema)
+	_, err := s.connector.pool.Exec(context.Background(),
+		fmt.Sprintf("CREATE TABLE %s(id BIGINT PRIMARY KEY,val TEXT)", tableName))
+	require.NoError(s.t, err)
+
+	err = s.connector.SyncSequenceValues(map[string][]SequenceValue{
+		tableName: {{ColumnName: "id", LastValue: 41}},
+	}, 0)
+	require.NoError(s.t, err)
+
+	// the column now takes its values from the sequence, starting after the last value of the source
+	var id int64
+	err = s.connector.pool.QueryRow(context.Background(),
+		fmt.Sprintf("INSERT INTO %s(val) VALUES ('after cutover') RETURNING id", tableName)).Scan(&id)
+	require.NoError(s.t, err)
+	require.Equal(s.t, int64(42), id)
+
+	// sequences are never moved backwards
+	err = s.connector.SyncSequenceValues(map[string][]SequenceValue{
+		tableName: {{ColumnName: "id", LastValue: 10}},
+	}, 0)
+	require.NoError(s.t, err)
+	err = s.connector.pool.QueryRow(context.Background(),
+		fmt.Sprintf("INSERT INTO %s(val) VALUES ('after second sync') RETURNING id", tableName)).Scan(&id)
+	require.NoError(s.t, err)
+	require.Equal(s.t, int64(43), id)
+}
+
 func TestPostgresSchemaDeltaTestSuite(t *testing.T) {
 	e2eshared.RunSuite(t, SetupSuite, func(s PostgresSchemaDeltaTestSuite) {
 		teardownTx, err := s.connector.pool.Begin(context.Background())
@@ -121,6 +121,7 @@ func (qe *QRepQueryExecutor) fieldDescriptionsToSchema(fds []pgconn.FieldDescrip
 			Name:     cname,
 			Type:     ctype,
 			Nullable: cnullable,
+			Typmod:   fd.TypeModifier,
 		}
 	}
 	return model.NewQRecordSchema(qfields)
@@ -91,6 +91,16 @@ func postgresOIDToQValueKind(recvOID uint32) qvalue.QValueKind {
 	}
 }
 
+// qValueKindToPostgresColumnType is qValueKindToPostgresType for DDL, numerics keep the precision and scale of the typmod.
+func qValueKindToPostgresColumnType(qvalueKind string, typmod int32) string {
+	if qvalue.QValueKind(qvalueKind) == qvalue.QValueKindNumeric {
+		if precision, scale := qvalue.ParseNumericTypmod(typmod); precision > 0 {
+			return fmt.Sprintf("NUMERIC(%d,%d)", precision, scale)
+		}
+	}
+	return qValueKindToPostgresType(qvalueKind)
+}
+
 func qValueKindToPostgresType(qvalueKind string) string {
 	switch qvalue.QValueKind(qvalueKind) {
 	case qvalue.QValueKindBoolean:
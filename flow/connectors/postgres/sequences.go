@@ -0,0 +1,150 @@
+package connpostgres
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultSequenceSyncMargin is added to the last values of source sequences when syncing them,
+// so that values handed out on the source until the final sync don't collide on the destination.
+const DefaultSequenceSyncMargin = 1000
+
+// SequenceValue is the last value of the sequence owned by a column, as for serial and identity columns.
+type SequenceValue struct {
+	ColumnName string
+	LastValue  int64
+}
+
+// GetOwnedSequenceValues returns the last values of the sequences owned by columns of the tables, keyed by table.
+// Sequences that have never been used are left out.
+func (c *PostgresConnector) GetOwnedSequenceValues(tables []string) (map[string][]SequenceValue, error) {
+	sequenceValues := make(map[string][]SequenceValue, len(tables))
+	for _, table := range tables {
+		schemaTable, err := utils.ParseSchemaTable(table)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing schema and table: %w", err)
+		}
+
+		// serial columns own their sequence with an auto dependency, identity columns with an internal one
+		rows, err := c.pool.Query(c.ctx, `SELECT a.attname,pg_sequence_last_value(s.oid)
+			FROM pg_class s
+			JOIN pg_depend d ON d.objid=s.oid AND d.classid='pg_class'::regclass
+				AND d.refclassid='pg_class'::regclass AND d.deptype IN ('a','i')
+			JOIN pg_attribute a ON a.attrelid=d.refobjid AND a.attnum=d.refobjsubid
+			WHERE s.relkind='S' AND d.refobjid=$1::regclass`, schemaTable.String())
+		if err != nil {
+			return nil, fmt.Errorf("error querying sequences of table %s: %w", table, err)
+		}
+
+		var columnName pgtype.Text
+		var lastValue pgtype.Int8
+		_, err = pgx.ForEachRow(rows, []any{&columnName, &lastValue}, func() error {
+			if lastValue.Valid {
+				sequenceValues[table] = append(sequenceValues[table], SequenceValue{
+					ColumnName: columnName.String,
+					LastValue:  lastValue.Int64,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading sequences of table %s: %w", table, err)
+		}
+	}
+
+	return sequenceValues, nil
+}
+
+// SyncSequenceValues advances the sequences owned by columns of the tables to the given values plus margin.
+// Sequences are never moved backwards. Tables created by PeerDB have no sequences, so columns without one
+// get a sequence owned by the column and used as its default, which the column keeps after cutover.
+func (c *PostgresConnector) SyncSequenceValues(sequenceValues map[string][]SequenceValue, margin int64) error {
+	syncSequencesTx, err := c.pool.Begin(c.ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for syncing sequences: %w", err)
+	}
+	defer func() {
+		deferErr := syncSequencesTx.Rollback(c.ctx)
+		if deferErr != pgx.ErrTxClosed && deferErr != nil {
+			c.logger.Error("error rolling back transaction for syncing sequences", slog.Any("error", deferErr))
+		}
+	}()
+
+	for table, values := range sequenceValues {
+		schemaTable, err := utils.ParseSchemaTable(table)
+		if err != nil {
+			return fmt.Errorf("error parsing schema and table: %w", err)
+		}
+
+		for _, value := range values {
+			sequence, err := c.getOrCreateColumnSequence(syncSequencesTx, schemaTable, value.ColumnName)
+			if err != nil {
+				return err
+			}
+			if sequence == "" {
+				c.logger.Warn(fmt.Sprintf("column %s of table %s has no sequence, skipping", value.ColumnName, table))
+				continue
+			}
+
+			_, err = syncSequencesTx.Exec(c.ctx, `SELECT setval($1::regclass,
+				GREATEST($2::BIGINT,COALESCE(pg_sequence_last_value($1::regclass),$2::BIGINT)))`,
+				sequence, value.LastValue+margin)
+			if err != nil {
+				return fmt.Errorf("error syncing sequence of column %s of table %s: %w", value.ColumnName, table, err)
+			}
+		}
+	}
+
+	err = syncSequencesTx.Commit(c.ctx)
+	if err != nil {
+		return fmt.Errorf("error committing transaction for syncing sequences: %w", err)
+	}
+	return nil
+}
+
+// getOrCreateColumnSequence returns the sequence owned by a column, creating one named like the sequence
+// of a serial column if there is none. Returns an empty string if a sequence of that name exists
+// but isn't owned by the column.
+func (c *PostgresConnector) getOrCreateColumnSequence(
+	tx pgx.Tx,
+	schemaTable *utils.SchemaTable,
+	columnName string,
+) (string, error) {
+	var sequence pgtype.Text
+	err := tx.QueryRow(c.ctx, "SELECT pg_get_serial_sequence($1,$2)",
+		schemaTable.String(), columnName).Scan(&sequence)
+	if err != nil {
+		return "", fmt.Errorf("error getting sequence of column %s of table %s: %w", columnName, schemaTable, err)
+	}
+	if sequence.Valid {
+		return sequence.String, nil
+	}
+
+	sequenceName := fmt.Sprintf("%s.%s", utils.QuoteIdentifier(schemaTable.Schema),
+		utils.QuoteIdentifier(fmt.Sprintf("%s_%s_seq", schemaTable.Table, columnName)))
+	_, err = tx.Exec(c.ctx, fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s OWNED BY %s.%s",
+		sequenceName, schemaTable.String(), utils.QuoteIdentifier(columnName)))
+	if err != nil {
+		return "", fmt.Errorf("error creating sequence of column %s of table %s: %w", columnName, schemaTable, err)
+	}
+
+	err = tx.QueryRow(c.ctx, "SELECT pg_get_serial_sequence($1,$2)",
+		schemaTable.String(), columnName).Scan(&sequence)
+	if err != nil {
+		return "", fmt.Errorf("error getting sequence of column %s of table %s: %w", columnName, schemaTable, err)
+	}
+	if !sequence.Valid {
+		return "", nil
+	}
+
+	_, err = tx.Exec(c.ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT nextval(%s)",
+		schemaTable.String(), utils.QuoteIdentifier(columnName), utils.QuoteLiteral(sequence.String)))
+	if err != nil {
+		return "", fmt.Errorf("error setting default of column %s of table %s: %w", columnName, schemaTable, err)
+	}
+	return sequence.String, nil
+}
@@ -0,0 +1,171 @@
+package connpostgres
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// how often the primary is asked to log a snapshot while a slot is being created on the standby
+const standbySnapshotInterval = time.Second
+
+// standbyPostgresConfig returns the config of the standby of a peer, its database is the same as on the primary.
+func standbyPostgresConfig(config *protos.PostgresConfig) *protos.PostgresConfig {
+	return &protos.PostgresConfig{
+		Host:      config.Standby.Host,
+		Port:      config.Standby.Port,
+		User:      config.Standby.User,
+		Password:  config.Standby.Password,
+		Database:  config.Database,
+		SshConfig: config.Standby.SshConfig,
+	}
+}
+
+func (c *PostgresConnector) standbyPoolConfig() (*pgxpool.Config, *protos.SSHConfig, error) {
+	if c.config.Standby == nil {
+		return nil, nil, errors.New("no standby is configured for the source peer")
+	}
+
+	standbyConfig := standbyPostgresConfig(c.config)
+	connConfig, err := pgxpool.ParseConfig(utils.GetPGConnectionString(standbyConfig))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse standby connection string: %w", err)
+	}
+	return connConfig, standbyConfig.SshConfig, nil
+}
+
+// getStandbyPool returns the pool for non-replication queries on the standby.
+func (c *PostgresConnector) getStandbyPool() (*SSHWrappedPostgresPool, error) {
+	if c.standbyPool != nil {
+		return c.standbyPool, nil
+	}
+
+	connConfig, sshConfig, err := c.standbyPoolConfig()
+	if err != nil {
+		return nil, err
+	}
+	runtimeParams := connConfig.ConnConfig.RuntimeParams
+	runtimeParams["application_name"] = "peerdb_query_executor"
+	runtimeParams["idle_in_transaction_session_timeout"] = "0"
+	runtimeParams["statement_timeout"] = "0"
+	connConfig.MaxConns = 1
+
+	pool, err := NewSSHWrappedPostgresPool(c.ctx, connConfig, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create standby connection pool: %w", err)
+	}
+
+	c.standbyPool = pool
+	return pool, nil
+}
+
+// getStandbyReplPool returns the pool for the replication connection to the standby.
+func (c *PostgresConnector) getStandbyReplPool() (*SSHWrappedPostgresPool, error) {
+	if c.standbyReplPool != nil {
+		return c.standbyReplPool, nil
+	}
+
+	replConfig, sshConfig, err := c.standbyPoolConfig()
+	if err != nil {
+		return nil, err
+	}
+	replConfig.ConnConfig.RuntimeParams["replication"] = "database"
+	replConfig.ConnConfig.RuntimeParams["bytea_output"] = "hex"
+	replConfig.MaxConns = 1
+
+	pool, err := NewSSHWrappedPostgresPool(c.ctx, replConfig, sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create standby replication connection pool: %w", err)
+	}
+
+	c.standbyReplPool = pool
+	return pool, nil
+}
+
+// checkStandby ensures that logical decoding can be done on the standby of the source.
+// It has to run Postgres 16 or later, and hot_standby_feedback has to be on, otherwise the primary
+// can remove catalog rows still needed by the slot on the standby, which invalidates the slot.
+// A standby that has been promoted is used as is, slots on it keep working after promotion.
+func (c *PostgresConnector) checkStandby(pool *SSHWrappedPostgresPool) error {
+	var version pgtype.Int8
+	err := pool.QueryRow(c.ctx, "SELECT current_setting('server_version_num')::INTEGER").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to get standby server version: %w", err)
+	}
+	if version.Int64 < 160000 {
+		return fmt.Errorf("logical decoding on a standby requires Postgres 16 or later, standby runs %d",
+			version.Int64)
+	}
+
+	var inRecovery pgtype.Bool
+	err = pool.QueryRow(c.ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery)
+	if err != nil {
+		return fmt.Errorf("failed to check if standby is in recovery: %w", err)
+	}
+	if !inRecovery.Bool {
+		c.logger.Warn("standby of the source peer has been promoted, pulling changes from it as a primary")
+		return nil
+	}
+
+	var hotStandbyFeedback pgtype.Text
+	err = pool.QueryRow(c.ctx, "SELECT current_setting('hot_standby_feedback')").Scan(&hotStandbyFeedback)
+	if err != nil {
+		return fmt.Errorf("failed to get hot_standby_feedback of standby: %w", err)
+	}
+	if hotStandbyFeedback.String != "on" {
+		return errors.New("hot_standby_feedback must be on for logical decoding on the standby")
+	}
+
+	return nil
+}
+
+// checkStandbySlot checks if the replication slot exists on the standby.
+// Slots on a standby are invalidated when they conflict with recovery, for example when the primary
+// has removed rows the slot still needs or wal_level was lowered, such a slot can't be used anymore.
+func (c *PostgresConnector) checkStandbySlot(pool *SSHWrappedPostgresPool, slot string) (bool, error) {
+	var conflicting pgtype.Bool
+	err := pool.QueryRow(c.ctx,
+		"SELECT conflicting FROM pg_replication_slots WHERE slot_name = $1",
+		slot).Scan(&conflicting)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("error checking for replication slot on standby - %s: %w", slot, err)
+	}
+
+	if conflicting.Bool {
+		return true, fmt.Errorf("replication slot %s on the standby has been invalidated by a conflict with recovery, "+
+			"the mirror has to be resynced", slot)
+	}
+	return true, nil
+}
+
+// logStandbySnapshots makes the primary log the running transactions until done is closed.
+// Creating a slot on a standby waits for such a record, which the primary otherwise only logs
+// at checkpoints, so slot creation would take up to checkpoint_timeout.
+func (c *PostgresConnector) logStandbySnapshots(done <-chan struct{}) {
+	ticker := time.NewTicker(standbySnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := c.pool.Exec(c.ctx, "SELECT pg_log_standby_snapshot()")
+		if err != nil {
+			c.logger.Warn(fmt.Sprintf("failed to log standby snapshot on primary: %v", err))
+		}
+
+		select {
+		case <-done:
+			return
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package connpostgres
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// maxIdentifierLength is the length Postgres truncates identifiers to.
+const maxIdentifierLength = 63
+
+// TableMetadataStatement is a statement recreating metadata of a source table on its destination table.
+type TableMetadataStatement struct {
+	SQL string
+	// name of the index created by the statement, if any. Index names are unique per schema,
+	// so an existing index by that name is only skipped if it is on the destination table.
+	IndexName string
+}
+
+// TableMetadataDDL holds the statements recreating the metadata of a source table on its destination table.
+type TableMetadataDDL struct {
+	// column defaults, check constraints and column comments
+	Constraints []TableMetadataStatement
+	// secondary indexes and unique constraints, which can be created after the initial load
+	Indexes []TableMetadataStatement
+}
+
+// destinationIndexName names an index of a destination table after the destination table, as the names of
+// indexes of source tables in different schemas can clash once their tables are in the same schema.
+func destinationIndexName(srcTable *utils.SchemaTable, dstTable *utils.SchemaTable, name string) string {
+	name = dstTable.Table + "_" + strings.TrimPrefix(name, srcTable.Table+"_")
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+	// truncated like Postgres would, with a hash of the full name to keep truncated names apart
+	hash := sha256.Sum256([]byte(name))
+	suffix := "_" + hex.EncodeToString(hash[:4])
+	end := maxIdentifierLength - len(suffix)
+	for end > 0 && !utf8.RuneStart(name[end]) {
+		end--
+	}
+	return name[:end] + suffix
+}
+
+// deferredUniqueConstraintDef makes a unique constraint definition initially deferred. Normalize applies
+// the changes of a batch by kind rather than in order, so a value moving between rows can conflict
+// with itself until the end of the batch.
+func deferredUniqueConstraintDef(definition string, deferrable bool, deferred bool) string {
+	if deferred {
+		return definition
+	}
+	if deferrable {
+		return definition + " INITIALLY DEFERRED"
+	}
+	return definition + " DEFERRABLE INITIALLY DEFERRED"
+}
+
+// referencesExcluded returns true if any of the columns is excluded from the destination table.
+func referencesExcluded(columns []string, exclude []string) bool {
+	for _, column := range columns {
+		if slices.Contains(exclude, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTableMetadataDDL reads the secondary indexes, unique and check constraints, defaults and column comments
+// of a source table and returns the statements recreating them on the destination table.
+// Metadata referencing excluded columns is skipped, as are defaults using sequences, which don't exist
+// on the destination, and unique indexes and constraints if skipUnique is set. Destination tables keep rows
+// that are gone from the source in history mode and with soft deletes, and rows of other sources when
+// several sources share them, so values unique on the source can repeat on the destination.
+// Unique indexes are created without uniqueness, as unlike unique constraints they cannot be deferred
+// to the end of a normalize batch.
+func (c *PostgresConnector) GetTableMetadataDDL(
+	srcTable string,
+	dstTable string,
+	exclude []string,
+	skipUnique bool,
+) (*TableMetadataDDL, error) {
+	srcSchemaTable, err := utils.ParseSchemaTable(srcTable)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing source table: %w", err)
+	}
+	dstSchemaTable, err := utils.ParseSchemaTable(dstTable)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing destination table: %w", err)
+	}
+
+	ddl := &TableMetadataDDL{}
+	var name, definition pgtype.Text
+	var isUnique pgtype.Bool
+	var columns []string
+
+	// indexes backing constraints are created with their constraint
+	rows, err := c.pool.Query(c.ctx, `SELECT c.relname,i.indisunique,
+		substring(pg_get_indexdef(i.indexrelid) FROM ' USING .*$'),
+		ARRAY(SELECT a.attname FROM pg_attribute a WHERE a.attrelid=i.indrelid AND a.attnum=ANY(i.indkey))
+		FROM pg_index i JOIN pg_class c ON c.oid=i.indexrelid
+		WHERE i.indrelid=$1::regclass AND NOT i.indisprimary
+		AND NOT EXISTS(SELECT 1 FROM pg_constraint WHERE conindid=i.indexrelid AND conrelid=i.indrelid)`,
+		srcSchemaTable.String())
+	if err != nil {
+		return nil, fmt.Errorf("error querying indexes of table %s: %w", srcTable, err)
+	}
+	_, err = pgx.ForEachRow(rows, []any{&name, &isUnique, &definition, &columns}, func() error {
+		if referencesExcluded(columns, exclude) || (skipUnique && isUnique.Bool) {
+			return nil
+		}
+		indexName := destinationIndexName(srcSchemaTable, dstSchemaTable, name.String)
+		ddl.Indexes = append(ddl.Indexes, TableMetadataStatement{
+			SQL: fmt.Sprintf("CREATE INDEX %s ON %s%s",
+				utils.QuoteIdentifier(indexName), dstSchemaTable.String(), definition.String),
+			IndexName: indexName,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading indexes of table %s: %w", srcTable, err)
+	}
+
+	var constraintType pgtype.Text
+	var deferrable, deferred pgtype.Bool
+	rows, err = c.pool.Query(c.ctx, `SELECT conname,contype::TEXT,pg_get_constraintdef(oid),condeferrable,condeferred,
+		ARRAY(SELECT a.attname FROM pg_attribute a WHERE a.attrelid=conrelid AND a.attnum=ANY(conkey))
+		FROM pg_constraint WHERE conrelid=$1::regclass AND contype IN ('u','c') AND conislocal`,
+		srcSchemaTable.String())
+	if err != nil {
+		return nil, fmt.Errorf("error querying constraints of table %s: %w", srcTable, err)
+	}
+	_, err = pgx.ForEachRow(rows, []any{&name, &constraintType, &definition, &deferrable, &deferred, &columns},
+		func() error {
+			if referencesExcluded(columns, exclude) {
+				return nil
+			}
+			if constraintType.String != "u" {
+				ddl.Constraints = append(ddl.Constraints, TableMetadataStatement{
+					SQL: fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+						dstSchemaTable.String(), utils.QuoteIdentifier(name.String), definition.String),
+				})
+			} else if !skipUnique {
+				// unique constraints are backed by an index of the same name
+				indexName := destinationIndexName(srcSchemaTable, dstSchemaTable, name.String)
+				ddl.Indexes = append(ddl.Indexes, TableMetadataStatement{
+					SQL: fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+						dstSchemaTable.String(), utils.QuoteIdentifier(indexName),
+						deferredUniqueConstraintDef(definition.String, deferrable.Bool, deferred.Bool)),
+					IndexName: indexName,
+				})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error reading constraints of table %s: %w", srcTable, err)
+	}
+
+	var columnName, defaultExpr, comment pgtype.Text
+	rows, err = c.pool.Query(c.ctx, `SELECT a.attname,
+		CASE WHEN a.attgenerated='' THEN pg_get_expr(d.adbin,d.adrelid) END,
+		col_description(a.attrelid,a.attnum)
+		FROM pg_attribute a LEFT JOIN pg_attrdef d ON d.adrelid=a.attrelid AND d.adnum=a.attnum
+		WHERE a.attrelid=$1::regclass AND a.attnum>0 AND NOT a.attisdropped ORDER BY a.attnum`,
+		srcSchemaTable.String())
+	if err != nil {
+		return nil, fmt.Errorf("error querying columns of table %s: %w", srcTable, err)
+	}
+	_, err = pgx.ForEachRow(rows, []any{&columnName, &defaultExpr, &comment}, func() error {
+		if slices.Contains(exclude, columnName.String) {
+			return nil
+		}
+		if defaultExpr.Valid && !strings.Contains(defaultExpr.String, "nextval(") {
+			ddl.Constraints = append(ddl.Constraints, TableMetadataStatement{
+				SQL: fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s",
+					dstSchemaTable.String(), utils.QuoteIdentifier(columnName.String), defaultExpr.String),
+			})
+		}
+		if comment.Valid {
+			ddl.Constraints = append(ddl.Constraints, TableMetadataStatement{
+				SQL: fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s", dstSchemaTable.String(),
+					utils.QuoteIdentifier(columnName.String), utils.QuoteLiteral(comment.String)),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading columns of table %s: %w", srcTable, err)
+	}
+
+	return ddl, nil
+}
+
+// ExecuteTableMetadataDDL runs statements returned by GetTableMetadataDDL on a destination table,
+// constraints and indexes of the table that already exist are skipped so that it can be retried.
+func (c *PostgresConnector) ExecuteTableMetadataDDL(dstTable string, stmts []TableMetadataStatement) error {
+	dstSchemaTable, err := utils.ParseSchemaTable(dstTable)
+	if err != nil {
+		return fmt.Errorf("error parsing destination table: %w", err)
+	}
+
+	for _, stmt := range stmts {
+		_, err := c.pool.Exec(c.ctx, stmt.SQL)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) ||
+				(pgErr.Code != pgerrcode.DuplicateObject && pgErr.Code != pgerrcode.DuplicateTable) {
+				return fmt.Errorf("error executing %s: %w", stmt.SQL, err)
+			}
+			if stmt.IndexName != "" {
+				var onTable bool
+				err = c.pool.QueryRow(c.ctx,
+					`SELECT EXISTS(SELECT 1 FROM pg_index WHERE indexrelid=to_regclass($1) AND indrelid=$2::regclass)`,
+					utils.QuoteIdentifier(dstSchemaTable.Schema)+"."+utils.QuoteIdentifier(stmt.IndexName),
+					dstSchemaTable.String()).Scan(&onTable)
+				if err != nil {
+					return fmt.Errorf("error checking index %s: %w", stmt.IndexName, err)
+				}
+				if !onTable {
+					return fmt.Errorf("cannot create index %s on %s, the name is taken by another relation",
+						stmt.IndexName, dstTable)
+				}
+			}
+			continue
+		}
+		utils.RecordHeartbeatWithRecover(c.ctx, fmt.Sprintf("executed %s", stmt.SQL))
+	}
+	return nil
+}
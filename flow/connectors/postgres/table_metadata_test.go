@@ -0,0 +1,126 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestinationIndexName(t *testing.T) {
+	srcSchemaTable := &utils.SchemaTable{Schema: "sales", Table: "orders"}
+	dstSchemaTable := &utils.SchemaTable{Schema: "public", Table: "sales_orders"}
+
+	if result := destinationIndexName(srcSchemaTable, dstSchemaTable, "orders_created_at_idx"); result !=
+		"sales_orders_created_at_idx" {
+		t.Errorf("Unexpected name for index prefixed with its table: %s", result)
+	}
+	if result := destinationIndexName(srcSchemaTable, dstSchemaTable, "by_customer"); result !=
+		"sales_orders_by_customer" {
+		t.Errorf("Unexpected name for index not prefixed with its table: %s", result)
+	}
+}
+
+func TestDestinationIndexName_Truncated(t *testing.T) {
+	srcSchemaTable := &utils.SchemaTable{Schema: "sales", Table: "orders"}
+	dstSchemaTable := &utils.SchemaTable{Schema: "public", Table: strings.Repeat("é", 30)}
+
+	first := destinationIndexName(srcSchemaTable, dstSchemaTable, "orders_customer_id_created_at_idx")
+	second := destinationIndexName(srcSchemaTable, dstSchemaTable, "orders_customer_id_updated_at_idx")
+	if len(first) > maxIdentifierLength || len(second) > maxIdentifierLength {
+		t.Errorf("Names are longer than Postgres identifiers: %s, %s", first, second)
+	}
+	if !utf8.ValidString(first) || !utf8.ValidString(second) {
+		t.Errorf("Names were truncated in the middle of a character: %s, %s", first, second)
+	}
+	if first == second {
+		t.Errorf("Truncated names of different indexes are equal: %s", first)
+	}
+}
+
+func TestDeferredUniqueConstraintDef(t *testing.T) {
+	testCases := []struct {
+		definition string
+		deferrable bool
+		deferred   bool
+		expected   string
+	}{
+		{"UNIQUE (email)", false, false, "UNIQUE (email) DEFERRABLE INITIALLY DEFERRED"},
+		{"UNIQUE (email) DEFERRABLE", true, false, "UNIQUE (email) DEFERRABLE INITIALLY DEFERRED"},
+		{"UNIQUE (email) DEFERRABLE INITIALLY DEFERRED", true, true, "UNIQUE (email) DEFERRABLE INITIALLY DEFERRED"},
+	}
+	for _, tc := range testCases {
+		if result := deferredUniqueConstraintDef(tc.definition, tc.deferrable, tc.deferred); result != tc.expected {
+			t.Errorf("Unexpected result. Expected: %s, but got: %s", tc.expected, result)
+		}
+	}
+}
+
+func (s PostgresSchemaDeltaTestSuite) TestTableMetadataDDL() {
+	// source tables of different schemas with indexes of the same name, mirrored into one schema
+	srcSchema := s.schema + "_src"
+	_, err := s.connector.pool.Exec(context.Background(), fmt.Sprintf(`CREATE SCHEMA %[1]s;
+		CREATE TABLE %[1]s.accounts(id INT PRIMARY KEY,email TEXT UNIQUE,created_at TIMESTAMP);
+		CREATE INDEX by_created_at ON %[1]s.accounts(created_at);
+		CREATE TABLE %[2]s.users(id INT PRIMARY KEY,created_at TIMESTAMP);
+		CREATE INDEX by_created_at ON %[2]s.users(created_at);
+		CREATE TABLE %[2]s.accounts(id INT PRIMARY KEY,email TEXT,created_at TIMESTAMP);
+		CREATE TABLE %[2]s.users_copy(id INT PRIMARY KEY,created_at TIMESTAMP)`, srcSchema, s.schema))
+	require.NoError(s.t, err)
+	defer func() {
+		_, err := s.connector.pool.Exec(context.Background(), fmt.Sprintf("DROP SCHEMA %s CASCADE", srcSchema))
+		require.NoError(s.t, err)
+	}()
+
+	for _, mapping := range []struct {
+		srcTable   string
+		dstTable   string
+		numIndexes int
+	}{
+		{srcSchema + ".accounts", s.schema + ".accounts", 2},
+		{s.schema + ".users", s.schema + ".users_copy", 1},
+	} {
+		ddl, err := s.connector.GetTableMetadataDDL(mapping.srcTable, mapping.dstTable, nil, false)
+		require.NoError(s.t, err)
+		require.Len(s.t, ddl.Indexes, mapping.numIndexes)
+		err = s.connector.ExecuteTableMetadataDDL(mapping.dstTable, ddl.Indexes)
+		require.NoError(s.t, err)
+		// retries skip the indexes that were already created
+		err = s.connector.ExecuteTableMetadataDDL(mapping.dstTable, ddl.Indexes)
+		require.NoError(s.t, err)
+	}
+
+	// unique constraints are left out where values unique on the source can repeat on the destination
+	ddl, err := s.connector.GetTableMetadataDDL(srcSchema+".accounts", s.schema+".accounts", nil, true)
+	require.NoError(s.t, err)
+	require.Len(s.t, ddl.Indexes, 1)
+
+	// an index name taken by another table is not silently skipped
+	err = s.connector.ExecuteTableMetadataDDL(s.schema+".users", []TableMetadataStatement{{
+		SQL:       fmt.Sprintf("CREATE INDEX accounts_by_created_at ON %s.users(created_at)", s.schema),
+		IndexName: "accounts_by_created_at",
+	}})
+	require.Error(s.t, err)
+
+	var indexNames []string
+	err = s.connector.pool.QueryRow(context.Background(), `SELECT array_agg(indexname::TEXT ORDER BY indexname)
+		FROM pg_indexes WHERE schemaname=$1 AND indexname NOT LIKE '%pkey'`, s.schema).Scan(&indexNames)
+	require.NoError(s.t, err)
+	require.Equal(s.t, []string{"accounts_by_created_at", "accounts_email_key", "users_copy_by_created_at"}, indexNames)
+
+	// unique values can move between rows within a normalize batch
+	tx, err := s.connector.pool.Begin(context.Background())
+	require.NoError(s.t, err)
+	defer func() {
+		_ = tx.Rollback(context.Background())
+	}()
+	_, err = tx.Exec(context.Background(), fmt.Sprintf(`INSERT INTO %[1]s.accounts(id,email) VALUES (1,'a@example.com');
+		INSERT INTO %[1]s.accounts(id,email) VALUES (2,'a@example.com');
+		UPDATE %[1]s.accounts SET email=NULL WHERE id=1`, s.schema))
+	require.NoError(s.t, err)
+	require.NoError(s.t, tx.Commit(context.Background()))
+}
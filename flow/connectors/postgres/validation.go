@@ -0,0 +1,97 @@
+package connpostgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChecksumTableRange counts and checksums the rows of a table in a range of its key.
+// Rows are streamed through a cursor, so ranges don't have to fit in memory.
+func (c *PostgresConnector) ChecksumTableRange(req *model.TableRangeRequest) (*model.TableRangeChecksum, error) {
+	schemaTable, err := utils.ParseSchemaTable(req.TableIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing table %s: %w", req.TableIdentifier, err)
+	}
+
+	columns := req.AllColumns()
+	quotedColumns := make([]string, 0, len(columns))
+	for _, col := range columns {
+		quotedColumns = append(quotedColumns, utils.QuoteIdentifier(col))
+	}
+
+	var conditions []string
+	var args []interface{}
+	if req.RangeColumn != "" {
+		rangeExpr := utils.QuoteIdentifier(req.RangeColumn)
+		if req.RangeAsText {
+			rangeExpr += `::text COLLATE "C"`
+		}
+		if req.Start != nil {
+			args = append(args, req.Start)
+			conditions = append(conditions, fmt.Sprintf("%s>$%d", rangeExpr, len(args)))
+		}
+		if req.End != nil {
+			args = append(args, req.End)
+			conditions = append(conditions, fmt.Sprintf("%s<=$%d", rangeExpr, len(args)))
+		}
+	}
+	if req.SoftDeleteColName != "" {
+		conditions = append(conditions, fmt.Sprintf("NOT COALESCE(%s,false)", utils.QuoteIdentifier(req.SoftDeleteColName)))
+	}
+	if req.CurrentColName != "" {
+		conditions = append(conditions, utils.QuoteIdentifier(req.CurrentColName))
+	}
+	if req.SourceColName != "" {
+		args = append(args, req.SourceColValue)
+		conditions = append(conditions, fmt.Sprintf("%s=$%d", utils.QuoteIdentifier(req.SourceColName), len(args)))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(quotedColumns, ","), schemaTable.String())
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	executor := NewQRepQueryExecutor(c.pool.Pool, c.ctx, "", "")
+	stream := model.NewQRecordStream(shared.FetchAndChannelSize)
+	queryErr := make(chan error, 1)
+	go func() {
+		_, err := executor.ExecuteAndProcessQueryStream(stream, query, args...)
+		queryErr <- err
+	}()
+
+	checksum := model.NewTableRangeChecksum(req)
+	streamErr := checksum.AddQRecordStream(stream)
+	if err := <-queryErr; err != nil {
+		return nil, fmt.Errorf("error reading rows of table %s: %w", req.TableIdentifier, err)
+	}
+	if streamErr != nil {
+		return nil, fmt.Errorf("error reading rows of table %s: %w", req.TableIdentifier, streamErr)
+	}
+	return checksum, nil
+}
+
+// GetTextKeyRangeEnds splits a table into ranges of about rowsPerRange rows on the text of a key column
+// in byte order, which destinations compare strings in. Rows after the last end returned form the last range.
+func (c *PostgresConnector) GetTextKeyRangeEnds(table string, column string, rowsPerRange int64) ([]string, error) {
+	schemaTable, err := utils.ParseSchemaTable(table)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing table %s: %w", table, err)
+	}
+
+	rows, err := c.pool.Query(c.ctx, fmt.Sprintf(`SELECT k FROM (SELECT k,row_number() OVER (ORDER BY k) rn
+		FROM (SELECT %s::text COLLATE "C" k FROM %s) t) r WHERE rn%%$1=0 ORDER BY k`,
+		utils.QuoteIdentifier(column), schemaTable.String()), rowsPerRange)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ranges of table %s: %w", table, err)
+	}
+	rangeEnds, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("error reading ranges of table %s: %w", table, err)
+	}
+	return rangeEnds, nil
+}
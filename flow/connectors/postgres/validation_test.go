@@ -0,0 +1,43 @@
+package connpostgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/stretchr/testify/require"
+)
+
+func (s PostgresSchemaDeltaTestSuite) TestChecksumTextKeyRanges() {
+	tableName := fmt.Sprintf("%s.checksum_text_keys", s.schema)
+	_, err := s.connector.pool.Exec(context.Background(), fmt.Sprintf(`CREATE TABLE %s(id TEXT PRIMARY KEY,doc JSONB);
+		INSERT INTO %[1]s SELECT 'key_'||i,jsonb_build_object('i',i) FROM generate_series(1,10) i`, tableName))
+	require.NoError(s.t, err)
+
+	// ranges follow byte order, key_10 sorts before key_2
+	rangeEnds, err := s.connector.GetTextKeyRangeEnds(tableName, "id", 4)
+	require.NoError(s.t, err)
+	require.Equal(s.t, []string{"key_3", "key_7"}, rangeEnds)
+
+	var numRows int64
+	for i := 0; i <= len(rangeEnds); i++ {
+		req := &model.TableRangeRequest{
+			TableIdentifier: tableName,
+			KeyColumns:      []string{"id"},
+			Columns:         []string{"doc"},
+			JSONColumns:     []string{"doc"},
+			RangeColumn:     "id",
+			RangeAsText:     true,
+		}
+		if i > 0 {
+			req.Start = rangeEnds[i-1]
+		}
+		if i < len(rangeEnds) {
+			req.End = rangeEnds[i]
+		}
+		checksum, err := s.connector.ChecksumTableRange(req)
+		require.NoError(s.t, err)
+		numRows += checksum.NumRows
+	}
+	require.Equal(s.t, int64(10), numRows)
+}
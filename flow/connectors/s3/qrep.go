@@ -43,7 +43,17 @@ func getAvroSchema(
 	dstTableName string,
 	schema *model.QRecordSchema,
 ) (*model.QRecordAvroSchemaDefinition, error) {
-	avroSchema, err := model.GetAvroSchemaDefinition(dstTableName, schema)
+	// files are written like for Snowflake, except for numerics Snowflake stores as strings,
+	// which are written as decimals with the default precision and scale like on other destinations
+	fields := make([]model.QField, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if field.Type == qvalue.QValueKindNumeric && qvalue.NumericStoredAsString(field.Typmod, qvalue.QDWHTypeSnowflake) {
+			field.Typmod = qvalue.NumericTypmod(qvalue.PeerDBNumericPrecision, qvalue.PeerDBNumericScale)
+		}
+		fields = append(fields, field)
+	}
+
+	avroSchema, err := model.GetAvroSchemaDefinition(dstTableName, model.NewQRecordSchema(fields), qvalue.QDWHTypeSnowflake)
 	if err != nil {
 		return nil, fmt.Errorf("failed to define Avro schema: %w", err)
 	}
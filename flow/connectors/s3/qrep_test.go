@@ -0,0 +1,31 @@
+package conns3
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestGetAvroSchemaUnconstrainedNumeric(t *testing.T) {
+	schema := model.NewQRecordSchema([]model.QField{
+		{Name: "id", Type: qvalue.QValueKindInt64},
+		{Name: "unconstrained", Type: qvalue.QValueKindNumeric, Typmod: -1, Nullable: true},
+		{Name: "constrained", Type: qvalue.QValueKindNumeric, Typmod: qvalue.NumericTypmod(10, 2)},
+	})
+
+	avroSchema, err := getAvroSchema("dst", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(avroSchema.StringNumericFields) != 0 {
+		t.Errorf("expected no numerics written as strings, got %v", avroSchema.StringNumericFields)
+	}
+	expected := `{"type":"record","name":"dst","fields":[{"name":"id","type":"long"},` +
+		`{"name":"unconstrained","type":["null",{"type":"bytes","logicalType":"decimal","precision":38,"scale":9}]},` +
+		`{"name":"constrained","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}}]}`
+	if avroSchema.Schema != expected {
+		t.Errorf("expected schema %s, got %s", expected, avroSchema.Schema)
+	}
+}
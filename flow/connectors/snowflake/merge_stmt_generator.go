@@ -7,6 +7,7 @@ import (
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/PeerDB-io/peer-flow/shared"
 )
 
 type mergeStmtGenerator struct {
@@ -21,18 +22,33 @@ type mergeStmtGenerator struct {
 	normalizedTableSchema *protos.TableSchema
 	// array of toast column combinations that are unchanged
 	unchangedToastColumns []string
-	// _PEERDB_IS_DELETED and _SYNCED_AT columns
+	// _PEERDB_IS_DELETED, _SYNCED_AT and before image columns
 	peerdbCols *protos.PeerDBColumns
 }
 
-func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
-	parsedDstTable, _ := utils.ParseSchemaTable(m.dstTableName)
-	columnNames := utils.TableSchemaColumnNames(m.normalizedTableSchema)
+// before image of the row, taken from the match data of updates and deletes
+const beforeImageValueSQL = "CASE WHEN SOURCE._PEERDB_RECORD_TYPE != 0 THEN PARSE_JSON(SOURCE._PEERDB_MATCH_DATA) END"
 
+// transaction metadata columns, and their values taken from the raw table
+func transactionMetadataColumnsSQL(tableAlias string) ([]string, []string) {
+	return []string{
+		fmt.Sprintf(`"%s"`, shared.CommitTimestampColName),
+		fmt.Sprintf(`"%s"`, shared.LSNColName),
+		fmt.Sprintf(`"%s"`, shared.TransactionIDColName),
+	}, []string{
+		fmt.Sprintf("TO_TIMESTAMP_NTZ(%s._PEERDB_COMMIT_TS, 9)", tableAlias),
+		tableAlias + "._PEERDB_LSN",
+		tableAlias + "._PEERDB_XID",
+	}
+}
+
+// generateFlattenedCasts generates the projections extracting each column from the variant of the raw data.
+func (m *mergeStmtGenerator) generateFlattenedCasts() (string, error) {
 	flattenedCastsSQLArray := make([]string, 0, utils.TableSchemaColumns(m.normalizedTableSchema))
 	err := utils.IterColumnsError(m.normalizedTableSchema, func(columnName, genericColumnType string) error {
 		qvKind := qvalue.QValueKind(genericColumnType)
-		sfType, err := qValueKindToSnowflakeType(qvKind)
+		sfType, err := qValueKindToSnowflakeType(qvKind,
+			utils.TableSchemaColumnTypmod(m.normalizedTableSchema, columnName))
 		if err != nil {
 			return fmt.Errorf("failed to convert column type %s to snowflake type: %w", genericColumnType, err)
 		}
@@ -74,7 +90,17 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	flattenedCastsSQL := strings.TrimSuffix(strings.Join(flattenedCastsSQLArray, ""), ",")
+	return strings.TrimSuffix(strings.Join(flattenedCastsSQLArray, ""), ","), nil
+}
+
+func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
+	parsedDstTable, _ := utils.ParseSchemaTable(m.dstTableName)
+	columnNames := utils.TableSchemaColumnNames(m.normalizedTableSchema)
+
+	flattenedCastsSQL, err := m.generateFlattenedCasts()
+	if err != nil {
+		return "", err
+	}
 
 	quotedUpperColNames := make([]string, 0, len(columnNames))
 	for _, columnName := range columnNames {
@@ -84,6 +110,14 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 	quotedUpperColNames = append(quotedUpperColNames,
 		fmt.Sprintf(`"%s"`, strings.ToUpper(m.peerdbCols.SyncedAtColName)),
 	)
+	if m.peerdbCols.BeforeImageColName != "" {
+		quotedUpperColNames = append(quotedUpperColNames,
+			fmt.Sprintf(`"%s"`, strings.ToUpper(m.peerdbCols.BeforeImageColName)))
+	}
+	txnCols, txnValues := transactionMetadataColumnsSQL("SOURCE")
+	if m.peerdbCols.TransactionMetadata {
+		quotedUpperColNames = append(quotedUpperColNames, txnCols...)
+	}
 
 	insertColumnsSQL := strings.TrimSuffix(strings.Join(quotedUpperColNames, ","), ",")
 
@@ -94,6 +128,12 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 	}
 	// fill in synced_at column
 	insertValuesSQLArray = append(insertValuesSQLArray, "CURRENT_TIMESTAMP")
+	if m.peerdbCols.BeforeImageColName != "" {
+		insertValuesSQLArray = append(insertValuesSQLArray, beforeImageValueSQL)
+	}
+	if m.peerdbCols.TransactionMetadata {
+		insertValuesSQLArray = append(insertValuesSQLArray, txnValues...)
+	}
 	insertValuesSQL := strings.Join(insertValuesSQLArray, ",")
 	updateStatementsforToastCols := m.generateUpdateStatements(columnNames)
 
@@ -128,6 +168,15 @@ func (m *mergeStmtGenerator) generateMergeStmt() (string, error) {
 		if m.peerdbCols.SyncedAtColName != "" {
 			deletePart = fmt.Sprintf("%s, %s = CURRENT_TIMESTAMP", deletePart, m.peerdbCols.SyncedAtColName)
 		}
+		if m.peerdbCols.BeforeImageColName != "" {
+			deletePart = fmt.Sprintf("%s, %s = PARSE_JSON(SOURCE._PEERDB_MATCH_DATA)",
+				deletePart, m.peerdbCols.BeforeImageColName)
+		}
+		if m.peerdbCols.TransactionMetadata {
+			for i, txnCol := range txnCols {
+				deletePart = fmt.Sprintf("%s, %s = %s", deletePart, txnCol, txnValues[i])
+			}
+		}
 	}
 
 	mergeStatement := fmt.Sprintf(mergeStatementSQL, snowflakeSchemaTableNormalize(parsedDstTable),
@@ -175,7 +224,7 @@ func (m *mergeStmtGenerator) generateUpdateStatements(allCols []string) []string
 	for _, cols := range m.unchangedToastColumns {
 		unchangedColsArray := strings.Split(cols, ",")
 		otherCols := utils.ArrayMinus(allCols, unchangedColsArray)
-		tmpArray := make([]string, 0, len(otherCols)+2)
+		tmpArray := make([]string, 0, len(otherCols)+6)
 		for _, colName := range otherCols {
 			normalizedColName := SnowflakeIdentifierNormalize(colName)
 			tmpArray = append(tmpArray, fmt.Sprintf("%s = SOURCE.%s", normalizedColName, normalizedColName))
@@ -186,6 +235,16 @@ func (m *mergeStmtGenerator) generateUpdateStatements(allCols []string) []string
 			tmpArray = append(tmpArray, fmt.Sprintf(`"%s" = CURRENT_TIMESTAMP`,
 				m.peerdbCols.SyncedAtColName))
 		}
+		if m.peerdbCols.BeforeImageColName != "" {
+			tmpArray = append(tmpArray, fmt.Sprintf(`"%s" = %s`,
+				m.peerdbCols.BeforeImageColName, beforeImageValueSQL))
+		}
+		if m.peerdbCols.TransactionMetadata {
+			txnCols, txnValues := transactionMetadataColumnsSQL("SOURCE")
+			for i, txnCol := range txnCols {
+				tmpArray = append(tmpArray, fmt.Sprintf("%s = %s", txnCol, txnValues[i]))
+			}
+		}
 		// set soft-deleted to false, tackles insert after soft-delete
 		if handleSoftDelete {
 			tmpArray = append(tmpArray, fmt.Sprintf(`"%s" = FALSE`,
@@ -213,3 +272,102 @@ func (m *mergeStmtGenerator) generateUpdateStatements(allCols []string) []string
 	}
 	return updateStmts
 }
+
+// generateHistoryStmts generates statements that insert every change as a new version of the row,
+// then close the version that was current before this batch. Versions are valid from the commit time
+// of the transaction that made the change, deletes only close the current version.
+// Unchanged TOAST columns take the value of the last change in the batch that had them,
+// or of the version that was current before this batch.
+func (m *mergeStmtGenerator) generateHistoryStmts() ([]string, error) {
+	parsedDstTable, _ := utils.ParseSchemaTable(m.dstTableName)
+	dstTable := snowflakeSchemaTableNormalize(parsedDstTable)
+	columnNames := utils.TableSchemaColumnNames(m.normalizedTableSchema)
+
+	flattenedCastsSQL, err := m.generateFlattenedCasts()
+	if err != nil {
+		return nil, err
+	}
+
+	pkeyCount := len(m.normalizedTableSchema.PrimaryKeyColumns)
+	normalizedPkeyColsArray := make([]string, 0, pkeyCount)
+	for _, pkeyColName := range m.normalizedTableSchema.PrimaryKeyColumns {
+		normalizedPkeyColsArray = append(normalizedPkeyColsArray, SnowflakeIdentifierNormalize(pkeyColName))
+	}
+	normalizedPkeyCols := strings.Join(normalizedPkeyColsArray, ",")
+	versionOrderSQL := "ORDER BY _PEERDB_VERSION_TS,_PEERDB_LSN,_PEERDB_TIMESTAMP"
+
+	toastColumns := utils.UnchangedToastColumnNames(m.unchangedToastColumns)
+	versionedSQLArray := make([]string, 0, len(toastColumns)+1)
+	versionedSQLArray = append(versionedSQLArray, fmt.Sprintf(
+		"LEAD(_PEERDB_VERSION_TS) OVER (PARTITION BY %s %s) AS _PEERDB_NEXT_TIMESTAMP", normalizedPkeyCols, versionOrderSQL))
+	carriedSQLArray := make([]string, 0, len(toastColumns)+1)
+	carriedSQLArray = append(carriedSQLArray, "*")
+	insertColumnsSQLArray := make([]string, 0, len(columnNames)+4)
+	selectValuesSQLArray := make([]string, 0, len(columnNames)+4)
+	for _, columnName := range columnNames {
+		normalizedColName := SnowflakeIdentifierNormalize(columnName)
+		insertColumnsSQLArray = append(insertColumnsSQLArray, normalizedColName)
+		if _, ok := toastColumns[columnName]; !ok {
+			selectValuesSQLArray = append(selectValuesSQLArray, "VER."+normalizedColName)
+			continue
+		}
+		// unchanged toast columns take the value of the last change in this batch that had them,
+		// or of the version that was current before this batch if there was none
+		toastIdx := len(versionedSQLArray) - 1
+		versionedSQLArray = append(versionedSQLArray, fmt.Sprintf(
+			"COUNT(CASE WHEN ARRAY_CONTAINS('%s'::VARIANT, SPLIT(_PEERDB_UNCHANGED_TOAST_COLUMNS, ',')) THEN NULL ELSE 1 END) "+
+				"OVER (PARTITION BY %s %s ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS _PEERDB_TOAST_GROUP_%d",
+			columnName, normalizedPkeyCols, versionOrderSQL, toastIdx))
+		carriedSQLArray = append(carriedSQLArray, fmt.Sprintf(
+			"FIRST_VALUE(%s) OVER (PARTITION BY %s,_PEERDB_TOAST_GROUP_%d %s) AS _PEERDB_TOAST_VALUE_%d",
+			normalizedColName, normalizedPkeyCols, toastIdx, versionOrderSQL, toastIdx))
+		selectValuesSQLArray = append(selectValuesSQLArray, fmt.Sprintf(
+			"CASE WHEN VER._PEERDB_TOAST_GROUP_%d = 0 THEN CUR.%s ELSE VER._PEERDB_TOAST_VALUE_%d END",
+			toastIdx, normalizedColName, toastIdx))
+	}
+	insertColumnsSQLArray = append(insertColumnsSQLArray, fmt.Sprintf(`"%s"`, shared.HistoryValidFromColName),
+		fmt.Sprintf(`"%s"`, shared.HistoryValidToColName), fmt.Sprintf(`"%s"`, shared.HistoryIsCurrentColName))
+	selectValuesSQLArray = append(selectValuesSQLArray,
+		"TO_TIMESTAMP_NTZ(VER._PEERDB_VERSION_TS, 9)",
+		"TO_TIMESTAMP_NTZ(VER._PEERDB_NEXT_TIMESTAMP, 9)",
+		"VER._PEERDB_NEXT_TIMESTAMP IS NULL")
+	if m.peerdbCols.SyncedAtColName != "" {
+		insertColumnsSQLArray = append(insertColumnsSQLArray,
+			fmt.Sprintf(`"%s"`, strings.ToUpper(m.peerdbCols.SyncedAtColName)))
+		selectValuesSQLArray = append(selectValuesSQLArray, "CURRENT_TIMESTAMP")
+	}
+	if m.peerdbCols.TransactionMetadata {
+		txnCols, txnValues := transactionMetadataColumnsSQL("VER")
+		insertColumnsSQLArray = append(insertColumnsSQLArray, txnCols...)
+		selectValuesSQLArray = append(selectValuesSQLArray, txnValues...)
+	}
+
+	curJoinSQLArray := make([]string, 0, pkeyCount)
+	verExistsSQLArray := make([]string, 0, pkeyCount)
+	firstVerJoinSQLArray := make([]string, 0, pkeyCount)
+	for _, pkeyColName := range m.normalizedTableSchema.PrimaryKeyColumns {
+		normalizedPkeyColName := SnowflakeIdentifierNormalize(pkeyColName)
+		curJoinSQLArray = append(curJoinSQLArray, fmt.Sprintf("CUR.%s = VER.%s",
+			normalizedPkeyColName, normalizedPkeyColName))
+		verExistsSQLArray = append(verExistsSQLArray, fmt.Sprintf("DST.%s = VER.%s",
+			normalizedPkeyColName, normalizedPkeyColName))
+		firstVerJoinSQLArray = append(firstVerJoinSQLArray, fmt.Sprintf("DST.%s = FIRST_VER.%s",
+			normalizedPkeyColName, normalizedPkeyColName))
+	}
+
+	insertStatement := fmt.Sprintf(historyInsertStatementSQL, dstTable, strings.Join(insertColumnsSQLArray, ","),
+		toVariantColumnName, m.rawTableName, m.normalizeBatchID, m.syncBatchID, flattenedCastsSQL,
+		strings.Join(versionedSQLArray, ","), strings.Join(carriedSQLArray, ","), strings.Join(selectValuesSQLArray, ","),
+		dstTable, strings.Join(curJoinSQLArray, " AND "), shared.HistoryIsCurrentColName,
+		dstTable, strings.Join(verExistsSQLArray, " AND "), shared.HistoryValidFromColName)
+
+	closeStatement := fmt.Sprintf(historyCloseStatementSQL, dstTable,
+		shared.HistoryValidToColName, shared.HistoryIsCurrentColName,
+		normalizedPkeyCols, flattenedCastsSQL, toVariantColumnName,
+		m.rawTableName, m.normalizeBatchID, m.syncBatchID, normalizedPkeyCols,
+		strings.Join(firstVerJoinSQLArray, " AND "), shared.HistoryIsCurrentColName,
+		shared.HistoryValidFromColName, shared.HistoryValidFromColName)
+
+	// new versions are inserted first, so that unchanged toast columns can be read from the current version
+	return []string{insertStatement, closeStatement}, nil
+}
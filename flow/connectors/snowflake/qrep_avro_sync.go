@@ -158,7 +158,7 @@ func (s *SnowflakeAvroSyncMethod) addMissingColumns(
 	partitionLog := slog.String(string(shared.PartitionIDKey), partition.PartitionId)
 	// check if avro schema has additional columns compared to destination table
 	// if so, we need to add those columns to the destination table
-	colsToTypes := map[string]qvalue.QValueKind{}
+	colsToTypes := map[string]model.QField{}
 	for _, col := range schema.Fields {
 		hasColumn := false
 		// check ignoring case
@@ -172,7 +172,7 @@ func (s *SnowflakeAvroSyncMethod) addMissingColumns(
 		if !hasColumn {
 			s.connector.logger.Info(fmt.Sprintf("adding column %s to destination table %s",
 				col.Name, dstTableName), partitionLog)
-			colsToTypes[col.Name] = col.Type
+			colsToTypes[col.Name] = col
 		}
 	}
 
@@ -182,8 +182,8 @@ func (s *SnowflakeAvroSyncMethod) addMissingColumns(
 			return fmt.Errorf("failed to begin transaction: %w", err)
 		}
 
-		for colName, colType := range colsToTypes {
-			sfColType, err := colType.ToDWHColumnType(qvalue.QDWHTypeSnowflake)
+		for colName, col := range colsToTypes {
+			sfColType, err := qValueKindToSnowflakeType(col.Type, col.Typmod)
 			if err != nil {
 				return fmt.Errorf("failed to convert QValueKind to Snowflake column type: %w", err)
 			}
@@ -216,7 +216,7 @@ func (s *SnowflakeAvroSyncMethod) getAvroSchema(
 	dstTableName string,
 	schema *model.QRecordSchema,
 ) (*model.QRecordAvroSchemaDefinition, error) {
-	avroSchema, err := model.GetAvroSchemaDefinition(dstTableName, schema)
+	avroSchema, err := model.GetAvroSchemaDefinition(dstTableName, schema, qvalue.QDWHTypeSnowflake)
 	if err != nil {
 		return nil, fmt.Errorf("failed to define Avro schema: %w", err)
 	}
@@ -34,7 +34,16 @@ var snowflakeTypeToQValueKindMap = map[string]qvalue.QValueKind{
 	"GEOGRAPHY":     qvalue.QValueKindGeography,
 }
 
-func qValueKindToSnowflakeType(colType qvalue.QValueKind) (string, error) {
+func qValueKindToSnowflakeType(colType qvalue.QValueKind, typmod int32) (string, error) {
+	if colType == qvalue.QValueKindNumeric {
+		// NUMBER would round values beyond its 38 digits of precision
+		if qvalue.NumericStoredAsString(typmod, qvalue.QDWHTypeSnowflake) {
+			return "VARCHAR", nil
+		}
+		precision, scale := qvalue.DetermineNumericSettingForDWH(typmod, qvalue.QDWHTypeSnowflake)
+		return fmt.Sprintf("NUMBER(%d, %d)", precision, scale), nil
+	}
+
 	val, err := colType.ToDWHColumnType(qvalue.QDWHTypeSnowflake)
 	if err != nil {
 		return "", err
@@ -0,0 +1,31 @@
+package connsnowflake
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+)
+
+func TestNumericToSnowflakeType(t *testing.T) {
+	tests := []struct {
+		typmod   int32
+		expected string
+	}{
+		// unconstrained
+		{-1, "VARCHAR"},
+		// NUMERIC(20, 5)
+		{(20<<16 | 5) + 4, "NUMBER(20, 5)"},
+		// NUMERIC(50, 10)
+		{(50<<16 | 10) + 4, "VARCHAR"},
+	}
+
+	for _, tt := range tests {
+		actual, err := qValueKindToSnowflakeType(qvalue.QValueKindNumeric, tt.typmod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != tt.expected {
+			t.Errorf("qValueKindToSnowflakeType(numeric, %d) = %s, expected %s", tt.typmod, actual, tt.expected)
+		}
+	}
+}
@@ -33,17 +33,19 @@ const (
 	createRawTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s(_PEERDB_UID STRING NOT NULL,
 		_PEERDB_TIMESTAMP INT NOT NULL,_PEERDB_DESTINATION_TABLE_NAME STRING NOT NULL,_PEERDB_DATA STRING NOT NULL,
 		_PEERDB_RECORD_TYPE INTEGER NOT NULL, _PEERDB_MATCH_DATA STRING,_PEERDB_BATCH_ID INT,
-		_PEERDB_UNCHANGED_TOAST_COLUMNS STRING)`
+		_PEERDB_UNCHANGED_TOAST_COLUMNS STRING,_PEERDB_COMMIT_TS INT,_PEERDB_LSN INT,_PEERDB_XID INT)`
+	// raw tables created before transaction metadata was recorded lack its columns
+	migrateRawTableSQL          = "ALTER TABLE %s.%s ADD COLUMN IF NOT EXISTS %s INT"
 	rawTableMultiValueInsertSQL = "INSERT INTO %s.%s VALUES%s"
 	createNormalizedTableSQL    = "CREATE TABLE IF NOT EXISTS %s(%s)"
 	toVariantColumnName         = "VAR_COLS"
 	mergeStatementSQL           = `MERGE INTO %s TARGET USING (WITH VARIANT_CONVERTED AS (
 		SELECT _PEERDB_UID,_PEERDB_TIMESTAMP,TO_VARIANT(PARSE_JSON(_PEERDB_DATA)) %s,_PEERDB_RECORD_TYPE,
-		 _PEERDB_MATCH_DATA,_PEERDB_BATCH_ID,_PEERDB_UNCHANGED_TOAST_COLUMNS
+		 _PEERDB_MATCH_DATA,_PEERDB_BATCH_ID,_PEERDB_UNCHANGED_TOAST_COLUMNS,_PEERDB_COMMIT_TS,_PEERDB_LSN,_PEERDB_XID
 		FROM _PEERDB_INTERNAL.%s WHERE _PEERDB_BATCH_ID > %d AND _PEERDB_BATCH_ID <= %d AND
 		 _PEERDB_DESTINATION_TABLE_NAME = ? ), FLATTENED AS
 		 (SELECT _PEERDB_UID,_PEERDB_TIMESTAMP,_PEERDB_RECORD_TYPE,_PEERDB_MATCH_DATA,_PEERDB_BATCH_ID,
-			_PEERDB_UNCHANGED_TOAST_COLUMNS,%s
+			_PEERDB_UNCHANGED_TOAST_COLUMNS,_PEERDB_COMMIT_TS,_PEERDB_LSN,_PEERDB_XID,%s
 		 FROM VARIANT_CONVERTED), DEDUPLICATED_FLATTENED AS (SELECT _PEERDB_RANKED.* FROM
 		 (SELECT RANK() OVER
 		 (PARTITION BY %s ORDER BY _PEERDB_TIMESTAMP DESC) AS _PEERDB_RANK, * FROM FLATTENED)
@@ -52,6 +54,29 @@ const (
 		 WHEN NOT MATCHED AND (SOURCE._PEERDB_RECORD_TYPE != 2) THEN INSERT (%s) VALUES(%s)
 		 %s
 		 WHEN MATCHED AND (SOURCE._PEERDB_RECORD_TYPE = 2) THEN %s`
+	// versions are valid from the commit time of their transaction, raw rows synced before commit times were
+	// recorded fall back to the time they were read. Only the last change to a row within a transaction is kept.
+	historyInsertStatementSQL = `INSERT INTO %s (%s) WITH VARIANT_CONVERTED AS (
+		SELECT _PEERDB_TIMESTAMP,TO_VARIANT(PARSE_JSON(_PEERDB_DATA)) %s,_PEERDB_RECORD_TYPE,_PEERDB_UNCHANGED_TOAST_COLUMNS,
+		 _PEERDB_COMMIT_TS,_PEERDB_LSN,_PEERDB_XID FROM _PEERDB_INTERNAL.%s WHERE _PEERDB_BATCH_ID > %d AND _PEERDB_BATCH_ID <= %d AND
+		 _PEERDB_DESTINATION_TABLE_NAME = ? ), FLATTENED AS
+		 (SELECT _PEERDB_TIMESTAMP,COALESCE(_PEERDB_COMMIT_TS,_PEERDB_TIMESTAMP) AS _PEERDB_VERSION_TS,
+		 _PEERDB_RECORD_TYPE,_PEERDB_UNCHANGED_TOAST_COLUMNS,
+		 _PEERDB_COMMIT_TS,_PEERDB_LSN,_PEERDB_XID,%s FROM VARIANT_CONVERTED), VERSIONED AS (SELECT *,%s FROM FLATTENED),
+		 CARRIED AS (SELECT %s FROM VERSIONED)
+		 SELECT %s FROM CARRIED VER LEFT JOIN %s CUR ON %s AND CUR."%s"
+		 WHERE VER._PEERDB_RECORD_TYPE != 2 AND VER._PEERDB_NEXT_TIMESTAMP IS DISTINCT FROM VER._PEERDB_VERSION_TS
+		 AND NOT EXISTS (SELECT 1 FROM %s DST
+		 WHERE %s AND DST."%s" = TO_TIMESTAMP_NTZ(VER._PEERDB_VERSION_TS, 9))`
+	historyCloseStatementSQL = `UPDATE %s DST
+		SET "%s" = TO_TIMESTAMP_NTZ(FIRST_VER._PEERDB_FIRST_TIMESTAMP, 9), "%s" = FALSE
+		FROM (SELECT %s,MIN(_PEERDB_VERSION_TS) AS _PEERDB_FIRST_TIMESTAMP FROM
+		 (SELECT _PEERDB_VERSION_TS,%s FROM (SELECT COALESCE(_PEERDB_COMMIT_TS,_PEERDB_TIMESTAMP) AS _PEERDB_VERSION_TS,
+		 TO_VARIANT(PARSE_JSON(_PEERDB_DATA)) %s
+		 FROM _PEERDB_INTERNAL.%s WHERE _PEERDB_BATCH_ID > %d AND _PEERDB_BATCH_ID <= %d AND
+		 _PEERDB_DESTINATION_TABLE_NAME = ?)) GROUP BY %s) FIRST_VER
+		WHERE %s AND DST."%s"
+		 AND (DST."%s" IS NULL OR DST."%s" < TO_TIMESTAMP_NTZ(FIRST_VER._PEERDB_FIRST_TIMESTAMP, 9))`
 	getDistinctDestinationTableNames = `SELECT DISTINCT _PEERDB_DESTINATION_TABLE_NAME FROM %s.%s WHERE
 	 _PEERDB_BATCH_ID > %d AND _PEERDB_BATCH_ID <= %d`
 	getTableNametoUnchangedColsSQL = `SELECT _PEERDB_DESTINATION_TABLE_NAME,
@@ -435,7 +460,15 @@ func (c *SnowflakeConnector) SetupNormalizedTables(
 		}
 
 		normalizedTableCreateSQL := generateCreateTableSQLForNormalizedTable(
-			normalizedSchemaTable, tableSchema, req.SoftDeleteColName, req.SyncedAtColName)
+			normalizedSchemaTable, tableSchema, req.SoftDeleteColName, req.SyncedAtColName, req.BeforeImageColName,
+			req.HistoryMode, req.TransactionMetadata)
+		if clusterColumns := req.TableLayoutMapping[tableIdentifier].GetClusterColumns(); len(clusterColumns) > 0 {
+			normalizedClusterColumns := make([]string, 0, len(clusterColumns))
+			for _, clusterColumn := range clusterColumns {
+				normalizedClusterColumns = append(normalizedClusterColumns, SnowflakeIdentifierNormalize(clusterColumn))
+			}
+			normalizedTableCreateSQL += fmt.Sprintf(" CLUSTER BY(%s)", strings.Join(normalizedClusterColumns, ","))
+		}
 		_, err = c.database.ExecContext(c.ctx, normalizedTableCreateSQL)
 		if err != nil {
 			return nil, fmt.Errorf("[sf] error while creating normalized table: %w", err)
@@ -476,7 +509,8 @@ func (c *SnowflakeConnector) ReplayTableSchemaDeltas(flowJobName string,
 		}
 
 		for _, addedColumn := range schemaDelta.AddedColumns {
-			sfColtype, err := qValueKindToSnowflakeType(qvalue.QValueKind(addedColumn.ColumnType))
+			sfColtype, err := qValueKindToSnowflakeType(qvalue.QValueKind(addedColumn.ColumnType),
+				addedColumn.ColumnTypmod)
 			if err != nil {
 				return fmt.Errorf("failed to convert column type %s to snowflake type: %w",
 					addedColumn.ColumnType, err)
@@ -595,6 +629,7 @@ func (c *SnowflakeConnector) NormalizeRecords(req *model.NormalizeRecordsRequest
 	if err != nil {
 		return nil, err
 	}
+	batchIDs = req.ReplayBatchIDs(batchIDs)
 	// normalize has caught up with sync, chill until more records are loaded.
 	if batchIDs.NormalizeBatchID >= batchIDs.SyncBatchID {
 		return &model.NormalizeResponse{
@@ -622,6 +657,7 @@ func (c *SnowflakeConnector) NormalizeRecords(req *model.NormalizeRecordsRequest
 	if err != nil {
 		return nil, err
 	}
+	destinationTableNames = req.ReplayTableNames(destinationTableNames)
 
 	tableNametoUnchangedToastCols, err := c.getTableNametoUnchangedCols(req.FlowJobName, batchIDs.SyncBatchID, batchIDs.NormalizeBatchID)
 	if err != nil {
@@ -644,11 +680,28 @@ func (c *SnowflakeConnector) NormalizeRecords(req *model.NormalizeRecordsRequest
 				normalizedTableSchema: c.tableSchemaMapping[tableName],
 				unchangedToastColumns: tableNametoUnchangedToastCols[tableName],
 				peerdbCols: &protos.PeerDBColumns{
-					SoftDelete:        req.SoftDelete,
-					SoftDeleteColName: req.SoftDeleteColName,
-					SyncedAtColName:   req.SyncedAtColName,
+					SoftDelete:          req.SoftDelete,
+					SoftDeleteColName:   req.SoftDeleteColName,
+					SyncedAtColName:     req.SyncedAtColName,
+					BeforeImageColName:  req.BeforeImageColName,
+					HistoryMode:         req.HistoryMode,
+					TransactionMetadata: req.TransactionMetadata,
 				},
 			}
+
+			if req.HistoryMode {
+				historyStatements, err := mergeGen.generateHistoryStmts()
+				if err != nil {
+					return err
+				}
+				rowsAffected, err := c.execHistoryStatements(gCtx, tableName, historyStatements)
+				if err != nil {
+					return err
+				}
+				atomic.AddInt64(&totalRowsAffected, rowsAffected)
+				return nil
+			}
+
 			mergeStatement, err := mergeGen.generateMergeStmt()
 			if err != nil {
 				return err
@@ -685,10 +738,12 @@ func (c *SnowflakeConnector) NormalizeRecords(req *model.NormalizeRecordsRequest
 		return nil, fmt.Errorf("error while normalizing records: %w", err)
 	}
 
-	// updating metadata with new normalizeBatchID
-	err = c.updateNormalizeMetadata(req.FlowJobName, batchIDs.SyncBatchID)
-	if err != nil {
-		return nil, err
+	// updating metadata with new normalizeBatchID, replays don't normalize new batches
+	if len(req.ReplayTables) == 0 {
+		err = c.updateNormalizeMetadata(req.FlowJobName, batchIDs.SyncBatchID)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &model.NormalizeResponse{
@@ -698,6 +753,52 @@ func (c *SnowflakeConnector) NormalizeRecords(req *model.NormalizeRecordsRequest
 	}, nil
 }
 
+// execHistoryStatements runs the statements normalizing a table in history mode in one transaction,
+// returning the number of versions inserted.
+func (c *SnowflakeConnector) execHistoryStatements(
+	ctx context.Context,
+	tableName string,
+	historyStatements []string,
+) (int64, error) {
+	startTime := time.Now()
+	c.logger.Info("[history] inserting versions...", slog.String("destTable", tableName))
+
+	historyTx, err := c.database.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("unable to begin transaction for history of %s: %w", tableName, err)
+	}
+	defer func() {
+		deferErr := historyTx.Rollback()
+		if deferErr != sql.ErrTxDone && deferErr != nil {
+			c.logger.Error("error while rolling back transaction for history", slog.Any("error", deferErr))
+		}
+	}()
+
+	var rowsInserted int64
+	for i, historyStatement := range historyStatements {
+		result, err := historyTx.ExecContext(ctx, historyStatement, tableName)
+		if err != nil {
+			return 0, fmt.Errorf("failed to normalize history of %s (statement: %s): %w",
+				tableName, historyStatement, err)
+		}
+		if i == 0 {
+			rowsInserted, err = result.RowsAffected()
+			if err != nil {
+				return 0, fmt.Errorf("failed to get rows affected by history statement for table %s: %w", tableName, err)
+			}
+		}
+	}
+
+	err = historyTx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("unable to commit transaction for history of %s: %w", tableName, err)
+	}
+
+	c.logger.Info(fmt.Sprintf("[history] inserted %d versions into %s, took: %d seconds",
+		rowsInserted, tableName, time.Since(startTime)/time.Second))
+	return rowsInserted, nil
+}
+
 func (c *SnowflakeConnector) CreateRawTable(req *protos.CreateRawTableInput) (*protos.CreateRawTableOutput, error) {
 	rawTableIdentifier := getRawTableIdentifier(req.FlowJobName)
 
@@ -716,6 +817,10 @@ func (c *SnowflakeConnector) CreateRawTable(req *protos.CreateRawTableInput) (*p
 	if err != nil {
 		return nil, fmt.Errorf("unable to create raw table: %w", err)
 	}
+	err = c.migrateRawTable(createRawTableTx, rawTableIdentifier)
+	if err != nil {
+		return nil, err
+	}
 	err = createRawTableTx.Commit()
 	if err != nil {
 		return nil, fmt.Errorf("unable to commit transaction for creation of raw table: %w", err)
@@ -732,6 +837,41 @@ func (c *SnowflakeConnector) CreateRawTable(req *protos.CreateRawTableInput) (*p
 	}, nil
 }
 
+// MigrateRawTable adds the columns raw tables created before transaction metadata was recorded are missing,
+// mirrors that are already running don't create their raw table again.
+func (c *SnowflakeConnector) MigrateRawTable(flowJobName string) error {
+	migrateRawTableTx, err := c.database.BeginTx(c.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction for migration of raw table: %w", err)
+	}
+	defer func() {
+		deferErr := migrateRawTableTx.Rollback()
+		if deferErr != sql.ErrTxDone && deferErr != nil {
+			c.logger.Error("error while rolling back transaction for migration of raw table", slog.Any("error", deferErr))
+		}
+	}()
+
+	err = c.migrateRawTable(migrateRawTableTx, getRawTableIdentifier(flowJobName))
+	if err != nil {
+		return err
+	}
+	err = migrateRawTableTx.Commit()
+	if err != nil {
+		return fmt.Errorf("unable to commit transaction for migration of raw table: %w", err)
+	}
+	return nil
+}
+
+func (c *SnowflakeConnector) migrateRawTable(tx *sql.Tx, rawTableIdentifier string) error {
+	for _, col := range []string{"_PEERDB_COMMIT_TS", "_PEERDB_LSN", "_PEERDB_XID"} {
+		_, err := tx.ExecContext(c.ctx, fmt.Sprintf(migrateRawTableSQL, c.metadataSchema, rawTableIdentifier, col))
+		if err != nil {
+			return fmt.Errorf("unable to add column %s to raw table: %w", col, err)
+		}
+	}
+	return nil
+}
+
 func (c *SnowflakeConnector) SyncFlowCleanup(jobName string) error {
 	syncFlowCleanupTx, err := c.database.BeginTx(c.ctx, nil)
 	if err != nil {
@@ -791,11 +931,15 @@ func generateCreateTableSQLForNormalizedTable(
 	sourceTableSchema *protos.TableSchema,
 	softDeleteColName string,
 	syncedAtColName string,
+	beforeImageColName string,
+	historyMode bool,
+	transactionMetadata bool,
 ) string {
-	createTableSQLArray := make([]string, 0, utils.TableSchemaColumns(sourceTableSchema)+2)
+	createTableSQLArray := make([]string, 0, utils.TableSchemaColumns(sourceTableSchema)+7)
 	utils.IterColumns(sourceTableSchema, func(columnName, genericColumnType string) {
 		normalizedColName := SnowflakeIdentifierNormalize(columnName)
-		sfColType, err := qValueKindToSnowflakeType(qvalue.QValueKind(genericColumnType))
+		sfColType, err := qValueKindToSnowflakeType(qvalue.QValueKind(genericColumnType),
+			utils.TableSchemaColumnTypmod(sourceTableSchema, columnName))
 		if err != nil {
 			slog.Warn(fmt.Sprintf("failed to convert column type %s to snowflake type", genericColumnType),
 				slog.Any("error", err))
@@ -819,8 +963,29 @@ func generateCreateTableSQLForNormalizedTable(
 			fmt.Sprintf(`%s TIMESTAMP DEFAULT CURRENT_TIMESTAMP,`, syncedAtColName))
 	}
 
+	// add a column holding the values of the row before the last update or delete
+	if beforeImageColName != "" {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`%s VARIANT,`, beforeImageColName))
+	}
+
+	// in history mode a row has many versions, so the primary key is not unique.
+	// rows from the initial load have no start of validity.
+	if historyMode {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`"%s" TIMESTAMP,"%s" TIMESTAMP,"%s" BOOLEAN DEFAULT TRUE,`,
+				shared.HistoryValidFromColName, shared.HistoryValidToColName, shared.HistoryIsCurrentColName))
+	}
+
+	// add columns with the commit time, LSN and transaction ID of the last change to the row
+	if transactionMetadata {
+		createTableSQLArray = append(createTableSQLArray,
+			fmt.Sprintf(`"%s" TIMESTAMP,"%s" INTEGER,"%s" INTEGER,`,
+				shared.CommitTimestampColName, shared.LSNColName, shared.TransactionIDColName))
+	}
+
 	// add composite primary key to the table
-	if len(sourceTableSchema.PrimaryKeyColumns) > 0 {
+	if len(sourceTableSchema.PrimaryKeyColumns) > 0 && !historyMode {
 		normalizedPrimaryKeyCols := make([]string, 0, len(sourceTableSchema.PrimaryKeyColumns))
 		for _, primaryKeyCol := range sourceTableSchema.PrimaryKeyColumns {
 			normalizedPrimaryKeyCols = append(normalizedPrimaryKeyCols,
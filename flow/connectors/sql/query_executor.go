@@ -156,6 +156,28 @@ func (g *GenericSQLQueryExecutor) columnTypeToQField(ct *sql.ColumnType) (model.
 }
 
 func (g *GenericSQLQueryExecutor) processRows(rows *sqlx.Rows) (*model.QRecordBatch, error) {
+	var records []model.QRecord
+	qfields, err := g.processRowsFunc(rows, func(record model.QRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return a QRecordBatch
+	return &model.QRecordBatch{
+		NumRecords: uint32(len(records)),
+		Records:    records,
+		Schema:     model.NewQRecordSchema(qfields),
+	}, nil
+}
+
+// processRowsFunc converts rows to records and passes them to processRecord one at a time.
+func (g *GenericSQLQueryExecutor) processRowsFunc(
+	rows *sqlx.Rows,
+	processRecord func(model.QRecord) error,
+) ([]model.QField, error) {
 	dbColTypes, err := rows.ColumnTypes()
 	if err != nil {
 		return nil, err
@@ -173,7 +195,6 @@ func (g *GenericSQLQueryExecutor) processRows(rows *sqlx.Rows) (*model.QRecordBa
 		qfields[i] = qfield
 	}
 
-	var records []model.QRecord
 	totalRowsProcessed := 0
 	const heartBeatNumRows = 25000
 
@@ -243,7 +264,9 @@ func (g *GenericSQLQueryExecutor) processRows(rows *sqlx.Rows) (*model.QRecordBa
 			record.Set(i, qv)
 		}
 
-		records = append(records, record)
+		if err := processRecord(record); err != nil {
+			return nil, err
+		}
 		totalRowsProcessed += 1
 
 		if totalRowsProcessed%heartBeatNumRows == 0 {
@@ -256,12 +279,7 @@ func (g *GenericSQLQueryExecutor) processRows(rows *sqlx.Rows) (*model.QRecordBa
 		return nil, err
 	}
 
-	// Return a QRecordBatch
-	return &model.QRecordBatch{
-		NumRecords: uint32(len(records)),
-		Records:    records,
-		Schema:     model.NewQRecordSchema(qfields),
-	}, nil
+	return qfields, nil
 }
 
 func (g *GenericSQLQueryExecutor) ExecuteAndProcessQuery(
@@ -276,6 +294,22 @@ func (g *GenericSQLQueryExecutor) ExecuteAndProcessQuery(
 	return g.processRows(rows)
 }
 
+// ExecuteAndProcessQueryFunc passes the rows of a query to processRecord one at a time,
+// instead of holding all of them in memory.
+func (g *GenericSQLQueryExecutor) ExecuteAndProcessQueryFunc(
+	processRecord func(model.QRecord) error,
+	query string, args ...interface{},
+) error {
+	rows, err := g.db.QueryxContext(g.ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	_, err = g.processRowsFunc(rows, processRecord)
+	return err
+}
+
 func (g *GenericSQLQueryExecutor) NamedExecuteAndProcessQuery(
 	query string, arg interface{},
 ) (*model.QRecordBatch, error) {
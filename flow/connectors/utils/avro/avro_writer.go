@@ -148,6 +148,7 @@ func (p *peerDBOCFWriter) writeRecordsToOCFWriter(ocfWriter *goavro.OCFWriter) (
 			qRecordOrErr.Record,
 			p.targetDWH,
 			p.avroSchema.NullableFields,
+			p.avroSchema.StringNumericFields,
 			colNames,
 		)
 
@@ -33,6 +33,10 @@ type cdcRecordsStore struct {
 	flowJobName               string
 	dbFolderName              string
 	numRecordsSwitchThreshold int
+
+	// changes of transactions streamed before their commit, by top-level transaction ID
+	streamedTxns               map[uint32]*streamedTxn
+	numInMemoryStreamedRecords int
 }
 
 func NewCDCRecordsStore(flowJobName string) *cdcRecordsStore {
@@ -43,6 +47,7 @@ func NewCDCRecordsStore(flowJobName string) *cdcRecordsStore {
 		flowJobName:               flowJobName,
 		dbFolderName:              fmt.Sprintf("%s/%s_%s", os.TempDir(), flowJobName, shared.RandomString(8)),
 		numRecordsSwitchThreshold: peerdbenv.PeerDBCDCDiskSpillThreshold(),
+		streamedTxns:              make(map[uint32]*streamedTxn),
 	}
 }
 
@@ -55,6 +60,7 @@ func (c *cdcRecordsStore) initPebbleDB() error {
 	gob.Register(&model.InsertRecord{})
 	gob.Register(&model.UpdateRecord{})
 	gob.Register(&model.DeleteRecord{})
+	gob.Register(&model.MessageRecord{})
 	gob.Register(time.Time{})
 	gob.Register(&big.Rat{})
 
@@ -157,6 +163,7 @@ func (c *cdcRecordsStore) Len() int {
 
 func (c *cdcRecordsStore) Close() error {
 	c.inMemoryRecords = nil
+	c.streamedTxns = nil
 	if c.pebbleDB != nil {
 		err := c.pebbleDB.Close()
 		if err != nil {
@@ -144,3 +144,77 @@ func TestTimeAndRatEncoding(t *testing.T) {
 
 	require.NoError(t, cdcRecordsStore.Close())
 }
+
+func TestStreamedTxnSpillAndAbort(t *testing.T) {
+	t.Parallel()
+	cdcRecordsStore := NewCDCRecordsStore("test_streamed_txn")
+	cdcRecordsStore.numRecordsSwitchThreshold = 2
+
+	const xid, subXid uint32 = 100, 101
+	added := make([]model.Record, 0, 5)
+	for i := 0; i < 5; i++ {
+		_, rec := genKeyAndRec(t)
+		rec.(*model.InsertRecord).CheckPointID = int64(i)
+		recXid := xid
+		if i == 1 {
+			recXid = subXid
+		} else {
+			added = append(added, rec)
+		}
+		require.NoError(t, cdcRecordsStore.AddStreamedRecord(xid, recXid, rec))
+	}
+	// records after the threshold should be spilled to DB
+	require.Equal(t, 2, cdcRecordsStore.numInMemoryStreamedRecords)
+	require.NotNil(t, cdcRecordsStore.pebbleDB)
+
+	// records of another transaction that aborts should not be committed
+	_, rec := genKeyAndRec(t)
+	require.NoError(t, cdcRecordsStore.AddStreamedRecord(200, 200, rec))
+	require.NoError(t, cdcRecordsStore.AbortStreamedTxn(200, 200))
+	require.NoError(t, cdcRecordsStore.AbortStreamedTxn(xid, subXid))
+
+	committed := make([]model.Record, 0, 5)
+	err := cdcRecordsStore.CommitStreamedTxn(xid, func(rec model.Record) error {
+		committed = append(committed, rec)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, added, committed)
+	require.Equal(t, 0, cdcRecordsStore.NumStreamedTxns())
+	require.Equal(t, 0, cdcRecordsStore.numInMemoryStreamedRecords)
+	require.True(t, cdcRecordsStore.IsEmpty())
+
+	require.NoError(t, cdcRecordsStore.Close())
+}
+
+func TestMessageRecordEncoding(t *testing.T) {
+	t.Parallel()
+
+	cdcRecordsStore := NewCDCRecordsStore("test_message_record_encoding")
+	cdcRecordsStore.numRecordsSwitchThreshold = 0
+
+	key, _ := genKeyAndRec(t)
+	rec := &model.MessageRecord{
+		DestinationTableName: "test_outbox",
+		CheckPointID:         42,
+		TransactionID:        7,
+		CommitTime:           getTimeForTesting(t),
+		Prefix:               "outbox",
+		// payloads need not be valid UTF-8
+		Content:       []byte{0xff, 0xfe, 0x00, 0x01},
+		Transactional: true,
+	}
+	err := cdcRecordsStore.Set(key, rec)
+	require.NoError(t, err)
+
+	retreived, ok, err := cdcRecordsStore.Get(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, rec, retreived)
+
+	itemsJSON, err := retreived.GetItems().ToJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"prefix":"outbox","content":"//4AAQ==","transactional":true,"lsn":42}`, itemsJSON)
+
+	require.NoError(t, cdcRecordsStore.Close())
+}
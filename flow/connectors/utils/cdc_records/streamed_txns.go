@@ -0,0 +1,181 @@
+package cdc_records
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/cockroachdb/pebble"
+)
+
+// keys of streamed records are prefixed with a byte that never starts a gob encoded record key
+const streamedKeyPrefix byte = 0x80
+
+type streamedRecord struct {
+	// ID of the (sub)transaction that made the change
+	SubXid uint32
+	Record model.Record
+}
+
+type streamedTxn struct {
+	inMemoryRecords []streamedRecord
+	// once a transaction spills to disk, its later changes are spilled too, to keep them in order
+	spilled        bool
+	nextSeq        uint64
+	abortedSubXids map[uint32]struct{}
+}
+
+func streamedRecordKey(xid uint32, seq uint64) []byte {
+	key := make([]byte, 13)
+	key[0] = streamedKeyPrefix
+	binary.BigEndian.PutUint32(key[1:5], xid)
+	binary.BigEndian.PutUint64(key[5:], seq)
+	return key
+}
+
+// bounds of the keys of all streamed records of a transaction
+func streamedTxnKeyRange(xid uint32) ([]byte, []byte) {
+	upper := make([]byte, 5, 14)
+	upper[0] = streamedKeyPrefix
+	binary.BigEndian.PutUint32(upper[1:5], xid)
+	upper = append(upper, bytes.Repeat([]byte{0xff}, 9)...)
+	return streamedRecordKey(xid, 0), upper
+}
+
+// AddStreamedRecord buffers a change of a transaction that is streamed before its commit.
+// subXid is the ID of the subtransaction that made the change, or xid itself.
+func (c *cdcRecordsStore) AddStreamedRecord(xid uint32, subXid uint32, rec model.Record) error {
+	txn, ok := c.streamedTxns[xid]
+	if !ok {
+		txn = &streamedTxn{abortedSubXids: make(map[uint32]struct{})}
+		c.streamedTxns[xid] = txn
+	}
+
+	if !txn.spilled && c.numInMemoryStreamedRecords < c.numRecordsSwitchThreshold {
+		txn.inMemoryRecords = append(txn.inMemoryRecords, streamedRecord{SubXid: subXid, Record: rec})
+		c.numInMemoryStreamedRecords++
+		return nil
+	}
+
+	if c.pebbleDB == nil {
+		slog.Info(fmt.Sprintf("more than %d streamed records read, spilling to disk",
+			c.numRecordsSwitchThreshold),
+			slog.String(string(shared.FlowNameKey), c.flowJobName))
+		err := c.initPebbleDB()
+		if err != nil {
+			return err
+		}
+	}
+	txn.spilled = true
+
+	encodedRec, err := encVal(&streamedRecord{SubXid: subXid, Record: rec})
+	if err != nil {
+		return err
+	}
+	err = c.pebbleDB.Set(streamedRecordKey(xid, txn.nextSeq), encodedRec, &pebble.WriteOptions{
+		Sync: false,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store streamed record in Pebble: %w", err)
+	}
+	txn.nextSeq++
+	return nil
+}
+
+// CommitStreamedTxn passes the buffered changes of a committed streamed transaction to processRecord
+// in the order they were made, skipping changes of aborted subtransactions, then drops them.
+func (c *cdcRecordsStore) CommitStreamedTxn(xid uint32, processRecord func(model.Record) error) error {
+	txn, ok := c.streamedTxns[xid]
+	if !ok {
+		return nil
+	}
+
+	for _, streamedRec := range txn.inMemoryRecords {
+		if _, aborted := txn.abortedSubXids[streamedRec.SubXid]; aborted {
+			continue
+		}
+		err := processRecord(streamedRec.Record)
+		if err != nil {
+			return err
+		}
+	}
+	if txn.spilled {
+		err := c.processSpilledStreamedRecords(xid, txn, processRecord)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.dropStreamedTxn(xid)
+}
+
+func (c *cdcRecordsStore) processSpilledStreamedRecords(
+	xid uint32,
+	txn *streamedTxn,
+	processRecord func(model.Record) error,
+) error {
+	lower, upper := streamedTxnKeyRange(xid)
+	iter, err := c.pebbleDB.NewIter(&pebble.IterOptions{LowerBound: lower, UpperBound: upper})
+	if err != nil {
+		return fmt.Errorf("failed to iterate over streamed records: %w", err)
+	}
+	defer iter.Close()
+
+	for iter.First(); iter.Valid(); iter.Next() {
+		var streamedRec streamedRecord
+		err := gob.NewDecoder(bytes.NewReader(iter.Value())).Decode(&streamedRec)
+		if err != nil {
+			return fmt.Errorf("failed to decode streamed record: %w", err)
+		}
+		if _, aborted := txn.abortedSubXids[streamedRec.SubXid]; aborted {
+			continue
+		}
+		err = processRecord(streamedRec.Record)
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// AbortStreamedTxn drops the buffered changes of an aborted streamed transaction,
+// or only those of a subtransaction if subXid is not the top-level transaction.
+func (c *cdcRecordsStore) AbortStreamedTxn(xid uint32, subXid uint32) error {
+	txn, ok := c.streamedTxns[xid]
+	if !ok {
+		return nil
+	}
+	if subXid != xid {
+		txn.abortedSubXids[subXid] = struct{}{}
+		return nil
+	}
+	return c.dropStreamedTxn(xid)
+}
+
+func (c *cdcRecordsStore) dropStreamedTxn(xid uint32) error {
+	txn, ok := c.streamedTxns[xid]
+	if !ok {
+		return nil
+	}
+	delete(c.streamedTxns, xid)
+	c.numInMemoryStreamedRecords -= len(txn.inMemoryRecords)
+
+	if !txn.spilled {
+		return nil
+	}
+	lower, upper := streamedTxnKeyRange(xid)
+	err := c.pebbleDB.DeleteRange(lower, upper, &pebble.WriteOptions{Sync: false})
+	if err != nil {
+		return fmt.Errorf("failed to delete streamed records: %w", err)
+	}
+	return nil
+}
+
+// NumStreamedTxns returns the number of streamed transactions that have not committed or aborted yet.
+func (c *cdcRecordsStore) NumStreamedTxns() int {
+	return len(c.streamedTxns)
+}
@@ -2,11 +2,62 @@ package utils
 
 import (
 	"slices"
+	"strings"
 
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
 	"golang.org/x/exp/maps"
 )
 
+// AddSourceColumn returns the schema of a table with the column the source of rows is stored in,
+// as part of its primary key, for tables mirrored from several sources.
+// Tables without a primary key are keyed by all their columns and keep an empty primary key.
+func AddSourceColumn(schema *protos.TableSchema, sourceColName string) *protos.TableSchema {
+	columnCount := TableSchemaColumns(schema) + 1
+	columnNames := make([]string, 0, columnCount)
+	columnTypes := make([]string, 0, columnCount)
+	columnTypmods := make([]int32, 0, columnCount)
+	IterColumns(schema, func(columnName, columnType string) {
+		if columnName != sourceColName {
+			columnNames = append(columnNames, columnName)
+			columnTypes = append(columnTypes, columnType)
+			columnTypmods = append(columnTypmods, TableSchemaColumnTypmod(schema, columnName))
+		}
+	})
+	columnNames = append(columnNames, sourceColName)
+	columnTypes = append(columnTypes, string(qvalue.QValueKindString))
+	columnTypmods = append(columnTypmods, -1)
+
+	primaryKeyColumns := slices.Clone(schema.PrimaryKeyColumns)
+	if len(primaryKeyColumns) > 0 && !slices.Contains(primaryKeyColumns, sourceColName) {
+		primaryKeyColumns = append(primaryKeyColumns, sourceColName)
+	}
+
+	return &protos.TableSchema{
+		TableIdentifier:       schema.TableIdentifier,
+		PrimaryKeyColumns:     primaryKeyColumns,
+		IsReplicaIdentityFull: schema.IsReplicaIdentityFull,
+		ColumnNames:           columnNames,
+		ColumnTypes:           columnTypes,
+		ColumnTypmods:         columnTypmods,
+		PartitionKey:          schema.PartitionKey,
+	}
+}
+
+// UnchangedToastColumnNames returns the columns that are unchanged TOAST columns of any record of a batch,
+// from the distinct comma separated lists of unchanged TOAST columns of its records.
+func UnchangedToastColumnNames(unchangedToastColumns []string) map[string]struct{} {
+	columnNames := make(map[string]struct{})
+	for _, cols := range unchangedToastColumns {
+		for _, col := range strings.Split(cols, ",") {
+			if col != "" {
+				columnNames[col] = struct{}{}
+			}
+		}
+	}
+	return columnNames
+}
+
 func TableSchemaColumns(schema *protos.TableSchema) int {
 	if schema.Columns != nil {
 		return len(schema.Columns)
@@ -53,3 +104,12 @@ func IterColumnsError(schema *protos.TableSchema, iter func(k, v string) error)
 	}
 	return nil
 }
+
+// TableSchemaColumnTypmod returns the typmod of a column, or -1 if it isn't known.
+func TableSchemaColumnTypmod(schema *protos.TableSchema, columnName string) int32 {
+	idx := slices.Index(schema.ColumnNames, columnName)
+	if idx == -1 || idx >= len(schema.ColumnTypmods) {
+		return -1
+	}
+	return schema.ColumnTypmods[idx]
+}
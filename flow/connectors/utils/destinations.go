@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"google.golang.org/protobuf/proto"
+)
+
+// DestinationConfigs returns the config of a CDC mirror for each destination it syncs to,
+// the destination of the mirror first and then its fan-out destinations.
+func DestinationConfigs(config *protos.FlowConnectionConfigs) []*protos.FlowConnectionConfigs {
+	if len(config.FanOutDestinations) == 0 {
+		return []*protos.FlowConnectionConfigs{config}
+	}
+
+	configs := make([]*protos.FlowConnectionConfigs, 0, len(config.FanOutDestinations)+1)
+	configs = append(configs, config)
+	for _, destination := range config.FanOutDestinations {
+		fanOutConfig := proto.Clone(config).(*protos.FlowConnectionConfigs)
+		fanOutConfig.Destination = destination
+		fanOutConfig.FanOutDestinations = nil
+		configs = append(configs, fanOutConfig)
+	}
+	return configs
+}
@@ -10,6 +10,10 @@ func QuoteIdentifier(identifier string) string {
 	return fmt.Sprintf(`"%s"`, identifier)
 }
 
+func QuoteLiteral(literal string) string {
+	return "'" + strings.ReplaceAll(literal, "'", "''") + "'"
+}
+
 // SchemaTable is a table in a schema.
 type SchemaTable struct {
 	Schema string
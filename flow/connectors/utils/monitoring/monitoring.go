@@ -164,13 +164,25 @@ func InitializeQRepRun(
 		return fmt.Errorf("unable to update flow config in catalog: %w", err)
 	}
 
+	// partitions are added together, so that a run resuming from them never sees only some of them
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction for adding partitions: %w", err)
+	}
+	defer func() {
+		deferErr := tx.Rollback(ctx)
+		if deferErr != pgx.ErrTxClosed && deferErr != nil {
+			slog.Error("error rolling back transaction for adding partitions", slog.Any("error", deferErr))
+		}
+	}()
+
 	for _, partition := range partitions {
-		if err := addPartitionToQRepRun(ctx, pool, flowJobName, runUUID, partition); err != nil {
+		if err := addPartitionToQRepRun(ctx, tx, flowJobName, runUUID, partition); err != nil {
 			return fmt.Errorf("unable to add partition to qrep run: %w", err)
 		}
 	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
 func UpdateStartTimeForQRepRun(ctx context.Context, pool *pgxpool.Pool, runUUID string) error {
@@ -220,7 +232,7 @@ func AppendSlotSizeInfo(
 	return nil
 }
 
-func addPartitionToQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+func addPartitionToQRepRun(ctx context.Context, tx pgx.Tx, flowJobName string,
 	runUUID string, partition *protos.QRepPartition,
 ) error {
 	if partition.Range == nil && partition.FullTablePartition {
@@ -262,12 +274,17 @@ func addPartitionToQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName
 		return fmt.Errorf("unknown range type: %v", x)
 	}
 
-	_, err := pool.Exec(ctx,
+	partitionBytes, err := proto.Marshal(partition)
+	if err != nil {
+		return fmt.Errorf("unable to marshal partition: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
 		`INSERT INTO peerdb_stats.qrep_partitions
-		(flow_name,run_uuid,partition_uuid,partition_start,partition_end,restart_count)
-		 VALUES($1,$2,$3,$4,$5,$6) ON CONFLICT(run_uuid,partition_uuid) DO UPDATE SET
+		(flow_name,run_uuid,partition_uuid,partition_start,partition_end,restart_count,partition_proto)
+		 VALUES($1,$2,$3,$4,$5,$6,$7) ON CONFLICT(run_uuid,partition_uuid) DO UPDATE SET
 		 restart_count=qrep_partitions.restart_count+1`,
-		flowJobName, runUUID, partition.PartitionId, rangeStart, rangeEnd, 0)
+		flowJobName, runUUID, partition.PartitionId, rangeStart, rangeEnd, 0, partitionBytes)
 	if err != nil {
 		return fmt.Errorf("error while inserting qrep partition in qrep_partitions: %w", err)
 	}
@@ -275,6 +292,39 @@ func addPartitionToQRepRun(ctx context.Context, pool *pgxpool.Pool, flowJobName
 	return nil
 }
 
+// GetRecordedQRepPartitions returns the partitions recorded by earlier runs of a flow in their original order,
+// along with the IDs of the partitions that finished syncing.
+func GetRecordedQRepPartitions(ctx context.Context, pool *pgxpool.Pool, flowJobName string) (
+	[]*protos.QRepPartition, map[string]struct{}, error,
+) {
+	rows, err := pool.Query(ctx, `SELECT partition_proto,bool_or(end_time IS NOT NULL)
+		FROM peerdb_stats.qrep_partitions WHERE flow_name=$1 AND partition_proto IS NOT NULL
+		GROUP BY partition_uuid,partition_proto ORDER BY min(id)`, flowJobName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while querying qrep partitions of flow %s: %w", flowJobName, err)
+	}
+
+	var partitions []*protos.QRepPartition
+	finished := make(map[string]struct{})
+	var partitionBytes []byte
+	var done bool
+	_, err = pgx.ForEachRow(rows, []any{&partitionBytes, &done}, func() error {
+		partition := &protos.QRepPartition{}
+		if err := proto.Unmarshal(partitionBytes, partition); err != nil {
+			return fmt.Errorf("unable to unmarshal partition: %w", err)
+		}
+		partitions = append(partitions, partition)
+		if done {
+			finished[partition.PartitionId] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error while reading qrep partitions of flow %s: %w", flowJobName, err)
+	}
+	return partitions, finished, nil
+}
+
 func UpdateStartTimeForPartition(
 	ctx context.Context,
 	pool *pgxpool.Pool,
@@ -322,3 +372,169 @@ func UpdateRowsSyncedForPartition(ctx context.Context, pool *pgxpool.Pool, rowsS
 	}
 	return nil
 }
+
+// IncrementalSnapshotProgress is the progress of a table being snapshotted incrementally.
+type IncrementalSnapshotProgress struct {
+	TableName string
+	// primary key of the last row read, as text, nil before the first chunk
+	LastKey []string
+	// identifies the request, progress of an earlier request of the table is not recorded
+	RequestedAt time.Time
+}
+
+// RequestIncrementalSnapshot starts incremental snapshots of source tables of a flow,
+// tables that were snapshotted before or are being snapshotted are snapshotted again from the start.
+func RequestIncrementalSnapshot(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+	tableNames []string,
+) error {
+	batch := &pgx.Batch{}
+	for _, tableName := range tableNames {
+		batch.Queue(`INSERT INTO peerdb_stats.incremental_snapshots(flow_name,table_name) VALUES($1,$2)
+		 ON CONFLICT(flow_name,table_name) DO UPDATE SET last_key=NULL,pending_key=NULL,pending_lsn=NULL,
+		 pending_done=FALSE,done=FALSE,requested_at=now()`, flowJobName, tableName)
+	}
+	err := pool.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return fmt.Errorf("error while requesting incremental snapshots of flow %s: %w", flowJobName, err)
+	}
+	return nil
+}
+
+// GetIncrementalSnapshotProgress returns the tables of a flow with unfinished incremental snapshots.
+// Chunks pending since the last pull are recorded if they were synced up to syncedLSN, and discarded otherwise.
+func GetIncrementalSnapshotProgress(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+	syncedLSN int64,
+) ([]*IncrementalSnapshotProgress, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to begin transaction for incremental snapshot progress: %w", err)
+	}
+	defer func() {
+		deferErr := tx.Rollback(ctx)
+		if deferErr != pgx.ErrTxClosed && deferErr != nil {
+			slog.Error("error rolling back transaction for incremental snapshot progress", slog.Any("error", deferErr))
+		}
+	}()
+
+	_, err = tx.Exec(ctx, `UPDATE peerdb_stats.incremental_snapshots SET last_key=pending_key,done=pending_done
+	 WHERE flow_name=$1 AND pending_lsn<=$2`, flowJobName, syncedLSN)
+	if err != nil {
+		return nil, fmt.Errorf("error while recording synced incremental snapshot chunks: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE peerdb_stats.incremental_snapshots SET pending_key=NULL,pending_lsn=NULL,
+	 pending_done=FALSE WHERE flow_name=$1 AND pending_lsn IS NOT NULL`, flowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("error while clearing pending incremental snapshot chunks: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT table_name,last_key,requested_at FROM peerdb_stats.incremental_snapshots
+	 WHERE flow_name=$1 AND NOT done ORDER BY requested_at,table_name`, flowJobName)
+	if err != nil {
+		return nil, fmt.Errorf("error while querying incremental snapshots of flow %s: %w", flowJobName, err)
+	}
+	progress, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*IncrementalSnapshotProgress, error) {
+		p := &IncrementalSnapshotProgress{}
+		err := row.Scan(&p.TableName, &p.LastKey, &p.RequestedAt)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while reading incremental snapshots of flow %s: %w", flowJobName, err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error while committing incremental snapshot progress: %w", err)
+	}
+	return progress, nil
+}
+
+// UpdateIncrementalSnapshotPending records the last chunk of a table read by a pull,
+// it is confirmed once changes are synced up to pendingLSN.
+func UpdateIncrementalSnapshotPending(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+	progress *IncrementalSnapshotProgress, pendingLSN int64, pendingDone bool,
+) error {
+	_, err := pool.Exec(ctx, `UPDATE peerdb_stats.incremental_snapshots SET pending_key=$1,pending_lsn=$2,
+	 pending_done=$3 WHERE flow_name=$4 AND table_name=$5 AND requested_at=$6`,
+		progress.LastKey, pendingLSN, pendingDone, flowJobName, progress.TableName, progress.RequestedAt)
+	if err != nil {
+		return fmt.Errorf("error while updating incremental snapshot of table %s: %w", progress.TableName, err)
+	}
+	return nil
+}
+
+// MirrorValidationRange is the result of comparing a range of a table between source and destination.
+type MirrorValidationRange struct {
+	TableName string
+	// bounds of the range as text, nil when unbounded
+	RangeStart      *string
+	RangeEnd        *string
+	SourceRows      int64
+	DestinationRows int64
+	KeysMatch       bool
+	RowsMatch       bool
+}
+
+// InitializeMirrorValidation records the start of a validation of the tables of a flow.
+func InitializeMirrorValidation(ctx context.Context, pool *pgxpool.Pool, flowJobName string,
+	validationID string, numTables int,
+) error {
+	_, err := pool.Exec(ctx, `INSERT INTO peerdb_stats.mirror_validations(validation_id,flow_name,num_tables)
+	 VALUES($1,$2,$3) ON CONFLICT(validation_id) DO NOTHING`, validationID, flowJobName, numTables)
+	if err != nil {
+		return fmt.Errorf("error while initializing validation of flow %s: %w", flowJobName, err)
+	}
+	return nil
+}
+
+// AddMirrorValidationMismatch records a range of a table whose rows differ between source and destination.
+func AddMirrorValidationMismatch(ctx context.Context, pool *pgxpool.Pool, validationID string,
+	mismatch *MirrorValidationRange,
+) error {
+	_, err := pool.Exec(ctx, `INSERT INTO peerdb_stats.mirror_validation_mismatches(validation_id,table_name,
+	 range_start,range_end,source_rows,destination_rows,keys_match,rows_match) VALUES($1,$2,$3,$4,$5,$6,$7,$8)`,
+		validationID, mismatch.TableName, mismatch.RangeStart, mismatch.RangeEnd,
+		mismatch.SourceRows, mismatch.DestinationRows, mismatch.KeysMatch, mismatch.RowsMatch)
+	if err != nil {
+		return fmt.Errorf("error while recording mismatch of table %s for validation %s: %w",
+			mismatch.TableName, validationID, err)
+	}
+	return nil
+}
+
+// SetMirrorValidationRanges records the number of ranges of a table once it has been compared,
+// replacing the number recorded by earlier attempts at the table.
+func SetMirrorValidationRanges(ctx context.Context, pool *pgxpool.Pool, validationID string,
+	tableName string, numRanges int,
+) error {
+	_, err := pool.Exec(ctx, `INSERT INTO peerdb_stats.mirror_validation_tables(validation_id,table_name,num_ranges)
+	 VALUES($1,$2,$3) ON CONFLICT(validation_id,table_name) DO UPDATE SET num_ranges=EXCLUDED.num_ranges`,
+		validationID, tableName, numRanges)
+	if err != nil {
+		return fmt.Errorf("error while counting ranges of table %s for validation %s: %w", tableName, validationID, err)
+	}
+	return nil
+}
+
+// ClearMirrorValidationTable removes the mismatches recorded for a table, before it is validated again.
+func ClearMirrorValidationTable(ctx context.Context, pool *pgxpool.Pool, validationID string,
+	tableName string,
+) error {
+	_, err := pool.Exec(ctx, `DELETE FROM peerdb_stats.mirror_validation_mismatches
+	 WHERE validation_id=$1 AND table_name=$2`, validationID, tableName)
+	if err != nil {
+		return fmt.Errorf("error while clearing table %s of validation %s: %w", tableName, validationID, err)
+	}
+	return nil
+}
+
+// FinishMirrorValidation records the end of a validation, with the error it failed with if any.
+func FinishMirrorValidation(ctx context.Context, pool *pgxpool.Pool, validationID string,
+	errorMessage string,
+) error {
+	_, err := pool.Exec(ctx, `UPDATE peerdb_stats.mirror_validations SET end_time=now(),error_message=NULLIF($1,'')
+	 WHERE validation_id=$2`, errorMessage, validationID)
+	if err != nil {
+		return fmt.Errorf("error while finishing validation %s: %w", validationID, err)
+	}
+	return nil
+}
@@ -53,6 +53,21 @@ func RecordsToRawTableStream(req *model.RecordsToStreamRequest) (*model.RecordsT
 				Type:     qvalue.QValueKindString,
 				Nullable: true,
 			},
+			{
+				Name:     "_peerdb_commit_ts",
+				Type:     qvalue.QValueKindInt64,
+				Nullable: true,
+			},
+			{
+				Name:     "_peerdb_lsn",
+				Type:     qvalue.QValueKindInt64,
+				Nullable: true,
+			},
+			{
+				Name:     "_peerdb_xid",
+				Type:     qvalue.QValueKindInt64,
+				Nullable: true,
+			},
 		},
 	})
 	if err != nil {
@@ -74,7 +89,7 @@ func RecordsToRawTableStream(req *model.RecordsToStreamRequest) (*model.RecordsT
 }
 
 func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, record model.Record) model.QRecordOrError {
-	var entries [8]qvalue.QValue
+	var entries [11]qvalue.QValue
 	switch typedRecord := record.(type) {
 	case *model.InsertRecord:
 		// json.Marshal converts bytes in Hex automatically to BASE64 string.
@@ -158,6 +173,32 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 			Value: KeysToString(typedRecord.UnchangedToastColumns),
 		}
 		tableMapping[typedRecord.DestinationTableName] += 1
+	case *model.MessageRecord:
+		// logical messages are inserted into their destination table
+		itemsJSON, err := typedRecord.GetItems().ToJSON()
+		if err != nil {
+			return model.QRecordOrError{
+				Err: fmt.Errorf("failed to serialize message record items to JSON: %w", err),
+			}
+		}
+
+		entries[3] = qvalue.QValue{
+			Kind:  qvalue.QValueKindString,
+			Value: itemsJSON,
+		}
+		entries[4] = qvalue.QValue{
+			Kind:  qvalue.QValueKindInt64,
+			Value: 0,
+		}
+		entries[5] = qvalue.QValue{
+			Kind:  qvalue.QValueKindString,
+			Value: "",
+		}
+		entries[7] = qvalue.QValue{
+			Kind:  qvalue.QValueKindString,
+			Value: "",
+		}
+		tableMapping[typedRecord.DestinationTableName] += 1
 	default:
 		return model.QRecordOrError{
 			Err: fmt.Errorf("unknown record type: %T", typedRecord),
@@ -180,10 +221,22 @@ func recordToQRecordOrError(tableMapping map[string]uint32, batchID int64, recor
 		Kind:  qvalue.QValueKindInt64,
 		Value: batchID,
 	}
+	entries[8] = qvalue.QValue{
+		Kind:  qvalue.QValueKindInt64,
+		Value: model.GetCommitTimestamp(record),
+	}
+	entries[9] = qvalue.QValue{
+		Kind:  qvalue.QValueKindInt64,
+		Value: record.GetCheckPointID(),
+	}
+	entries[10] = qvalue.QValue{
+		Kind:  qvalue.QValueKindInt64,
+		Value: int64(record.GetTransactionID()),
+	}
 
 	return model.QRecordOrError{
 		Record: model.QRecord{
-			NumEntries: 8,
+			NumEntries: 11,
 			Entries:    entries[:],
 		},
 	}
@@ -8,23 +8,26 @@ import (
 )
 
 type QRecordAvroConverter struct {
-	QRecord        QRecord
-	TargetDWH      qvalue.QDWHType
-	NullableFields map[string]struct{}
-	ColNames       []string
+	QRecord             QRecord
+	TargetDWH           qvalue.QDWHType
+	NullableFields      map[string]struct{}
+	StringNumericFields map[string]struct{}
+	ColNames            []string
 }
 
 func NewQRecordAvroConverter(
 	q QRecord,
 	targetDWH qvalue.QDWHType,
 	nullableFields map[string]struct{},
+	stringNumericFields map[string]struct{},
 	colNames []string,
 ) *QRecordAvroConverter {
 	return &QRecordAvroConverter{
-		QRecord:        q,
-		TargetDWH:      targetDWH,
-		NullableFields: nullableFields,
-		ColNames:       colNames,
+		QRecord:             q,
+		TargetDWH:           targetDWH,
+		NullableFields:      nullableFields,
+		StringNumericFields: stringNumericFields,
+		ColNames:            colNames,
 	}
 }
 
@@ -34,11 +37,13 @@ func (qac *QRecordAvroConverter) Convert() (map[string]interface{}, error) {
 	for idx := range qac.QRecord.Entries {
 		key := qac.ColNames[idx]
 		_, nullable := qac.NullableFields[key]
+		_, numericAsString := qac.StringNumericFields[key]
 
 		avroConverter := qvalue.NewQValueAvroConverter(
 			qac.QRecord.Entries[idx],
 			qac.TargetDWH,
 			nullable,
+			numericAsString,
 		)
 		avroVal, err := avroConverter.ToAvroValue()
 		if err != nil {
@@ -65,21 +70,29 @@ type QRecordAvroSchema struct {
 type QRecordAvroSchemaDefinition struct {
 	Schema         string
 	NullableFields map[string]struct{}
+	// numeric fields written as strings, as the destination can't store them without rounding
+	StringNumericFields map[string]struct{}
 }
 
 func GetAvroSchemaDefinition(
 	dstTableName string,
 	qRecordSchema *QRecordSchema,
+	targetDWH qvalue.QDWHType,
 ) (*QRecordAvroSchemaDefinition, error) {
 	avroFields := make([]QRecordAvroField, 0, len(qRecordSchema.Fields))
 	nullableFields := make(map[string]struct{})
+	stringNumericFields := make(map[string]struct{})
 
 	for _, qField := range qRecordSchema.Fields {
-		avroType, err := qvalue.GetAvroSchemaFromQValueKind(qField.Type)
+		avroType, err := qvalue.GetAvroSchemaFromQValueKind(qField.Type, targetDWH, qField.Typmod)
 		if err != nil {
 			return nil, err
 		}
 
+		if qField.Type == qvalue.QValueKindNumeric && qvalue.NumericStoredAsString(qField.Typmod, targetDWH) {
+			stringNumericFields[qField.Name] = struct{}{}
+		}
+
 		if qField.Nullable {
 			avroType = []interface{}{"null", avroType}
 			nullableFields[qField.Name] = struct{}{}
@@ -103,7 +116,8 @@ func GetAvroSchemaDefinition(
 	}
 
 	return &QRecordAvroSchemaDefinition{
-		Schema:         string(avroSchemaJSON),
-		NullableFields: nullableFields,
+		Schema:              string(avroSchemaJSON),
+		NullableFields:      nullableFields,
+		StringNumericFields: stringNumericFields,
 	}, nil
 }
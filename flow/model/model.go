@@ -54,11 +54,34 @@ type PullRecordsRequest struct {
 	RecordStream *CDCRecordStream
 	// last offset may be forwarded while processing records
 	SetLastOffset func(int64) error
+	// fetch values of unchanged TOAST columns from the source
+	BackfillUnchangedToastColumns bool
+	// keep the values of rows before updates and deletes
+	CaptureBeforeImages bool
+	// capture logical decoding messages with these prefixes
+	LogicalMessagePrefixes []string
+	// destination table of captured logical decoding messages
+	LogicalMessageDestination string
+	// pull changes from the standby of the source instead of the primary
+	PullFromStandby bool
+	// skip changes that were applied to the source by replication, for bidirectional mirrors
+	SkipReplicatedChanges bool
+	// number of rows read per chunk of incremental snapshots
+	IncrementalSnapshotChunkSize uint32
+	// store records whose values fail to convert in the dead letter table and skip them, instead of failing
+	DeadLetterFailedRecords bool
+	// column the source of records is stored in, for mirrors from several sources into the same tables
+	SourceColName  string
+	SourceColValue string
 }
 
 type Record interface {
 	// GetCheckPointID returns the ID of the record.
 	GetCheckPointID() int64
+	// GetTransactionID returns the ID of the source transaction of the record.
+	GetTransactionID() uint32
+	// GetCommitTime returns the commit time of the source transaction of the record.
+	GetCommitTime() time.Time
 	// get table name
 	GetDestinationTableName() string
 	// get columns and values for the record
@@ -185,7 +208,7 @@ func (r *RecordItems) toMap() (map[string]interface{}, error) {
 			if !ok {
 				return nil, errors.New("expected *big.Rat value")
 			}
-			jsonStruct[col] = bigRat.FloatString(9)
+			jsonStruct[col] = qvalue.NumericToString(bigRat)
 		case qvalue.QValueKindFloat64:
 			floatVal, ok := v.Value.(float64)
 			if !ok {
@@ -246,6 +269,8 @@ func (r *RecordItems) toMap() (map[string]interface{}, error) {
 
 type ToJSONOptions struct {
 	UnnestColumns map[string]struct{}
+	// if set, RecordToJSONWithOpts adds the before image of updates and deletes under this key
+	BeforeImageKey string
 }
 
 func NewToJSONOptions(unnestCols []string) *ToJSONOptions {
@@ -258,10 +283,10 @@ func NewToJSONOptions(unnestCols []string) *ToJSONOptions {
 	}
 }
 
-func (r *RecordItems) ToJSONWithOpts(opts *ToJSONOptions) (string, error) {
+func (r *RecordItems) toMapWithOpts(opts *ToJSONOptions) (map[string]interface{}, error) {
 	jsonStruct, err := r.toMap()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	for col, idx := range r.ColToValIdx {
@@ -271,7 +296,7 @@ func (r *RecordItems) ToJSONWithOpts(opts *ToJSONOptions) (string, error) {
 				var unnestStruct map[string]interface{}
 				err := json.Unmarshal([]byte(v.Value.(string)), &unnestStruct)
 				if err != nil {
-					return "", err
+					return nil, err
 				}
 
 				for k, v := range unnestStruct {
@@ -282,6 +307,15 @@ func (r *RecordItems) ToJSONWithOpts(opts *ToJSONOptions) (string, error) {
 		}
 	}
 
+	return jsonStruct, nil
+}
+
+func (r *RecordItems) ToJSONWithOpts(opts *ToJSONOptions) (string, error) {
+	jsonStruct, err := r.toMapWithOpts(opts)
+	if err != nil {
+		return "", err
+	}
+
 	jsonBytes, err := json.Marshal(jsonStruct)
 	if err != nil {
 		return "", err
@@ -295,6 +329,55 @@ func (r *RecordItems) ToJSON() (string, error) {
 	return r.ToJSONWithOpts(NewToJSONOptions(unnestCols))
 }
 
+// RecordToJSONWithOpts serializes the items of a record,
+// along with its before image if opts.BeforeImageKey is set.
+func RecordToJSONWithOpts(record Record, opts *ToJSONOptions) (string, error) {
+	jsonStruct, err := record.GetItems().toMapWithOpts(opts)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.BeforeImageKey != "" {
+		if beforeImage := GetBeforeImage(record); beforeImage != nil {
+			beforeStruct, err := beforeImage.toMapWithOpts(opts)
+			if err != nil {
+				return "", err
+			}
+			jsonStruct[opts.BeforeImageKey] = beforeStruct
+		}
+	}
+
+	jsonBytes, err := json.Marshal(jsonStruct)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// GetBeforeImage returns the values of the row before an update or delete, nil for other records.
+// For tables without REPLICA IDENTITY FULL, only the key columns and values seen earlier in the batch are known.
+func GetBeforeImage(record Record) *RecordItems {
+	switch r := record.(type) {
+	case *UpdateRecord:
+		return r.OldItems
+	case *DeleteRecord:
+		return r.Items
+	default:
+		return nil
+	}
+}
+
+// GetCommitTimestamp returns the commit time of the source transaction of a record in nanoseconds since epoch,
+// nil if the commit time is not known.
+func GetCommitTimestamp(record Record) interface{} {
+	commitTime := record.GetCommitTime()
+	if commitTime.IsZero() {
+		return nil
+	}
+	return commitTime.UnixNano()
+}
+
 type InsertRecord struct {
 	// Name of the source table
 	SourceTableName string
@@ -304,6 +387,10 @@ type InsertRecord struct {
 	CheckPointID int64
 	// CommitID is the ID of the commit corresponding to this record.
 	CommitID int64
+	// TransactionID is the ID of the source transaction of this record.
+	TransactionID uint32
+	// CommitTime is the commit time of the source transaction of this record.
+	CommitTime time.Time
 	// Items is a map of column name to value.
 	Items *RecordItems
 }
@@ -313,6 +400,14 @@ func (r *InsertRecord) GetCheckPointID() int64 {
 	return r.CheckPointID
 }
 
+func (r *InsertRecord) GetTransactionID() uint32 {
+	return r.TransactionID
+}
+
+func (r *InsertRecord) GetCommitTime() time.Time {
+	return r.CommitTime
+}
+
 func (r *InsertRecord) GetDestinationTableName() string {
 	return r.DestinationTableName
 }
@@ -326,6 +421,10 @@ type UpdateRecord struct {
 	SourceTableName string
 	// CheckPointID is the ID of the record.
 	CheckPointID int64
+	// TransactionID is the ID of the source transaction of this record.
+	TransactionID uint32
+	// CommitTime is the commit time of the source transaction of this record.
+	CommitTime time.Time
 	// Name of the destination table
 	DestinationTableName string
 	// OldItems is a map of column name to value.
@@ -341,6 +440,14 @@ func (r *UpdateRecord) GetCheckPointID() int64 {
 	return r.CheckPointID
 }
 
+func (r *UpdateRecord) GetTransactionID() uint32 {
+	return r.TransactionID
+}
+
+func (r *UpdateRecord) GetCommitTime() time.Time {
+	return r.CommitTime
+}
+
 // Implement Record interface for UpdateRecord.
 func (r *UpdateRecord) GetDestinationTableName() string {
 	return r.DestinationTableName
@@ -357,6 +464,10 @@ type DeleteRecord struct {
 	DestinationTableName string
 	// CheckPointID is the ID of the record.
 	CheckPointID int64
+	// TransactionID is the ID of the source transaction of this record.
+	TransactionID uint32
+	// CommitTime is the commit time of the source transaction of this record.
+	CommitTime time.Time
 	// Items is a map of column name to value.
 	Items *RecordItems
 	// unchanged toast columns, filled from latest UpdateRecord
@@ -368,6 +479,14 @@ func (r *DeleteRecord) GetCheckPointID() int64 {
 	return r.CheckPointID
 }
 
+func (r *DeleteRecord) GetTransactionID() uint32 {
+	return r.TransactionID
+}
+
+func (r *DeleteRecord) GetCommitTime() time.Time {
+	return r.CommitTime
+}
+
 func (r *DeleteRecord) GetDestinationTableName() string {
 	return r.DestinationTableName
 }
@@ -376,6 +495,78 @@ func (r *DeleteRecord) GetItems() *RecordItems {
 	return r.Items
 }
 
+// MessageRecord is a message emitted on the source with pg_logical_emit_message,
+// it is written as an insert into the destination table configured for logical messages.
+type MessageRecord struct {
+	// Name of the destination table
+	DestinationTableName string
+	// CheckPointID is the LSN of the message.
+	CheckPointID int64
+	// TransactionID is the ID of the source transaction of this message, 0 if not transactional.
+	TransactionID uint32
+	// CommitTime is the commit time of the source transaction of this message, zero if not transactional.
+	CommitTime time.Time
+	// Prefix is the prefix the message was emitted with.
+	Prefix string
+	// Content is the payload of the message, kept as bytes as it need not be valid UTF-8.
+	Content []byte
+	// Transactional is set if the message was emitted as part of a transaction.
+	Transactional bool
+}
+
+const (
+	MessagePrefixColName        = "prefix"
+	MessageContentColName       = "content"
+	MessageTransactionalColName = "transactional"
+	MessageLSNColName           = "lsn"
+)
+
+// Implement Record interface for MessageRecord.
+func (r *MessageRecord) GetCheckPointID() int64 {
+	return r.CheckPointID
+}
+
+func (r *MessageRecord) GetTransactionID() uint32 {
+	return r.TransactionID
+}
+
+func (r *MessageRecord) GetCommitTime() time.Time {
+	return r.CommitTime
+}
+
+func (r *MessageRecord) GetDestinationTableName() string {
+	return r.DestinationTableName
+}
+
+func (r *MessageRecord) GetItems() *RecordItems {
+	return NewRecordItemWithData(
+		[]string{MessagePrefixColName, MessageContentColName, MessageTransactionalColName, MessageLSNColName},
+		[]qvalue.QValue{
+			{Kind: qvalue.QValueKindString, Value: r.Prefix},
+			{Kind: qvalue.QValueKindBytes, Value: r.Content},
+			{Kind: qvalue.QValueKindBoolean, Value: r.Transactional},
+			{Kind: qvalue.QValueKindInt64, Value: r.CheckPointID},
+		},
+	)
+}
+
+// MessageTableSchema returns the schema of the destination table of logical messages,
+// keyed by the LSN of each message.
+func MessageTableSchema(tableIdentifier string) *protos.TableSchema {
+	return &protos.TableSchema{
+		TableIdentifier:   tableIdentifier,
+		PrimaryKeyColumns: []string{MessageLSNColName},
+		ColumnNames: []string{
+			MessagePrefixColName, MessageContentColName, MessageTransactionalColName, MessageLSNColName,
+		},
+		ColumnTypes: []string{
+			string(qvalue.QValueKindString), string(qvalue.QValueKindBytes),
+			string(qvalue.QValueKindBoolean), string(qvalue.QValueKindInt64),
+		},
+		ColumnTypmods: []int32{-1, -1, -1, -1},
+	}
+}
+
 type TableWithPkey struct {
 	TableName string
 	// SHA256 hash of the primary key columns
@@ -474,17 +665,43 @@ schemaLoop:
 }
 
 func (r *CDCRecordStream) Close() {
+	// set before the records are closed, so that it is seen by consumers once they have read all records
+	r.lastCheckpointSet = true
 	close(r.emptySignal)
 	close(r.records)
 	close(r.SchemaDeltas)
 	close(r.RelationMessageMapping)
-	r.lastCheckpointSet = true
 }
 
 func (r *CDCRecordStream) GetRecords() <-chan Record {
 	return r.records
 }
 
+// FanOut copies the records of the stream to a new stream per checkpoint, for destinations synced from the same pull.
+// Records up to the checkpoint of a destination have already been synced to it, and are left out of its stream.
+// The streams are closed with the last checkpoint of this stream once it is closed.
+func (r *CDCRecordStream) FanOut(checkpoints []int64) []*CDCRecordStream {
+	streams := make([]*CDCRecordStream, 0, len(checkpoints))
+	for range checkpoints {
+		streams = append(streams, NewCDCRecordStream())
+	}
+
+	go func() {
+		for record := range r.records {
+			for i, stream := range streams {
+				if record.GetCheckPointID() > checkpoints[i] {
+					stream.AddRecord(record)
+				}
+			}
+		}
+		for _, stream := range streams {
+			stream.UpdateLatestCheckpoint(r.lastCheckPointID.Load())
+			stream.Close()
+		}
+	}()
+	return streams
+}
+
 type SyncAndNormalizeBatchID struct {
 	SyncBatchID      int64
 	NormalizeBatchID int64
@@ -502,13 +719,53 @@ type SyncRecordsRequest struct {
 	PushBatchSize int64
 	// PushParallelism is the number of batches in Event Hub to push in parallel.
 	PushParallelism int64
+	// BeforeImageColName is the key of before images in event payloads, empty if not captured.
+	BeforeImageColName string
 }
 
 type NormalizeRecordsRequest struct {
-	FlowJobName       string
-	SoftDelete        bool
-	SoftDeleteColName string
-	SyncedAtColName   string
+	FlowJobName        string
+	SoftDelete         bool
+	SoftDeleteColName  string
+	SyncedAtColName    string
+	BeforeImageColName string
+	HistoryMode        bool
+	// write commit time, LSN and transaction ID of changes to the normalized tables
+	TransactionMetadata bool
+	// attribute changes to a replication origin, so they are not replicated back by bidirectional mirrors
+	Bidirectional bool
+	// how changes conflicting with changes made on the destination are resolved
+	ConflictResolution protos.ConflictResolution
+	// if set, changes to these destination tables in batches after ReplayFromBatchID are normalized again,
+	// up to the last normalized batch, without advancing the normalize batch ID
+	ReplayTables      []string
+	ReplayFromBatchID int64
+}
+
+// ReplayBatchIDs returns the batches to normalize, for replays these are the batches
+// after ReplayFromBatchID that were already normalized.
+func (r *NormalizeRecordsRequest) ReplayBatchIDs(batchIDs SyncAndNormalizeBatchID) SyncAndNormalizeBatchID {
+	if len(r.ReplayTables) == 0 {
+		return batchIDs
+	}
+	return SyncAndNormalizeBatchID{
+		SyncBatchID:      batchIDs.NormalizeBatchID,
+		NormalizeBatchID: r.ReplayFromBatchID,
+	}
+}
+
+// ReplayTableNames returns the destination tables to normalize, for replays only the replayed ones.
+func (r *NormalizeRecordsRequest) ReplayTableNames(tableNames []string) []string {
+	if len(r.ReplayTables) == 0 {
+		return tableNames
+	}
+	replayTableNames := make([]string, 0, len(r.ReplayTables))
+	for _, tableName := range tableNames {
+		if slices.Contains(r.ReplayTables, tableName) {
+			replayTableNames = append(replayTableNames, tableName)
+		}
+	}
+	return replayTableNames
 }
 
 type SyncResponse struct {
@@ -544,6 +801,14 @@ func (r *RelationRecord) GetCheckPointID() int64 {
 	return r.CheckPointID
 }
 
+func (r *RelationRecord) GetTransactionID() uint32 {
+	return 0
+}
+
+func (r *RelationRecord) GetCommitTime() time.Time {
+	return time.Time{}
+}
+
 func (r *RelationRecord) GetDestinationTableName() string {
 	return r.TableSchemaDelta.DstTableName
 }
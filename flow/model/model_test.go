@@ -0,0 +1,30 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestCDCRecordStreamFanOut(t *testing.T) {
+	stream := NewCDCRecordStream()
+	streams := stream.FanOut([]int64{0, 2})
+
+	for checkpoint := int64(1); checkpoint <= 3; checkpoint++ {
+		stream.AddRecord(&InsertRecord{CheckPointID: checkpoint, Items: NewRecordItems(0)})
+	}
+	stream.UpdateLatestCheckpoint(3)
+	stream.Close()
+
+	for i, expected := range []int{3, 1} {
+		numRecords := 0
+		for range streams[i].GetRecords() {
+			numRecords++
+		}
+		if numRecords != expected {
+			t.Errorf("Expected %d records in stream %d, got %d", expected, i, numRecords)
+		}
+		lastCheckpoint, err := streams[i].GetLastCheckpoint()
+		if err != nil || lastCheckpoint != 3 {
+			t.Errorf("Expected last checkpoint 3 in stream %d, got %d: %v", i, lastCheckpoint, err)
+		}
+	}
+}
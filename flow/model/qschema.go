@@ -10,6 +10,9 @@ type QField struct {
 	Name     string
 	Type     qvalue.QValueKind
 	Nullable bool
+	// type modifier of the source column, only populated for Postgres sources.
+	// used to determine precision and scale of numerics.
+	Typmod int32
 }
 
 type QRecordSchema struct {
@@ -30,8 +30,8 @@ type AvroSchemaNumeric struct {
 // representing the Avro schema and an error if the QValueKind is unsupported.
 //
 // For example, QValueKindInt64 would return an AvroLogicalSchema of "long". Unsupported QValueKinds
-// will return an error.
-func GetAvroSchemaFromQValueKind(kind QValueKind) (interface{}, error) {
+// will return an error. The typmod is used to determine the precision and scale of numerics.
+func GetAvroSchemaFromQValueKind(kind QValueKind, targetDWH QDWHType, typmod int32) (interface{}, error) {
 	switch kind {
 	case QValueKindString, QValueKindUUID:
 		return "string", nil
@@ -48,11 +48,15 @@ func GetAvroSchemaFromQValueKind(kind QValueKind) (interface{}, error) {
 	case QValueKindBytes, QValueKindBit:
 		return "bytes", nil
 	case QValueKindNumeric:
+		if NumericStoredAsString(typmod, targetDWH) {
+			return "string", nil
+		}
+		precision, scale := DetermineNumericSettingForDWH(typmod, targetDWH)
 		return AvroSchemaNumeric{
 			Type:        "bytes",
 			LogicalType: "decimal",
-			Precision:   38,
-			Scale:       9,
+			Precision:   int(precision),
+			Scale:       int(scale),
 		}, nil
 	case QValueKindTime, QValueKindTimeTZ, QValueKindDate, QValueKindTimestamp, QValueKindTimestampTZ:
 		return "string", nil
@@ -95,13 +99,16 @@ type QValueAvroConverter struct {
 	Value     QValue
 	TargetDWH QDWHType
 	Nullable  bool
+	// NumericAsString is set for numerics whose Avro schema is a string, see NumericStoredAsString
+	NumericAsString bool
 }
 
-func NewQValueAvroConverter(value QValue, targetDWH QDWHType, nullable bool) *QValueAvroConverter {
+func NewQValueAvroConverter(value QValue, targetDWH QDWHType, nullable bool, numericAsString bool) *QValueAvroConverter {
 	return &QValueAvroConverter{
-		Value:     value,
-		TargetDWH: targetDWH,
-		Nullable:  nullable,
+		Value:           value,
+		TargetDWH:       targetDWH,
+		Nullable:        nullable,
+		NumericAsString: numericAsString,
 	}
 }
 
@@ -221,6 +228,14 @@ func (c *QValueAvroConverter) processNumeric() (interface{}, error) {
 		return nil, fmt.Errorf("invalid Numeric value: expected *big.Rat, got %T", c.Value.Value)
 	}
 
+	if c.NumericAsString {
+		numStr := NumericToString(num)
+		if c.Nullable {
+			return goavro.Union("string", numStr), nil
+		}
+		return numStr, nil
+	}
+
 	if c.Nullable {
 		return goavro.Union("bytes.decimal", num), nil
 	}
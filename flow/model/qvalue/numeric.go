@@ -0,0 +1,117 @@
+package qvalue
+
+import (
+	"math/big"
+)
+
+const (
+	// default precision and scale used for numerics whose source column is unconstrained,
+	// or constrained beyond what the destination supports
+	PeerDBNumericPrecision = 38
+	PeerDBNumericScale     = 9
+
+	// BigQuery BIGNUMERIC supports 76 digits of precision and 38 digits of scale
+	BigQueryBigNumericPrecision = 76
+	BigQueryBigNumericScale     = 38
+
+	// Snowflake NUMBER supports at most 38 digits of precision, scale must be less than precision
+	SnowflakeNumberMaxPrecision = 38
+)
+
+// ParseNumericTypmod extracts the precision and scale from the typmod of a Postgres numeric column.
+// Unconstrained numerics (typmod -1) return 0 for both precision and scale.
+func ParseNumericTypmod(typmod int32) (int16, int16) {
+	// typmod includes the 4 byte varlena header
+	const varHdrSz = 4
+	if typmod < varHdrSz {
+		return 0, 0
+	}
+
+	typmod -= varHdrSz
+	precision := int16((typmod >> 16) & 0xFFFF)
+	// since PG15 the scale can be negative, it is stored as an 11 bit signed integer
+	scale := int16(((typmod & 0x7FF) ^ 1024) - 1024)
+	return precision, scale
+}
+
+// NumericTypmod returns the typmod of a Postgres numeric column with the given precision and scale.
+func NumericTypmod(precision, scale int16) int32 {
+	// typmod includes the 4 byte varlena header
+	return ((int32(precision) << 16) | (int32(scale) & 0x7FF)) + 4
+}
+
+// DetermineNumericSettingForDWH returns the precision and scale to use for a numeric column
+// with the given typmod on the destination. Unconstrained numerics on BigQuery use BIGNUMERIC limits,
+// other destinations fall back to PeerDBNumericPrecision and PeerDBNumericScale.
+func DetermineNumericSettingForDWH(typmod int32, dwh QDWHType) (int16, int16) {
+	precision, scale := ParseNumericTypmod(typmod)
+	if precision > 0 && scale < 0 {
+		// negative scale rounds to the left of the decimal point, so the values are integers
+		precision -= scale
+		scale = 0
+	} else if scale > precision {
+		// since PG15 the scale can also exceed the precision, all values are then below 1
+		precision = scale
+	}
+
+	switch dwh {
+	case QDWHTypeBigQuery:
+		if precision == 0 || precision > BigQueryBigNumericPrecision ||
+			scale > BigQueryBigNumericScale || precision-scale > BigQueryBigNumericPrecision-BigQueryBigNumericScale {
+			return BigQueryBigNumericPrecision, BigQueryBigNumericScale
+		}
+	default:
+		if precision == 0 || precision > SnowflakeNumberMaxPrecision {
+			return PeerDBNumericPrecision, PeerDBNumericScale
+		}
+	}
+
+	return precision, scale
+}
+
+// NumericStoredAsString reports whether a numeric column with the given typmod is stored as a string
+// on the destination, because no numeric type of the destination can hold its values without rounding.
+// This is the case on Snowflake for unconstrained numerics and numerics with more than 38 digits of precision.
+func NumericStoredAsString(typmod int32, dwh QDWHType) bool {
+	if dwh != QDWHTypeSnowflake {
+		return false
+	}
+	precision, scale := ParseNumericTypmod(typmod)
+	if precision > 0 && scale < 0 {
+		precision -= scale
+	}
+	return precision == 0 || precision > SnowflakeNumberMaxPrecision
+}
+
+// NumericToString formats a numeric without rounding.
+// Values decoded from Postgres always have a power of 10 as denominator and are formatted exactly,
+// anything else is rounded to PeerDBNumericScale digits.
+func NumericToString(num *big.Rat) string {
+	if num.IsInt() {
+		return num.Num().String()
+	}
+
+	// the number of decimal digits is the larger of the powers of 2 and 5 in the denominator
+	denom := new(big.Int).Set(num.Denom())
+	rem := new(big.Int)
+	var twos, fives int
+	for _, factor := range []struct {
+		divisor int64
+		count   *int
+	}{{2, &twos}, {5, &fives}} {
+		divisor := big.NewInt(factor.divisor)
+		for {
+			quo, _ := new(big.Int).QuoRem(denom, divisor, rem)
+			if rem.Sign() != 0 {
+				break
+			}
+			denom = quo
+			*factor.count++
+		}
+	}
+
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return num.FloatString(PeerDBNumericScale)
+	}
+	return num.FloatString(max(twos, fives))
+}
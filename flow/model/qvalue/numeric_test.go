@@ -0,0 +1,115 @@
+package qvalue
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParseNumericTypmod(t *testing.T) {
+	tests := []struct {
+		typmod    int32
+		precision int16
+		scale     int16
+	}{
+		{-1, 0, 0},
+		{NumericTypmod(10, 2), 10, 2},
+		{NumericTypmod(38, 20), 38, 20},
+		{NumericTypmod(5, -2), 5, -2},
+		{NumericTypmod(1000, 0), 1000, 0},
+	}
+
+	for _, tt := range tests {
+		precision, scale := ParseNumericTypmod(tt.typmod)
+		if precision != tt.precision || scale != tt.scale {
+			t.Errorf("ParseNumericTypmod(%d) = (%d, %d), expected (%d, %d)",
+				tt.typmod, precision, scale, tt.precision, tt.scale)
+		}
+	}
+}
+
+func TestDetermineNumericSettingForDWH(t *testing.T) {
+	tests := []struct {
+		typmod    int32
+		dwh       QDWHType
+		precision int16
+		scale     int16
+	}{
+		{-1, QDWHTypeSnowflake, PeerDBNumericPrecision, PeerDBNumericScale},
+		{-1, QDWHTypeBigQuery, BigQueryBigNumericPrecision, BigQueryBigNumericScale},
+		{NumericTypmod(20, 15), QDWHTypeSnowflake, 20, 15},
+		{NumericTypmod(20, 15), QDWHTypeBigQuery, 20, 15},
+		{NumericTypmod(50, 10), QDWHTypeSnowflake, PeerDBNumericPrecision, PeerDBNumericScale},
+		{NumericTypmod(45, 10), QDWHTypeBigQuery, 45, 10},
+		{NumericTypmod(50, 10), QDWHTypeBigQuery, BigQueryBigNumericPrecision, BigQueryBigNumericScale},
+		{NumericTypmod(5, -2), QDWHTypeSnowflake, 7, 0},
+		{NumericTypmod(2, 5), QDWHTypeSnowflake, 5, 5},
+	}
+
+	for _, tt := range tests {
+		precision, scale := DetermineNumericSettingForDWH(tt.typmod, tt.dwh)
+		if precision != tt.precision || scale != tt.scale {
+			t.Errorf("DetermineNumericSettingForDWH(%d, %d) = (%d, %d), expected (%d, %d)",
+				tt.typmod, tt.dwh, precision, scale, tt.precision, tt.scale)
+		}
+	}
+}
+
+func TestNumericToString(t *testing.T) {
+	tests := []struct {
+		num      *big.Rat
+		expected string
+	}{
+		{big.NewRat(42, 1), "42"},
+		{big.NewRat(-5, 2), "-2.5"},
+		{big.NewRat(1, 8), "0.125"},
+		{big.NewRat(123456789012345, 1000000000000000), "0.123456789012345"},
+		{big.NewRat(1, 3), "0.333333333"},
+	}
+
+	for _, tt := range tests {
+		if actual := NumericToString(tt.num); actual != tt.expected {
+			t.Errorf("NumericToString(%s) = %s, expected %s", tt.num, actual, tt.expected)
+		}
+	}
+}
+
+func TestNumericStoredAsString(t *testing.T) {
+	tests := []struct {
+		typmod   int32
+		dwh      QDWHType
+		expected bool
+	}{
+		{-1, QDWHTypeSnowflake, true},
+		{-1, QDWHTypeBigQuery, false},
+		{NumericTypmod(38, 9), QDWHTypeSnowflake, false},
+		{NumericTypmod(50, 10), QDWHTypeSnowflake, true},
+		{NumericTypmod(37, -2), QDWHTypeSnowflake, true},
+		{NumericTypmod(50, 10), QDWHTypeBigQuery, false},
+	}
+
+	for _, tt := range tests {
+		if actual := NumericStoredAsString(tt.typmod, tt.dwh); actual != tt.expected {
+			t.Errorf("NumericStoredAsString(%d, %d) = %t, expected %t", tt.typmod, tt.dwh, actual, tt.expected)
+		}
+	}
+}
+
+func TestNumericAsStringAvro(t *testing.T) {
+	schema, err := GetAvroSchemaFromQValueKind(QValueKindNumeric, QDWHTypeSnowflake, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema != "string" {
+		t.Errorf("expected unconstrained numeric to have a string Avro schema, got %v", schema)
+	}
+
+	num, _ := new(big.Rat).SetString("123456789012345678901234567890.123456789012345")
+	value, err := NewQValueAvroConverter(QValue{Kind: QValueKindNumeric, Value: num},
+		QDWHTypeSnowflake, false, true).ToAvroValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "123456789012345678901234567890.123456789012345" {
+		t.Errorf("expected numeric to be converted without rounding, got %v", value)
+	}
+}
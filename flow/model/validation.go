@@ -0,0 +1,207 @@
+package model
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math/big"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TableRangeRequest selects the rows of a table in a range of its key, to be counted and checksummed.
+type TableRangeRequest struct {
+	TableIdentifier string
+	// primary key columns, in the order of the primary key
+	KeyColumns []string
+	// the other columns to checksum
+	Columns []string
+	// columns holding JSON, their values are compared in a canonical form
+	JSONColumns []string
+	// column the range is on, the whole table is selected if empty
+	RangeColumn string
+	// the range is on the text of the range column in byte order, for keys that are neither integers nor timestamps
+	RangeAsText bool
+	// the range excludes Start and includes End, nil bounds are unbounded
+	Start any
+	End   any
+	// rows marked as deleted by this column are skipped, if set
+	SoftDeleteColName string
+	// only current versions of rows are selected with this column, for destinations in history mode
+	CurrentColName string
+	// only rows from this source are selected, for tables mirrored from several sources
+	SourceColName  string
+	SourceColValue string
+}
+
+// AllColumns returns the columns to select, key columns first.
+func (r *TableRangeRequest) AllColumns() []string {
+	columns := make([]string, 0, len(r.KeyColumns)+len(r.Columns))
+	columns = append(columns, r.KeyColumns...)
+	return append(columns, r.Columns...)
+}
+
+// TableRangeChecksum holds the number of rows of a range and sums of hashes of their keys and values.
+// The sums don't depend on the order of the rows, so the source and destination can be read in any order.
+// Values are hashed in a canonical form, so that they match across the types of different peers.
+type TableRangeChecksum struct {
+	NumRows     int64
+	KeyChecksum uint64
+	RowChecksum uint64
+
+	numKeyColumns int
+	jsonColumns   []bool
+}
+
+func NewTableRangeChecksum(req *TableRangeRequest) *TableRangeChecksum {
+	var jsonColumns []bool
+	if len(req.JSONColumns) > 0 {
+		for _, col := range req.AllColumns() {
+			jsonColumns = append(jsonColumns, slices.Contains(req.JSONColumns, col))
+		}
+	}
+	return &TableRangeChecksum{
+		numKeyColumns: len(req.KeyColumns),
+		jsonColumns:   jsonColumns,
+	}
+}
+
+// AddRow adds a row with the values of the columns of the request, key columns first.
+func (c *TableRangeChecksum) AddRow(values []any) {
+	h := fnv.New64a()
+	for i, val := range values {
+		if i == c.numKeyColumns {
+			c.KeyChecksum += h.Sum64()
+		}
+		if i < len(c.jsonColumns) && c.jsonColumns[i] {
+			val = canonicalJSON(val)
+		}
+		writeChecksumValue(h, val)
+	}
+	if len(values) <= c.numKeyColumns {
+		c.KeyChecksum += h.Sum64()
+	}
+	c.RowChecksum += h.Sum64()
+	c.NumRows++
+}
+
+// AddQRecord adds a record selected with the columns of the request.
+func (c *TableRangeChecksum) AddQRecord(record QRecord) {
+	values := make([]any, 0, len(record.Entries))
+	for _, entry := range record.Entries {
+		values = append(values, entry.Value)
+	}
+	c.AddRow(values)
+}
+
+// AddQRecordStream adds the records of a stream selected with the columns of the request,
+// the stream is read until it is closed and the first error in it is returned.
+func (c *TableRangeChecksum) AddQRecordStream(stream *QRecordStream) error {
+	var streamErr error
+	for record := range stream.Records {
+		if record.Err != nil {
+			if streamErr == nil {
+				streamErr = record.Err
+			}
+			continue
+		}
+		if streamErr == nil {
+			c.AddQRecord(record.Record)
+		}
+	}
+	return streamErr
+}
+
+// Matches returns whether the keys and the rows of two ranges are the same.
+func (c *TableRangeChecksum) Matches(other *TableRangeChecksum) (bool, bool) {
+	keysMatch := c.NumRows == other.NumRows && c.KeyChecksum == other.KeyChecksum
+	return keysMatch, keysMatch && c.RowChecksum == other.RowChecksum
+}
+
+// canonicalJSON formats JSON compactly with sorted keys, as peers format the same JSON differently.
+// Values that aren't valid JSON are left as they are.
+func canonicalJSON(val any) any {
+	var raw []byte
+	switch v := val.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return val
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var parsed any
+	if err := decoder.Decode(&parsed); err != nil {
+		return val
+	}
+	canonical, err := json.Marshal(parsed)
+	if err != nil {
+		return val
+	}
+	return string(canonical)
+}
+
+func writeChecksumValue(h hash.Hash64, val any) {
+	var s string
+	switch v := val.(type) {
+	case nil:
+		// separate NULL from every other value, including empty strings
+		_, _ = h.Write([]byte{0})
+		return
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case int:
+		s = strconv.FormatInt(int64(v), 10)
+	case int8:
+		s = strconv.FormatInt(int64(v), 10)
+	case int16:
+		s = strconv.FormatInt(int64(v), 10)
+	case int32:
+		s = strconv.FormatInt(int64(v), 10)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case uint8:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		s = strconv.FormatUint(v, 10)
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		// reals are widened to doubles by some destinations, they are formatted as reals again
+		if float64(float32(v)) == v {
+			s = strconv.FormatFloat(v, 'g', -1, 32)
+		} else {
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case *big.Rat:
+		s = v.RatString()
+	case time.Time:
+		s = v.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	case []byte:
+		s = hex.EncodeToString(v)
+	case [16]byte:
+		s = uuid.UUID(v).String()
+	case uuid.UUID:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	_, _ = h.Write([]byte{1})
+	_, _ = h.Write([]byte(s))
+	_, _ = h.Write([]byte{0x1f})
+}
@@ -0,0 +1,109 @@
+package model_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/model/qvalue"
+	"github.com/google/uuid"
+)
+
+func TestTableRangeChecksum(t *testing.T) {
+	req := &model.TableRangeRequest{KeyColumns: []string{"id"}, Columns: []string{"val", "at", "amount"}}
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	src := model.NewTableRangeChecksum(req)
+	src.AddRow([]any{int32(1), float32(1.1), at, big.NewRat(3, 2)})
+	src.AddRow([]any{int32(2), nil, at, nil})
+
+	// rows are read in another order, with the types of another peer
+	dst := model.NewTableRangeChecksum(req)
+	dst.AddRow([]any{int64(2), nil, at.In(time.FixedZone("", 3600)), nil})
+	dst.AddRow([]any{int64(1), float64(float32(1.1)), at.Add(500 * time.Nanosecond), big.NewRat(30, 20)})
+
+	keysMatch, rowsMatch := src.Matches(dst)
+	if !keysMatch || !rowsMatch {
+		t.Errorf("Expected checksums to match: %+v %+v", src, dst)
+	}
+
+	changed := model.NewTableRangeChecksum(req)
+	changed.AddRow([]any{int64(1), float64(1.1), at, big.NewRat(3, 2)})
+	changed.AddRow([]any{int64(2), "", at, nil})
+	keysMatch, rowsMatch = src.Matches(changed)
+	if !keysMatch || rowsMatch {
+		t.Errorf("Expected only keys to match: %+v %+v", src, changed)
+	}
+
+	missing := model.NewTableRangeChecksum(req)
+	missing.AddRow([]any{int64(1), float64(float32(1.1)), at, big.NewRat(3, 2)})
+	keysMatch, _ = src.Matches(missing)
+	if keysMatch {
+		t.Errorf("Expected keys not to match: %+v %+v", src, missing)
+	}
+}
+
+func TestTableRangeChecksumUUID(t *testing.T) {
+	req := &model.TableRangeRequest{KeyColumns: []string{"id"}}
+	id := uuid.New()
+
+	src := model.NewTableRangeChecksum(req)
+	src.AddRow([]any{[16]byte(id)})
+	dst := model.NewTableRangeChecksum(req)
+	dst.AddRow([]any{id.String()})
+
+	keysMatch, rowsMatch := src.Matches(dst)
+	if !keysMatch || !rowsMatch {
+		t.Errorf("Expected checksums to match: %+v %+v", src, dst)
+	}
+}
+
+func TestTableRangeChecksumJSON(t *testing.T) {
+	req := &model.TableRangeRequest{
+		KeyColumns:  []string{"id"},
+		Columns:     []string{"doc", "note"},
+		JSONColumns: []string{"doc"},
+	}
+
+	// jsonb on Postgres, VARIANT on Snowflake and JSON on BigQuery are each formatted differently
+	src := model.NewTableRangeChecksum(req)
+	src.AddRow([]any{int64(1), `{"b": [1, 2.50], "a": "x"}`, `{"b": 1}`})
+	dst := model.NewTableRangeChecksum(req)
+	dst.AddRow([]any{int64(1), "{\n  \"a\": \"x\",\n  \"b\": [\n    1,\n    2.50\n  ]\n}", `{"b": 1}`})
+
+	keysMatch, rowsMatch := src.Matches(dst)
+	if !keysMatch || !rowsMatch {
+		t.Errorf("Expected checksums to match: %+v %+v", src, dst)
+	}
+
+	// only JSON columns are compared in a canonical form
+	other := model.NewTableRangeChecksum(req)
+	other.AddRow([]any{int64(1), `{"a":"x","b":[1,2.50]}`, `{"b":1}`})
+	_, rowsMatch = src.Matches(other)
+	if rowsMatch {
+		t.Errorf("Expected rows not to match: %+v %+v", src, other)
+	}
+}
+
+func TestTableRangeChecksumStream(t *testing.T) {
+	req := &model.TableRangeRequest{KeyColumns: []string{"id"}}
+
+	stream := model.NewQRecordStream(3)
+	for _, id := range []int64{1, 2} {
+		record := model.NewQRecord(1)
+		record.Set(0, qvalue.QValue{Kind: qvalue.QValueKindInt64, Value: id})
+		stream.Records <- model.QRecordOrError{Record: record}
+	}
+	stream.Records <- model.QRecordOrError{Err: errors.New("connection lost")}
+	close(stream.Records)
+
+	checksum := model.NewTableRangeChecksum(req)
+	if err := checksum.AddQRecordStream(stream); err == nil {
+		t.Errorf("Expected the error of the stream to be returned")
+	}
+	if checksum.NumRows != 2 {
+		t.Errorf("Expected 2 rows, got %d", checksum.NumRows)
+	}
+}
@@ -14,11 +14,15 @@ const (
 	// Signals
 	CDCFlowSignalName              = "peer-flow-signal"
 	CDCDynamicPropertiesSignalName = "cdc-dynamic-properties"
+	ResyncTableSignalName          = "resync-table"
+	ResyncTableDoneSignalName      = "resync-table-done"
+	SchemaChangeSignalName         = "schema-change"
 
 	// Queries
-	CDCFlowStateQuery  = "q-cdc-flow-status"
-	QRepFlowStateQuery = "q-qrep-flow-state"
-	FlowStatusQuery    = "q-flow-status"
+	CDCFlowStateQuery        = "q-cdc-flow-status"
+	QRepFlowStateQuery       = "q-qrep-flow-state"
+	FlowStatusQuery          = "q-flow-status"
+	PendingSchemaDeltasQuery = "q-pending-schema-deltas"
 
 	// Updates
 	FlowStatusUpdate = "u-flow-status"
@@ -26,6 +30,20 @@ const (
 
 const MirrorNameSearchAttribute = "MirrorName"
 
+// columns added to normalized tables in history mode
+const (
+	HistoryValidFromColName = "_PEERDB_VALID_FROM"
+	HistoryValidToColName   = "_PEERDB_VALID_TO"
+	HistoryIsCurrentColName = "_PEERDB_IS_CURRENT"
+)
+
+// columns added to normalized tables with transaction metadata
+const (
+	CommitTimestampColName = "_PEERDB_COMMIT_TS"
+	LSNColName             = "_PEERDB_LSN"
+	TransactionIDColName   = "_PEERDB_XID"
+)
+
 type (
 	CDCFlowSignal int64
 	ContextKey    string
@@ -3,9 +3,11 @@ package peerflow
 import (
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
 	"github.com/PeerDB-io/peer-flow/model"
 	"github.com/PeerDB-io/peer-flow/shared"
@@ -14,10 +16,13 @@ import (
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
+	"golang.org/x/exp/maps"
 )
 
 const (
 	maxSyncFlowsPerCDCFlow = 32
+	// how often sequences are synced to the destination when sync_sequences is set
+	sequenceSyncInterval = 5 * time.Minute
 )
 
 type CDCFlowLimits struct {
@@ -49,6 +54,44 @@ type CDCFlowWorkflowState struct {
 	RelationMessageMapping model.RelationMessageMapping
 	// current workflow state
 	CurrentFlowState protos.FlowStatus
+	// when sequences were last synced to the destination.
+	LastSequenceSyncTime time.Time
+	// tables being resynced while changes are synced, by source table.
+	TableResyncs map[string]*TableResync
+	// schema changes waiting to be accepted or rejected, under the pause schema change policy.
+	PendingSchemaDeltas []*protos.TableSchemaDelta
+	// ID of the snapshot flow, which the flow names of the clones of the tables are derived from.
+	SnapshotFlowID string
+}
+
+// CloneFlowNames returns the flow names of the clones of the tables of the mirror,
+// those started by its snapshot flow and by the resyncs of its tables in progress.
+// Clones started before their flow names were derived have random ones, which aren't returned.
+func (s *CDCFlowWorkflowState) CloneFlowNames(cfg *protos.FlowConnectionConfigs) []string {
+	cloneFlowNames := make([]string, 0, len(s.TableResyncs))
+	if s.SnapshotFlowID != "" {
+		for _, destination := range append([]*protos.Peer{cfg.Destination}, cfg.FanOutDestinations...) {
+			for _, mapping := range cfg.TableMappings {
+				cloneFlowNames = append(cloneFlowNames, cloneFlowName(cfg.FlowJobName, s.SnapshotFlowID,
+					cloneTableName(cfg, mapping.DestinationTableIdentifier, destination)))
+			}
+		}
+	}
+	for _, resync := range s.TableResyncs {
+		cloneFlowNames = append(cloneFlowNames, cloneFlowName(cfg.FlowJobName, resync.WorkflowID,
+			resync.DestinationTableIdentifier+resyncTableSuffix))
+	}
+	return cloneFlowNames
+}
+
+// TableResync tracks a table being resynced inside a running CDC mirror.
+type TableResync struct {
+	SourceTableIdentifier      string
+	DestinationTableIdentifier string
+	// changes synced in batches after this one may not be in the snapshot, they are replayed after the swap
+	StartSyncBatchID int64
+	// ID of the resync table workflow, which outlives runs of the CDC flow that continue as new
+	WorkflowID string
 }
 
 type SignalProps struct {
@@ -145,6 +188,184 @@ func (w *CDCFlowWorkflowExecution) receiveAndHandleSignalAsync(ctx workflow.Cont
 	}
 }
 
+// startTableResync starts resyncing a table into a new destination table, in a child workflow.
+// The child signals the CDC flow by ID once it completes, as the run that started it may have continued as new.
+func (w *CDCFlowWorkflowExecution) startTableResync(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+	sourceTable string,
+) error {
+	var mapping *protos.TableMapping
+	for _, tableMapping := range cfg.TableMappings {
+		if tableMapping.SourceTableIdentifier == sourceTable {
+			mapping = tableMapping
+			break
+		}
+	}
+	if mapping == nil {
+		return fmt.Errorf("table %s is not part of the mirror", sourceTable)
+	}
+	if len(cfg.FanOutDestinations) > 0 {
+		return fmt.Errorf("table %s can't be resynced, the mirror fans out to other destinations", sourceTable)
+	}
+	if cfg.SourceColName != "" {
+		return fmt.Errorf("table %s can't be resynced, its destination table is shared with other sources", sourceTable)
+	}
+	if cfg.HistoryMode {
+		return fmt.Errorf("table %s can't be resynced, the mirror keeps the history of its rows", sourceTable)
+	}
+
+	lastSyncBatchIDCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	var lastSyncBatchID int64
+	lastSyncBatchIDFuture := workflow.ExecuteActivity(lastSyncBatchIDCtx, flowable.GetLastSyncBatchID, cfg)
+	if err := lastSyncBatchIDFuture.Get(lastSyncBatchIDCtx, &lastSyncBatchID); err != nil {
+		return fmt.Errorf("failed to get last sync batch ID: %w", err)
+	}
+
+	resyncFlowID, err := GetChildWorkflowID(ctx, "resync-table-flow", cfg.FlowJobName)
+	if err != nil {
+		return err
+	}
+	// abandoned rather than cancelled when this run closes, resyncs are cancelled when the mirror shuts down
+	resyncCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:        resyncFlowID,
+		ParentClosePolicy: enums.PARENT_CLOSE_POLICY_ABANDON,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: resyncTableMaxAttempts,
+		},
+		SearchAttributes: map[string]interface{}{
+			shared.MirrorNameSearchAttribute: cfg.FlowJobName,
+		},
+		WaitForCancellation: true,
+	})
+
+	future := workflow.ExecuteChildWorkflow(resyncCtx, ResyncTableWorkflow, cfg, mapping)
+	if err := future.GetChildWorkflowExecution().Get(resyncCtx, nil); err != nil {
+		return fmt.Errorf("failed to start resync of table %s: %w", sourceTable, err)
+	}
+
+	if state.TableResyncs == nil {
+		state.TableResyncs = make(map[string]*TableResync)
+	}
+	state.TableResyncs[sourceTable] = &TableResync{
+		SourceTableIdentifier:      sourceTable,
+		DestinationTableIdentifier: mapping.DestinationTableIdentifier,
+		StartSyncBatchID:           lastSyncBatchID,
+		WorkflowID:                 resyncFlowID,
+	}
+	w.logger.Info("resyncing table ", sourceTable, " after batch ", lastSyncBatchID)
+	return nil
+}
+
+// finishTableResyncs swaps in the tables whose resync workflows signalled that they completed.
+func (w *CDCFlowWorkflowExecution) finishTableResyncs(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+	resyncDoneChan workflow.ReceiveChannel,
+) {
+	var result TableResyncResult
+	for resyncDoneChan.ReceiveAsync(&result) {
+		resync, ok := state.TableResyncs[result.SourceTableIdentifier]
+		if !ok || resync.WorkflowID != result.WorkflowID {
+			w.logger.Warn("ignoring completion of resync that is not in progress: ", result.WorkflowID)
+			continue
+		}
+
+		var err error
+		if result.Error != "" {
+			err = fmt.Errorf("failed to resync table %s: %s", result.SourceTableIdentifier, result.Error)
+		} else {
+			err = w.finishTableResync(ctx, cfg, resync)
+		}
+		if err != nil {
+			w.logger.Error("failed to resync table: ", err)
+			state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+		}
+		delete(state.TableResyncs, result.SourceTableIdentifier)
+	}
+}
+
+// cancelTableResyncs cancels the resyncs in progress, which are not swapped in once the mirror stops.
+func (w *CDCFlowWorkflowExecution) cancelTableResyncs(ctx workflow.Context, state *CDCFlowWorkflowState) {
+	// the mirror may be stopping because it was cancelled
+	ctx, cancel := workflow.NewDisconnectedContext(ctx)
+	defer cancel()
+
+	// iterated in order so that replays of the workflow are deterministic
+	resyncTables := maps.Keys(state.TableResyncs)
+	sort.Strings(resyncTables)
+	for _, sourceTable := range resyncTables {
+		resync := state.TableResyncs[sourceTable]
+		err := workflow.RequestCancelExternalWorkflow(ctx, resync.WorkflowID, "").Get(ctx, nil)
+		if err != nil {
+			w.logger.Warn("failed to cancel resync of table ", sourceTable, ": ", err)
+		}
+	}
+	state.TableResyncs = nil
+}
+
+// finishTableResync swaps the resynced table in, and replays the changes synced since the resync started.
+func (w *CDCFlowWorkflowExecution) finishTableResync(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	resync *TableResync,
+) error {
+	dstName := resync.DestinationTableIdentifier
+	renameOpts := &protos.RenameTablesInput{
+		FlowJobName:     cfg.FlowJobName,
+		Peer:            cfg.Destination,
+		SyncedAtColName: &cfg.SyncedAtColName,
+		RenameTableOptions: []*protos.RenameTableOption{{
+			CurrentName: dstName + resyncTableSuffix,
+			NewName:     dstName,
+			TableSchema: cfg.TableNameSchemaMapping[dstName],
+		}},
+	}
+	if cfg.SoftDelete {
+		renameOpts.SoftDeleteColName = &cfg.SoftDeleteColName
+	}
+
+	resyncCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 12 * time.Hour,
+		HeartbeatTimeout:    1 * time.Hour,
+	})
+	renameTablesFuture := workflow.ExecuteActivity(resyncCtx, flowable.RenameTables, renameOpts)
+	if err := renameTablesFuture.Get(resyncCtx, nil); err != nil {
+		return fmt.Errorf("failed to swap in resynced table %s: %w", dstName, err)
+	}
+
+	replayFuture := workflow.ExecuteActivity(resyncCtx, flowable.ReplayNormalizedChanges,
+		cfg, []string{dstName}, resync.StartSyncBatchID)
+	if err := replayFuture.Get(resyncCtx, nil); err != nil {
+		return fmt.Errorf("failed to replay changes to resynced table %s: %w", dstName, err)
+	}
+
+	w.logger.Info("finished resyncing table ", resync.SourceTableIdentifier)
+	return nil
+}
+
+// syncSequences syncs the sequences of the mirrored tables to the destination, errors are recorded in the state.
+func (w *CDCFlowWorkflowExecution) syncSequences(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+) {
+	syncSequencesCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	syncSequencesFuture := workflow.ExecuteActivity(syncSequencesCtx, flowable.SyncSequences, cfg)
+	if err := syncSequencesFuture.Get(syncSequencesCtx, nil); err != nil {
+		w.logger.Error("failed to sync sequences: ", err)
+		state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+		return
+	}
+	state.LastSequenceSyncTime = workflow.Now(ctx)
+}
+
 func CDCFlowWorkflowWithConfig(
 	ctx workflow.Context,
 	cfg *protos.FlowConnectionConfigs,
@@ -177,6 +398,12 @@ func CDCFlowWorkflowWithConfig(
 	if err != nil {
 		return state, fmt.Errorf("failed to set `%s` query handler: %w", shared.FlowStatusQuery, err)
 	}
+	err = workflow.SetQueryHandler(ctx, shared.PendingSchemaDeltasQuery, func() ([]*protos.TableSchemaDelta, error) {
+		return state.PendingSchemaDeltas, nil
+	})
+	if err != nil {
+		return state, fmt.Errorf("failed to set `%s` query handler: %w", shared.PendingSchemaDeltasQuery, err)
+	}
 	err = workflow.SetUpdateHandler(ctx, shared.FlowStatusUpdate, func(status protos.FlowStatus) error {
 		state.CurrentFlowState = status
 		return nil
@@ -231,6 +458,7 @@ func CDCFlowWorkflowWithConfig(
 		if err != nil {
 			return state, err
 		}
+		state.SnapshotFlowID = snapshotFlowID
 
 		taskQueue, err := shared.GetPeerFlowTaskQueueName(shared.SnapshotFlowTaskQueueID)
 		if err != nil {
@@ -256,7 +484,6 @@ func CDCFlowWorkflowWithConfig(
 		if cfg.Resync {
 			renameOpts := &protos.RenameTablesInput{}
 			renameOpts.FlowJobName = cfg.FlowJobName
-			renameOpts.Peer = cfg.Destination
 			if cfg.SoftDelete {
 				renameOpts.SoftDeleteColName = &cfg.SoftDeleteColName
 			}
@@ -281,9 +508,27 @@ func CDCFlowWorkflowWithConfig(
 				StartToCloseTimeout: 12 * time.Hour,
 				HeartbeatTimeout:    1 * time.Hour,
 			})
-			renameTablesFuture := workflow.ExecuteActivity(renameTablesCtx, flowable.RenameTables, renameOpts)
-			if err := renameTablesFuture.Get(renameTablesCtx, nil); err != nil {
-				return state, fmt.Errorf("failed to execute rename tables activity: %w", err)
+			for _, destination := range destinationPeers(ctx, cfg) {
+				renameOpts.Peer = destination
+				renameTablesFuture := workflow.ExecuteActivity(renameTablesCtx, flowable.RenameTables, renameOpts)
+				if err := renameTablesFuture.Get(renameTablesCtx, nil); err != nil {
+					return state, fmt.Errorf("failed to execute rename tables activity: %w", err)
+				}
+			}
+		}
+
+		// indexes left out by the setup flow are created once the tables have been loaded
+		if cfg.ReplicateTableMetadata && cfg.DeferIndexCreation {
+			replicateIndexesCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+				StartToCloseTimeout: 12 * time.Hour,
+				HeartbeatTimeout:    1 * time.Hour,
+			})
+			for _, destinationCfg := range destinationConfigs(ctx, cfg) {
+				replicateIndexesFuture := workflow.ExecuteActivity(replicateIndexesCtx,
+					flowable.ReplicateTableMetadata, destinationCfg, true)
+				if err := replicateIndexesFuture.Get(replicateIndexesCtx, nil); err != nil {
+					return state, fmt.Errorf("failed to create indexes on destination tables: %w", err)
+				}
 			}
 		}
 
@@ -294,6 +539,21 @@ func CDCFlowWorkflowWithConfig(
 		if cfg.InitialCopyOnly {
 			return nil, nil
 		}
+	} else if workflow.GetVersion(ctx, "migrate-raw-table", workflow.DefaultVersion, 1) > workflow.DefaultVersion {
+		// raw tables are only created by the setup flow, running mirrors migrate theirs when a run starts
+		migrateRawTableCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: 5 * time.Minute,
+		})
+		for _, destination := range destinationPeers(ctx, cfg) {
+			migrateRawTableFuture := workflow.ExecuteActivity(migrateRawTableCtx, flowable.MigrateRawTable,
+				&protos.CreateRawTableInput{
+					PeerConnectionConfig: destination,
+					FlowJobName:          cfg.FlowJobName,
+				})
+			if err := migrateRawTableFuture.Get(migrateRawTableCtx, nil); err != nil {
+				return state, fmt.Errorf("failed to migrate raw table: %w", err)
+			}
+		}
 	}
 
 	syncFlowOptions := &protos.SyncFlowOptions{
@@ -329,11 +589,45 @@ func CDCFlowWorkflowWithConfig(
 	currentSyncFlowNum := 0
 	totalRecordsSynced := 0
 
+	resyncSignalChan := workflow.GetSignalChannel(ctx, shared.ResyncTableSignalName)
+	resyncDoneChan := workflow.GetSignalChannel(ctx, shared.ResyncTableDoneSignalName)
+	// runs started before tables could be resynced replay without starting resyncs
+	tableResyncsVersioned := workflow.GetVersion(ctx, "table-resync", workflow.DefaultVersion, 1) >
+		workflow.DefaultVersion
+	schemaChangeSignalChan := workflow.GetSignalChannel(ctx, shared.SchemaChangeSignalName)
+	// runs started before the schema change policy replay with schema changes applied by the sync flow
+	schemaChangePolicyVersioned := workflow.GetVersion(ctx, "schema-change-policy", workflow.DefaultVersion, 1) >
+		workflow.DefaultVersion
+
 	for {
 		// check and act on signals before a fresh flow starts.
 		w.receiveAndHandleSignalAsync(ctx, state)
 
+		// schema changes are only decided on while they are pending, later decisions are stale
+		var schemaChangeAccepted bool
+		for schemaChangeSignalChan.ReceiveAsync(&schemaChangeAccepted) {
+			w.logger.Warn("no schema changes are pending, ignoring decision on schema changes")
+		}
+
+		var resyncTable string
+		for resyncSignalChan.ReceiveAsync(&resyncTable) {
+			if !tableResyncsVersioned {
+				w.logger.Warn("table resyncs are not supported until the mirror continues as new: ", resyncTable)
+				continue
+			}
+			if _, ok := state.TableResyncs[resyncTable]; ok {
+				w.logger.Warn("table is already being resynced: ", resyncTable)
+				continue
+			}
+			if err := w.startTableResync(ctx, cfg, state, resyncTable); err != nil {
+				w.logger.Error("failed to start resync of table: ", err)
+				state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
+			}
+		}
+		w.finishTableResyncs(ctx, cfg, state, resyncDoneChan)
+
 		if err := ctx.Err(); err != nil {
+			w.cancelTableResyncs(ctx, state)
 			return nil, err
 		}
 
@@ -343,6 +637,12 @@ func CDCFlowWorkflowWithConfig(
 			signalChan := workflow.GetSignalChannel(ctx, shared.CDCFlowSignalName)
 			var signalVal shared.CDCFlowSignal
 
+			// writes move to the destination while the mirror is paused for cutover,
+			// so sequences are synced once more after the last changes have been normalized
+			if cfg.SyncSequences {
+				w.syncSequences(ctx, cfg, state)
+			}
+
 			for state.ActiveSignal == shared.PauseSignal {
 				w.logger.Info("mirror has been paused for ", time.Since(startTime))
 				// only place we block on receive, so signal processing is immediate
@@ -350,6 +650,7 @@ func CDCFlowWorkflowWithConfig(
 				if ok {
 					state.ActiveSignal = shared.FlowSignalHandler(state.ActiveSignal, signalVal, w.logger)
 				} else if err := ctx.Err(); err != nil {
+					w.cancelTableResyncs(ctx, state)
 					return nil, err
 				}
 			}
@@ -360,6 +661,7 @@ func CDCFlowWorkflowWithConfig(
 		// check if the peer flow has been shutdown
 		if state.ActiveSignal == shared.ShutdownSignal {
 			w.logger.Info("peer flow has been shutdown")
+			w.cancelTableResyncs(ctx, state)
 			state.CurrentFlowState = protos.FlowStatus_STATUS_TERMINATED
 			return state, nil
 		}
@@ -369,7 +671,7 @@ func CDCFlowWorkflowWithConfig(
 		// check if total sync flows have been completed
 		// since this happens immediately after we check for signals, the case of a signal being missed
 		// due to a new workflow starting is vanishingly low, but possible
-		if limits.TotalSyncFlows != 0 && currentSyncFlowNum == limits.TotalSyncFlows {
+		if limits.TotalSyncFlows != 0 && currentSyncFlowNum >= limits.TotalSyncFlows {
 			w.logger.Info("All the syncflows have completed successfully, there was a"+
 				" limit on the number of syncflows to be executed: ", limits.TotalSyncFlows)
 			break
@@ -379,6 +681,7 @@ func CDCFlowWorkflowWithConfig(
 		// check if total records synced have been completed
 		if totalRecordsSynced == limits.ExitAfterRecords {
 			w.logger.Warn("All the records have been synced successfully, so ending the flow")
+			w.cancelTableResyncs(ctx, state)
 			break
 		}
 
@@ -421,8 +724,21 @@ func CDCFlowWorkflowWithConfig(
 		w.logger.Info("Total records synced: ", totalRecordsSynced)
 
 		var tableSchemaDeltas []*protos.TableSchemaDelta = nil
-		if childSyncFlowRes != nil {
-			tableSchemaDeltas = childSyncFlowRes.TableSchemaDeltas
+		if !schemaChangePolicyVersioned {
+			if childSyncFlowRes != nil {
+				tableSchemaDeltas = childSyncFlowRes.TableSchemaDeltas
+			}
+		} else if childSyncFlowRes != nil && len(childSyncFlowRes.TableSchemaDeltas) > 0 {
+			tableSchemaDeltas, err = w.applySchemaChangePolicy(ctx, cfg, state, childSyncFlowRes.TableSchemaDeltas)
+			if err != nil {
+				return state, err
+			}
+			if state.ActiveSignal == shared.ShutdownSignal {
+				w.logger.Info("peer flow has been shutdown")
+				w.cancelTableResyncs(ctx, state)
+				state.CurrentFlowState = protos.FlowStatus_STATUS_TERMINATED
+				return state, nil
+			}
 		}
 
 		// slightly hacky: table schema mapping is cached, so we need to manually update it if schema changes.
@@ -451,7 +767,11 @@ func CDCFlowWorkflowWithConfig(
 				state.SyncFlowErrors = append(state.SyncFlowErrors, err.Error())
 			} else {
 				for i := range modifiedSrcTables {
-					cfg.TableNameSchemaMapping[modifiedDstTables[i]] = getModifiedSchemaRes.TableNameSchemaMapping[modifiedSrcTables[i]]
+					tableSchema := getModifiedSchemaRes.TableNameSchemaMapping[modifiedSrcTables[i]]
+					if cfg.SourceColName != "" {
+						tableSchema = utils.AddSourceColumn(tableSchema, cfg.SourceColName)
+					}
+					cfg.TableNameSchemaMapping[modifiedDstTables[i]] = tableSchema
 				}
 			}
 		}
@@ -484,9 +804,114 @@ func CDCFlowWorkflowWithConfig(
 		} else {
 			state.NormalizeFlowStatuses = append(state.NormalizeFlowStatuses, childNormalizeFlowRes)
 		}
+
+		if cfg.SyncSequences && workflow.Now(ctx).Sub(state.LastSequenceSyncTime) >= sequenceSyncInterval {
+			w.syncSequences(ctx, cfg, state)
+		}
 		cdcPropertiesSelector.Select(ctx)
 	}
 
+	// signals left in channels are lost when continuing as new, resyncs still in progress are carried over
+	w.finishTableResyncs(ctx, cfg, state, resyncDoneChan)
 	state.TruncateProgress(w.logger)
 	return nil, workflow.NewContinueAsNewError(ctx, CDCFlowWorkflowWithConfig, cfg, limits, state)
 }
+
+// applySchemaChangePolicy decides what is done with the schema changes detected by a sync flow,
+// according to the schema change policy of the mirror. It returns the changes applied to the destination,
+// which the cached table schemas are updated with.
+func (w *CDCFlowWorkflowExecution) applySchemaChangePolicy(
+	ctx workflow.Context,
+	cfg *protos.FlowConnectionConfigs,
+	state *CDCFlowWorkflowState,
+	tableSchemaDeltas []*protos.TableSchemaDelta,
+) ([]*protos.TableSchemaDelta, error) {
+	alertCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+
+	switch cfg.SchemaChangePolicy {
+	case protos.SchemaChangePolicy_SCHEMA_CHANGE_POLICY_APPLY:
+		// already replayed on the destination by the sync flow
+		return tableSchemaDeltas, nil
+	case protos.SchemaChangePolicy_SCHEMA_CHANGE_POLICY_IGNORE:
+		w.logger.Info("ignoring schema changes: ", tableSchemaDeltas)
+		return nil, nil
+	case protos.SchemaChangePolicy_SCHEMA_CHANGE_POLICY_FAIL:
+		alertFuture := workflow.ExecuteActivity(alertCtx, flowable.AlertSchemaChange, cfg, tableSchemaDeltas)
+		if err := alertFuture.Get(alertCtx, nil); err != nil {
+			w.logger.Error("failed to alert on schema changes: ", err)
+		}
+		return nil, fmt.Errorf("schema changes detected on source tables of mirror %s", cfg.FlowJobName)
+	}
+
+	state.PendingSchemaDeltas = tableSchemaDeltas
+	state.CurrentFlowState = protos.FlowStatus_STATUS_PAUSED
+	alertFuture := workflow.ExecuteActivity(alertCtx, flowable.AlertSchemaChange, cfg, tableSchemaDeltas)
+	if err := alertFuture.Get(alertCtx, nil); err != nil {
+		w.logger.Error("failed to alert on schema changes: ", err)
+	}
+
+	var accepted bool
+	decided := false
+	selector := workflow.NewSelector(ctx)
+	selector.AddReceive(workflow.GetSignalChannel(ctx, shared.SchemaChangeSignalName),
+		func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, &accepted)
+			decided = true
+		})
+	selector.AddReceive(workflow.GetSignalChannel(ctx, shared.CDCFlowSignalName),
+		func(c workflow.ReceiveChannel, more bool) {
+			var signalVal shared.CDCFlowSignal
+			c.Receive(ctx, &signalVal)
+			state.ActiveSignal = shared.FlowSignalHandler(state.ActiveSignal, signalVal, w.logger)
+		})
+	selector.AddReceive(ctx.Done(), func(_ workflow.ReceiveChannel, _ bool) {})
+
+	w.logger.Info("mirror has been paused until schema changes are accepted or rejected")
+	for !decided && state.ActiveSignal != shared.ShutdownSignal {
+		selector.Select(ctx)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	state.PendingSchemaDeltas = nil
+	state.CurrentFlowState = protos.FlowStatus_STATUS_RUNNING
+	if !accepted {
+		w.logger.Info("schema changes have been rejected")
+		return nil, nil
+	}
+
+	replayTableSchemaDeltaCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Minute,
+		WaitForCancellation: true,
+	})
+	replayTableSchemaDeltaFuture := workflow.ExecuteActivity(replayTableSchemaDeltaCtx,
+		flowable.ReplayTableSchemaDeltas, &protos.ReplayTableSchemaDeltaInput{
+			FlowConnectionConfigs: cfg,
+			TableSchemaDeltas:     tableSchemaDeltas,
+		})
+	if err := replayTableSchemaDeltaFuture.Get(replayTableSchemaDeltaCtx, nil); err != nil {
+		return nil, fmt.Errorf("failed to replay schema delta: %w", err)
+	}
+	w.logger.Info("schema changes have been accepted")
+	return tableSchemaDeltas, nil
+}
+
+// destinationPeers returns the destination of a mirror followed by its fan-out destinations.
+// Runs started before mirrors could fan out replay with the destination of the mirror only.
+func destinationPeers(ctx workflow.Context, cfg *protos.FlowConnectionConfigs) []*protos.Peer {
+	if workflow.GetVersion(ctx, "fan-out-destinations", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return []*protos.Peer{cfg.Destination}
+	}
+	return append([]*protos.Peer{cfg.Destination}, cfg.FanOutDestinations...)
+}
+
+// destinationConfigs returns the config of a mirror for each destination it syncs to,
+// versioned like destinationPeers.
+func destinationConfigs(ctx workflow.Context, cfg *protos.FlowConnectionConfigs) []*protos.FlowConnectionConfigs {
+	if workflow.GetVersion(ctx, "fan-out-destinations", workflow.DefaultVersion, 1) == workflow.DefaultVersion {
+		return []*protos.FlowConnectionConfigs{cfg}
+	}
+	return utils.DestinationConfigs(cfg)
+}
@@ -0,0 +1,187 @@
+package peerflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func newCDCFlowTestEnv(t *testing.T) *testsuite.TestWorkflowEnvironment {
+	t.Helper()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SyncFlowWorkflow)
+	env.RegisterWorkflow(NormalizeFlowWorkflow)
+	env.RegisterWorkflow(ResyncTableWorkflow)
+	env.RegisterActivity(flowable)
+	env.OnWorkflow(SyncFlowWorkflow, mock.Anything, mock.Anything, mock.Anything).
+		Return(&model.SyncResponse{}, nil).Maybe()
+	env.OnWorkflow(NormalizeFlowWorkflow, mock.Anything, mock.Anything).
+		Return(&model.NormalizeResponse{}, nil).Maybe()
+	env.OnActivity(flowable.MigrateRawTable, mock.Anything, mock.Anything).Return(nil).Maybe()
+	return env
+}
+
+func runningCDCFlowState() *CDCFlowWorkflowState {
+	state := NewCDCFlowWorkflowState()
+	state.CurrentFlowState = protos.FlowStatus_STATUS_RUNNING
+	return state
+}
+
+func testCDCFlowConfig() *protos.FlowConnectionConfigs {
+	return &protos.FlowConnectionConfigs{
+		FlowJobName: "cdc_flow_test",
+		Source:      &protos.Peer{Name: "source", Type: protos.DBType_POSTGRES},
+		Destination: &protos.Peer{Name: "destination", Type: protos.DBType_SNOWFLAKE},
+		TableMappings: []*protos.TableMapping{{
+			SourceTableIdentifier:      "public.users",
+			DestinationTableIdentifier: "public.users",
+		}},
+		TableNameSchemaMapping: map[string]*protos.TableSchema{
+			"public.users": {TableIdentifier: "public.users", PrimaryKeyColumns: []string{"id"}},
+		},
+	}
+}
+
+// continuedAsNewState returns the state a CDC flow continued as new with.
+func continuedAsNewState(t *testing.T, env *testsuite.TestWorkflowEnvironment) *CDCFlowWorkflowState {
+	t.Helper()
+
+	var continueAsNewErr *workflow.ContinueAsNewError
+	require.True(t, errors.As(env.GetWorkflowError(), &continueAsNewErr))
+	var cfg *protos.FlowConnectionConfigs
+	var limits *CDCFlowLimits
+	var state *CDCFlowWorkflowState
+	err := converter.GetDefaultDataConverter().FromPayloads(continueAsNewErr.Input, &cfg, &limits, &state)
+	require.NoError(t, err)
+	return state
+}
+
+func TestCDCFlowMigratesRawTableOfRunningMirror(t *testing.T) {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(SyncFlowWorkflow)
+	env.RegisterWorkflow(NormalizeFlowWorkflow)
+	env.RegisterActivity(flowable)
+	env.OnWorkflow(SyncFlowWorkflow, mock.Anything, mock.Anything, mock.Anything).
+		Return(&model.SyncResponse{}, nil).Maybe()
+	env.OnWorkflow(NormalizeFlowWorkflow, mock.Anything, mock.Anything).
+		Return(&model.NormalizeResponse{}, nil).Maybe()
+	env.OnActivity(flowable.MigrateRawTable, mock.Anything, mock.MatchedBy(func(req *protos.CreateRawTableInput) bool {
+		return req.FlowJobName == "cdc_flow_test" && req.PeerConnectionConfig.Name == "destination"
+	})).Return(nil).Once()
+
+	env.ExecuteWorkflow(CDCFlowWorkflowWithConfig, testCDCFlowConfig(),
+		&CDCFlowLimits{TotalSyncFlows: 1, ExitAfterRecords: -1}, runningCDCFlowState())
+
+	require.True(t, env.IsWorkflowCompleted())
+	continuedAsNewState(t, env)
+	env.AssertExpectations(t)
+}
+
+func TestCDCFlowContinuesAsNewDuringTableResync(t *testing.T) {
+	env := newCDCFlowTestEnv(t)
+	env.OnActivity(flowable.GetLastSyncBatchID, mock.Anything, mock.Anything).Return(int64(7), nil)
+	// the resync does not complete within this run
+	env.OnWorkflow(ResyncTableWorkflow, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(shared.ResyncTableSignalName, "public.users")
+	}, 0)
+
+	env.ExecuteWorkflow(CDCFlowWorkflowWithConfig, testCDCFlowConfig(),
+		&CDCFlowLimits{TotalSyncFlows: 2, ExitAfterRecords: -1}, runningCDCFlowState())
+
+	require.True(t, env.IsWorkflowCompleted())
+	state := continuedAsNewState(t, env)
+	require.Len(t, state.TableResyncs, 1)
+	resync := state.TableResyncs["public.users"]
+	require.Equal(t, "public.users", resync.DestinationTableIdentifier)
+	require.Equal(t, int64(7), resync.StartSyncBatchID)
+	require.NotEmpty(t, resync.WorkflowID)
+}
+
+func TestCDCFlowRejectsTableResyncInHistoryMode(t *testing.T) {
+	env := newCDCFlowTestEnv(t)
+	env.OnActivity(flowable.GetLastSyncBatchID, mock.Anything, mock.Anything).Return(int64(7), nil).Maybe()
+	env.OnWorkflow(ResyncTableWorkflow, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(shared.ResyncTableSignalName, "public.users")
+	}, 0)
+
+	cfg := testCDCFlowConfig()
+	cfg.HistoryMode = true
+	env.ExecuteWorkflow(CDCFlowWorkflowWithConfig, cfg,
+		&CDCFlowLimits{TotalSyncFlows: 2, ExitAfterRecords: -1}, runningCDCFlowState())
+
+	require.True(t, env.IsWorkflowCompleted())
+	state := continuedAsNewState(t, env)
+	require.Empty(t, state.TableResyncs)
+}
+
+func TestCDCFlowFinishesTableResyncOfPreviousRun(t *testing.T) {
+	env := newCDCFlowTestEnv(t)
+	env.OnActivity(flowable.RenameTables, mock.Anything, mock.MatchedBy(func(req *protos.RenameTablesInput) bool {
+		return req.RenameTableOptions[0].CurrentName == "public.users_resync" &&
+			req.RenameTableOptions[0].NewName == "public.users"
+	})).Return(&protos.RenameTablesOutput{}, nil).Once()
+	env.OnActivity(flowable.ReplayNormalizedChanges, mock.Anything, mock.Anything, []string{"public.users"}, int64(7)).
+		Return(nil).Once()
+	env.RegisterDelayedCallback(func() {
+		// completions of resyncs that were cancelled or restarted are ignored
+		env.SignalWorkflow(shared.ResyncTableDoneSignalName, TableResyncResult{
+			SourceTableIdentifier: "public.users",
+			WorkflowID:            "resync-table-flow-stale",
+		})
+		env.SignalWorkflow(shared.ResyncTableDoneSignalName, TableResyncResult{
+			SourceTableIdentifier: "public.users",
+			WorkflowID:            "resync-table-flow-previous-run",
+		})
+	}, 0)
+
+	state := runningCDCFlowState()
+	state.TableResyncs = map[string]*TableResync{"public.users": {
+		SourceTableIdentifier:      "public.users",
+		DestinationTableIdentifier: "public.users",
+		StartSyncBatchID:           7,
+		WorkflowID:                 "resync-table-flow-previous-run",
+	}}
+	env.ExecuteWorkflow(CDCFlowWorkflowWithConfig, testCDCFlowConfig(),
+		&CDCFlowLimits{TotalSyncFlows: 2, ExitAfterRecords: -1}, state)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Empty(t, continuedAsNewState(t, env).TableResyncs)
+	env.AssertExpectations(t)
+}
+
+func TestCDCFlowCancelsTableResyncsOnShutdown(t *testing.T) {
+	env := newCDCFlowTestEnv(t)
+	env.OnRequestCancelExternalWorkflow(mock.Anything, "resync-table-flow-previous-run", "").Return(nil).Once()
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(shared.CDCFlowSignalName, shared.ShutdownSignal)
+	}, 0)
+
+	state := runningCDCFlowState()
+	state.TableResyncs = map[string]*TableResync{"public.users": {
+		SourceTableIdentifier:      "public.users",
+		DestinationTableIdentifier: "public.users",
+		WorkflowID:                 "resync-table-flow-previous-run",
+	}}
+	env.ExecuteWorkflow(CDCFlowWorkflowWithConfig, testCDCFlowConfig(), &CDCFlowLimits{ExitAfterRecords: -1}, state)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var result *CDCFlowWorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, protos.FlowStatus_STATUS_TERMINATED, result.CurrentFlowState)
+	require.Empty(t, result.TableResyncs)
+	env.AssertExpectations(t)
+}
@@ -51,14 +51,26 @@ func (s *NormalizeFlowExecution) executeNormalizeFlow(
 		HeartbeatTimeout:    5 * time.Minute,
 	})
 
-	startNormalizeInput := &protos.StartNormalizeInput{
-		FlowConnectionConfigs: config,
+	// each destination is normalized on its own, the response is the one of the destination of the mirror
+	destinationConfigs := destinationConfigs(ctx, config)
+	normalizeFutures := make([]workflow.Future, 0, len(destinationConfigs))
+	for _, destinationConfig := range destinationConfigs {
+		startNormalizeInput := &protos.StartNormalizeInput{
+			FlowConnectionConfigs: destinationConfig,
+		}
+		normalizeFutures = append(normalizeFutures,
+			workflow.ExecuteActivity(normalizeFlowCtx, flowable.StartNormalize, startNormalizeInput))
 	}
-	fStartNormalize := workflow.ExecuteActivity(normalizeFlowCtx, flowable.StartNormalize, startNormalizeInput)
 
 	var normalizeResponse *model.NormalizeResponse
-	if err := fStartNormalize.Get(normalizeFlowCtx, &normalizeResponse); err != nil {
-		return nil, fmt.Errorf("failed to flow: %w", err)
+	for i, fStartNormalize := range normalizeFutures {
+		var res *model.NormalizeResponse
+		if err := fStartNormalize.Get(normalizeFlowCtx, &res); err != nil {
+			return nil, fmt.Errorf("failed to flow: %w", err)
+		}
+		if i == 0 {
+			normalizeResponse = res
+		}
 	}
 
 	return normalizeResponse, nil
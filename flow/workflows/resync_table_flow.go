@@ -0,0 +1,124 @@
+package peerflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/concurrency"
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/shared"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	// resyncTableSuffix is appended to the destination table a table is resynced into, before it is swapped in.
+	resyncTableSuffix = "_resync"
+	// attempts of a resync table workflow, it only reports failure to the CDC flow on the last one
+	resyncTableMaxAttempts = 20
+)
+
+// TableResyncResult is signalled to the CDC flow by a resync table workflow once it completes.
+type TableResyncResult struct {
+	SourceTableIdentifier string
+	WorkflowID            string
+	// empty if the table was resynced
+	Error string
+}
+
+// ResyncTableWorkflow snapshots a single table of a CDC mirror into a new destination table,
+// while the mirror keeps syncing the changes of the table into its raw table.
+// The CDC flow swaps the new table in and replays the changes once this signals it.
+func ResyncTableWorkflow(
+	ctx workflow.Context,
+	config *protos.FlowConnectionConfigs,
+	mapping *protos.TableMapping,
+) error {
+	logger := workflow.GetLogger(ctx)
+	info := workflow.GetInfo(ctx)
+
+	err := resyncTable(ctx, config, mapping)
+	if err != nil && (temporal.IsCanceledError(err) || info.Attempt < resyncTableMaxAttempts) {
+		return err
+	}
+
+	// signalled by ID, as the CDC flow may have continued as new since it started the resync
+	if info.ParentWorkflowExecution != nil {
+		result := TableResyncResult{
+			SourceTableIdentifier: mapping.SourceTableIdentifier,
+			WorkflowID:            info.WorkflowExecution.ID,
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		signalErr := workflow.SignalExternalWorkflow(ctx, info.ParentWorkflowExecution.ID, "",
+			shared.ResyncTableDoneSignalName, result).Get(ctx, nil)
+		if signalErr != nil {
+			logger.Error("failed to signal completion of resync to CDC flow", "error", signalErr)
+		}
+	}
+	return err
+}
+
+// resyncTable creates the new destination table and clones the source table into it.
+func resyncTable(
+	ctx workflow.Context,
+	config *protos.FlowConnectionConfigs,
+	mapping *protos.TableMapping,
+) error {
+	logger := workflow.GetLogger(ctx)
+
+	resyncTableName := mapping.DestinationTableIdentifier + resyncTableSuffix
+	tableSchema, ok := config.TableNameSchemaMapping[mapping.DestinationTableIdentifier]
+	if !ok {
+		return fmt.Errorf("schema of table %s not found", mapping.DestinationTableIdentifier)
+	}
+
+	setupConfig := &protos.SetupNormalizedTableBatchInput{
+		PeerConnectionConfig:       config.Destination,
+		TableNameSchemaMapping:     map[string]*protos.TableSchema{resyncTableName: tableSchema},
+		SoftDeleteColName:          config.SoftDeleteColName,
+		SyncedAtColName:            config.SyncedAtColName,
+		FlowName:                   config.FlowJobName,
+		BeforeImageColName:         config.BeforeImageColName,
+		HistoryMode:                config.HistoryMode,
+		TransactionMetadata:        config.TransactionMetadata,
+		ConflictResolution:         config.ConflictResolution,
+		SourcePeerConnectionConfig: config.Source,
+	}
+	if mapping.Layout != nil {
+		setupConfig.TableLayoutMapping = map[string]*protos.DestinationTableLayout{resyncTableName: mapping.Layout}
+	}
+
+	setupCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Hour,
+		HeartbeatTimeout:    5 * time.Minute,
+	})
+	future := workflow.ExecuteActivity(setupCtx, flowable.CreateNormalizedTable, setupConfig)
+	if err := future.Get(setupCtx, nil); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", resyncTableName, err)
+	}
+
+	se := &SnapshotFlowExecution{
+		config: config,
+		logger: logger,
+	}
+	resyncMapping := &protos.TableMapping{
+		SourceTableIdentifier:      mapping.SourceTableIdentifier,
+		DestinationTableIdentifier: resyncTableName,
+		PartitionKey:               mapping.PartitionKey,
+		Exclude:                    mapping.Exclude,
+	}
+
+	// changes are replayed from the raw table after the swap, so the table is read without a snapshot
+	boundSelector := concurrency.NewBoundSelector(1, ctx)
+	if err := se.cloneTable(boundSelector, ctx, "", resyncMapping, config.Destination); err != nil {
+		return fmt.Errorf("failed to start clone of table %s: %w", mapping.SourceTableIdentifier, err)
+	}
+	if err := boundSelector.Wait(); err != nil {
+		return fmt.Errorf("failed to clone table %s: %w", mapping.SourceTableIdentifier, err)
+	}
+
+	logger.Info("resynced table", "sourceTable", mapping.SourceTableIdentifier, "destinationTable", resyncTableName)
+	return nil
+}
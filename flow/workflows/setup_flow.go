@@ -9,6 +9,7 @@ import (
 	"github.com/PeerDB-io/peer-flow/activities"
 	"github.com/PeerDB-io/peer-flow/connectors/utils"
 	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/PeerDB-io/peer-flow/model"
 	"golang.org/x/exp/maps"
 
 	"go.temporal.io/sdk/log"
@@ -74,28 +75,31 @@ func (s *SetupFlowExecution) checkConnectionsAndSetupMetadataTables(
 		return fmt.Errorf("failed to check source peer connection: %w", err)
 	}
 
-	dstSetupInput := &protos.SetupInput{
-		Peer:     config.Destination,
-		FlowName: config.FlowJobName,
-	}
+	// fan-out destinations are set up like the destination
+	for _, destination := range destinationPeers(ctx, config) {
+		dstSetupInput := &protos.SetupInput{
+			Peer:     destination,
+			FlowName: config.FlowJobName,
+		}
 
-	// then check the destination peer connection
-	destConnStatusFuture := workflow.ExecuteActivity(ctx, flowable.CheckConnection, dstSetupInput)
-	var destConnStatus activities.CheckConnectionResult
-	if err := destConnStatusFuture.Get(ctx, &destConnStatus); err != nil {
-		return fmt.Errorf("failed to check destination peer connection: %w", err)
-	}
+		// then check the destination peer connection
+		destConnStatusFuture := workflow.ExecuteActivity(ctx, flowable.CheckConnection, dstSetupInput)
+		var destConnStatus activities.CheckConnectionResult
+		if err := destConnStatusFuture.Get(ctx, &destConnStatus); err != nil {
+			return fmt.Errorf("failed to check destination peer connection: %w", err)
+		}
 
-	s.logger.Info("ensuring metadata table exists - ", s.CDCFlowName)
+		s.logger.Info("ensuring metadata table exists - ", s.CDCFlowName)
 
-	// then setup the destination peer metadata tables
-	if destConnStatus.NeedsSetupMetadataTables {
-		fDst := workflow.ExecuteActivity(ctx, flowable.SetupMetadataTables, dstSetupInput)
-		if err := fDst.Get(ctx, nil); err != nil {
-			return fmt.Errorf("failed to setup destination peer metadata tables: %w", err)
+		// then setup the destination peer metadata tables
+		if destConnStatus.NeedsSetupMetadataTables {
+			fDst := workflow.ExecuteActivity(ctx, flowable.SetupMetadataTables, dstSetupInput)
+			if err := fDst.Get(ctx, nil); err != nil {
+				return fmt.Errorf("failed to setup destination peer metadata tables: %w", err)
+			}
+		} else {
+			s.logger.Info("destination peer metadata tables already exist")
 		}
-	} else {
-		s.logger.Info("destination peer metadata tables already exist")
 	}
 
 	return nil
@@ -156,16 +160,18 @@ func (s *SetupFlowExecution) createRawTable(
 	})
 
 	// attempt to create the tables.
-	createRawTblInput := &protos.CreateRawTableInput{
-		PeerConnectionConfig: config.Destination,
-		FlowJobName:          s.CDCFlowName,
-		TableNameMapping:     s.tableNameMapping,
-		CdcSyncMode:          config.CdcSyncMode,
-	}
+	for _, destination := range destinationPeers(ctx, config) {
+		createRawTblInput := &protos.CreateRawTableInput{
+			PeerConnectionConfig: destination,
+			FlowJobName:          s.CDCFlowName,
+			TableNameMapping:     s.tableNameMapping,
+			CdcSyncMode:          config.CdcSyncMode,
+		}
 
-	rawTblFuture := workflow.ExecuteActivity(ctx, flowable.CreateRawTable, createRawTblInput)
-	if err := rawTblFuture.Get(ctx, nil); err != nil {
-		return fmt.Errorf("failed to create raw table: %w", err)
+		rawTblFuture := workflow.ExecuteActivity(ctx, flowable.CreateRawTable, createRawTblInput)
+		if err := rawTblFuture.Get(ctx, nil); err != nil {
+			return fmt.Errorf("failed to create raw table: %w", err)
+		}
 	}
 
 	return nil
@@ -216,10 +222,12 @@ func (s *SetupFlowExecution) fetchTableSchemaAndSetupNormalizedTables(
 					columnCount := utils.TableSchemaColumns(tableSchema)
 					columnNames := make([]string, 0, columnCount)
 					columnTypes := make([]string, 0, columnCount)
+					columnTypmods := make([]int32, 0, columnCount)
 					utils.IterColumns(tableSchema, func(columnName, columnType string) {
 						if !slices.Contains(mapping.Exclude, columnName) {
 							columnNames = append(columnNames, columnName)
 							columnTypes = append(columnTypes, columnType)
+							columnTypmods = append(columnTypmods, utils.TableSchemaColumnTypmod(tableSchema, columnName))
 						}
 					})
 					tableSchema = &protos.TableSchema{
@@ -229,30 +237,73 @@ func (s *SetupFlowExecution) fetchTableSchemaAndSetupNormalizedTables(
 						IsReplicaIdentityFull: tableSchema.IsReplicaIdentityFull,
 						ColumnNames:           columnNames,
 						ColumnTypes:           columnTypes,
+						ColumnTypmods:         columnTypmods,
+						PartitionKey:          tableSchema.PartitionKey,
 					}
 				}
 				break
 			}
 		}
+		if flowConnectionConfigs.SourceColName != "" {
+			tableSchema = utils.AddSourceColumn(tableSchema, flowConnectionConfigs.SourceColName)
+		}
 		normalizedTableMapping[normalizedTableName] = tableSchema
 
 		s.logger.Info("normalized table schema: ", normalizedTableName, " -> ", tableSchema)
 	}
 
-	// now setup the normalized tables on the destination peer
-	setupConfig := &protos.SetupNormalizedTableBatchInput{
-		PeerConnectionConfig:   flowConnectionConfigs.Destination,
-		TableNameSchemaMapping: normalizedTableMapping,
-		SoftDeleteColName:      flowConnectionConfigs.SoftDeleteColName,
-		SyncedAtColName:        flowConnectionConfigs.SyncedAtColName,
-		FlowName:               flowConnectionConfigs.FlowJobName,
+	// captured logical decoding messages are written to their own table
+	if len(flowConnectionConfigs.LogicalMessagePrefixes) > 0 && flowConnectionConfigs.LogicalMessageDestination != "" {
+		normalizedTableMapping[flowConnectionConfigs.LogicalMessageDestination] =
+			model.MessageTableSchema(flowConnectionConfigs.LogicalMessageDestination)
+	}
+
+	tableLayoutMapping := make(map[string]*protos.DestinationTableLayout)
+	for _, mapping := range flowConnectionConfigs.TableMappings {
+		if mapping.Layout != nil {
+			tableLayoutMapping[mapping.DestinationTableIdentifier] = mapping.Layout
+		}
 	}
 
-	future = workflow.ExecuteActivity(ctx, flowable.CreateNormalizedTable, setupConfig)
-	var createNormalizedTablesOutput *protos.SetupNormalizedTableBatchOutput
-	if err := future.Get(ctx, &createNormalizedTablesOutput); err != nil {
-		s.logger.Error("failed to create normalized tables: ", err)
-		return nil, fmt.Errorf("failed to create normalized tables: %w", err)
+	// now setup the normalized tables on the destination peer, and on its fan-out destinations
+	for _, destinationConfig := range destinationConfigs(ctx, flowConnectionConfigs) {
+		setupConfig := &protos.SetupNormalizedTableBatchInput{
+			PeerConnectionConfig:       destinationConfig.Destination,
+			TableNameSchemaMapping:     normalizedTableMapping,
+			SoftDeleteColName:          destinationConfig.SoftDeleteColName,
+			SyncedAtColName:            destinationConfig.SyncedAtColName,
+			FlowName:                   destinationConfig.FlowJobName,
+			BeforeImageColName:         destinationConfig.BeforeImageColName,
+			HistoryMode:                destinationConfig.HistoryMode,
+			TransactionMetadata:        destinationConfig.TransactionMetadata,
+			ConflictResolution:         destinationConfig.ConflictResolution,
+			TableLayoutMapping:         tableLayoutMapping,
+			SourcePeerConnectionConfig: flowConnectionConfigs.Source,
+		}
+
+		future = workflow.ExecuteActivity(ctx, flowable.CreateNormalizedTable, setupConfig)
+		var createNormalizedTablesOutput *protos.SetupNormalizedTableBatchOutput
+		if err := future.Get(ctx, &createNormalizedTablesOutput); err != nil {
+			s.logger.Error("failed to create normalized tables: ", err)
+			return nil, fmt.Errorf("failed to create normalized tables: %w", err)
+		}
+
+		// indexes are created after the snapshot when deferred
+		if destinationConfig.ReplicateTableMetadata {
+			future = workflow.ExecuteActivity(ctx, flowable.ReplicateTableMetadata, destinationConfig, false)
+			if err := future.Get(ctx, nil); err != nil {
+				s.logger.Error("failed to replicate table metadata: ", err)
+				return nil, fmt.Errorf("failed to replicate table metadata: %w", err)
+			}
+
+			if !destinationConfig.DeferIndexCreation {
+				future = workflow.ExecuteActivity(ctx, flowable.ReplicateTableMetadata, destinationConfig, true)
+				if err := future.Get(ctx, nil); err != nil {
+					s.logger.Error("failed to create indexes on destination tables: ", err)
+					return nil, fmt.Errorf("failed to create indexes on destination tables: %w", err)
+				}
+			}
+		}
 	}
 
 	s.logger.Info("finished setting up normalized tables for peer flow - ", s.CDCFlowName)
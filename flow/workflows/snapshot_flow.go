@@ -21,6 +21,8 @@ import (
 type SnapshotFlowExecution struct {
 	config *protos.FlowConnectionConfigs
 	logger log.Logger
+	// snapshot flows started before incremental snapshots were added replay without them
+	incrementalSnapshotsVersioned bool
 }
 
 // ensurePullability ensures that the source peer is pullable.
@@ -46,9 +48,10 @@ func (s *SnapshotFlowExecution) setupReplication(
 		PeerConnectionConfig:        s.config.Source,
 		FlowJobName:                 flowName,
 		TableNameMapping:            tblNameMapping,
-		DoInitialCopy:               s.config.DoInitialCopy,
+		DoInitialCopy:               s.config.DoInitialCopy && !s.incrementalSnapshot(),
 		ExistingPublicationName:     s.config.PublicationName,
 		ExistingReplicationSlotName: s.config.ReplicationSlotName,
+		PullFromStandby:             s.config.PullFromStandby,
 	}
 
 	res := &protos.SetupReplicationOutput{}
@@ -62,6 +65,31 @@ func (s *SnapshotFlowExecution) setupReplication(
 	return res, nil
 }
 
+// incrementalSnapshot returns true if the tables are snapshotted incrementally by the sync flows,
+// instead of being cloned from a snapshot exported by the slot. Resyncs always clone, as the
+// resynced tables replace the current ones once the snapshot completes.
+func (s *SnapshotFlowExecution) incrementalSnapshot() bool {
+	return s.incrementalSnapshotsVersioned && s.config.IncrementalSnapshot &&
+		!s.config.InitialCopyOnly && !s.config.Resync
+}
+
+func (s *SnapshotFlowExecution) requestIncrementalSnapshot(
+	ctx workflow.Context,
+) error {
+	flowName := s.config.FlowJobName
+	s.logger.Info("requesting incremental snapshot for peer flow - ", flowName)
+
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+
+	if err := workflow.ExecuteActivity(ctx, flowable.RequestIncrementalSnapshot, s.config).Get(ctx, nil); err != nil {
+		return fmt.Errorf("failed to request incremental snapshot for peer flow: %w", err)
+	}
+
+	return nil
+}
+
 func (s *SnapshotFlowExecution) closeSlotKeepAlive(
 	ctx workflow.Context,
 ) error {
@@ -81,11 +109,29 @@ func (s *SnapshotFlowExecution) closeSlotKeepAlive(
 	return nil
 }
 
+// cloneTableName names the clone of a table to a destination of a mirror,
+// clones to fan-out destinations are told apart from the clones to the destination by the peer name.
+func cloneTableName(cfg *protos.FlowConnectionConfigs, dstName string, destination *protos.Peer) string {
+	if destination.Name != cfg.Destination.Name {
+		return destination.Name + "_" + dstName
+	}
+	return dstName
+}
+
+// cloneFlowName returns the workflow ID of a clone, which is also its flow name,
+// derived from the ID of the workflow that started it.
+func cloneFlowName(flowName string, parentWorkflowID string, cloneName string) string {
+	cloneID := uuid.NewSHA1(uuid.NameSpaceOID, []byte(parentWorkflowID+"/"+cloneName))
+	return regexp.MustCompile("[^a-zA-Z0-9]+").ReplaceAllString(
+		fmt.Sprintf("clone_%s_%s_%s", flowName, cloneName, cloneID.String()), "_")
+}
+
 func (s *SnapshotFlowExecution) cloneTable(
 	boundSelector *concurrency.BoundSelector,
 	childCtx workflow.Context,
 	snapshotName string,
 	mapping *protos.TableMapping,
+	destination *protos.Peer,
 ) error {
 	flowName := s.config.FlowJobName
 	cloneLog := slog.Group("clone-log",
@@ -94,17 +140,23 @@ func (s *SnapshotFlowExecution) cloneTable(
 
 	srcName := mapping.SourceTableIdentifier
 	dstName := mapping.DestinationTableIdentifier
-	childWorkflowIDSideEffect := workflow.SideEffect(childCtx, func(ctx workflow.Context) interface{} {
-		childWorkflowID := fmt.Sprintf("clone_%s_%s_%s", flowName, dstName, uuid.New().String())
-		reg := regexp.MustCompile("[^a-zA-Z0-9]+")
-		return reg.ReplaceAllString(childWorkflowID, "_")
-	})
-
+	cloneName := cloneTableName(s.config, dstName, destination)
 	var childWorkflowID string
-	if err := childWorkflowIDSideEffect.Get(&childWorkflowID); err != nil {
-		slog.Error(fmt.Sprintf("failed to get child id for source table %s and destination table %s",
-			srcName, dstName), slog.Any("error", err), cloneLog)
-		return fmt.Errorf("failed to get child workflow ID: %w", err)
+	if workflow.GetVersion(childCtx, "resumable-clone-id", workflow.DefaultVersion, 1) > workflow.DefaultVersion {
+		// the snapshot flow keeps its workflow ID when it is retried, so the clone keeps its ID too,
+		// and resumes from the partitions recorded for it that haven't finished
+		childWorkflowID = cloneFlowName(flowName, workflow.GetInfo(childCtx).WorkflowExecution.ID, cloneName)
+	} else {
+		childWorkflowIDSideEffect := workflow.SideEffect(childCtx, func(ctx workflow.Context) interface{} {
+			childWorkflowID := fmt.Sprintf("clone_%s_%s_%s", flowName, cloneName, uuid.New().String())
+			reg := regexp.MustCompile("[^a-zA-Z0-9]+")
+			return reg.ReplaceAllString(childWorkflowID, "_")
+		})
+		if err := childWorkflowIDSideEffect.Get(&childWorkflowID); err != nil {
+			slog.Error(fmt.Sprintf("failed to get child id for source table %s and destination table %s",
+				srcName, dstName), slog.Any("error", err), cloneLog)
+			return fmt.Errorf("failed to get child workflow ID: %w", err)
+		}
 	}
 
 	slog.Info(fmt.Sprintf("Obtained child id %s for source table %s and destination table %s",
@@ -136,19 +188,7 @@ func (s *SnapshotFlowExecution) cloneTable(
 		slog.Error("unable to parse source table", slog.Any("error", err), cloneLog)
 		return fmt.Errorf("unable to parse source table: %w", err)
 	}
-	from := "*"
-	if len(mapping.Exclude) != 0 {
-		for _, v := range s.config.TableNameSchemaMapping {
-			if v.TableIdentifier == srcName {
-				colNames := utils.TableSchemaColumnNames(v)
-				for i, colName := range colNames {
-					colNames[i] = fmt.Sprintf(`"%s"`, colName)
-				}
-				from = strings.Join(colNames, ",")
-				break
-			}
-		}
-	}
+	from := cloneSelectColumns(s.config, mapping, srcName)
 
 	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s BETWEEN {{.start}} AND {{.end}}",
 		from, parsedSrcTable.String(), partitionCol)
@@ -166,7 +206,7 @@ func (s *SnapshotFlowExecution) cloneTable(
 	config := &protos.QRepConfig{
 		FlowJobName:                childWorkflowID,
 		SourcePeer:                 sourcePostgres,
-		DestinationPeer:            s.config.Destination,
+		DestinationPeer:            destination,
 		Query:                      query,
 		WatermarkColumn:            partitionCol,
 		WatermarkTable:             srcName,
@@ -177,6 +217,7 @@ func (s *SnapshotFlowExecution) cloneTable(
 		StagingPath:                s.config.SnapshotStagingPath,
 		SyncedAtColName:            s.config.SyncedAtColName,
 		SoftDeleteColName:          s.config.SoftDeleteColName,
+		ResumePartitions:           true,
 		WriteMode: &protos.QRepWriteMode{
 			WriteType: protos.QRepWriteType_QREP_WRITE_MODE_APPEND,
 		},
@@ -197,19 +238,22 @@ func (s *SnapshotFlowExecution) cloneTables(
 
 	boundSelector := concurrency.NewBoundSelector(maxParallelClones, ctx)
 
-	for _, v := range s.config.TableMappings {
-		source := v.SourceTableIdentifier
-		destination := v.DestinationTableIdentifier
-		snapshotName := slotInfo.SnapshotName
-		slog.Info(fmt.Sprintf(
-			"Cloning table with source table %s and destination table name %s",
-			source, destination),
-			slog.String("snapshotName", snapshotName),
-		)
-		err := s.cloneTable(boundSelector, ctx, snapshotName, v)
-		if err != nil {
-			s.logger.Error("failed to start clone child workflow: ", err)
-			continue
+	// fan-out destinations are loaded from the same snapshot
+	for _, destinationPeer := range destinationPeers(ctx, s.config) {
+		for _, v := range s.config.TableMappings {
+			source := v.SourceTableIdentifier
+			destination := v.DestinationTableIdentifier
+			snapshotName := slotInfo.SnapshotName
+			slog.Info(fmt.Sprintf(
+				"Cloning table with source table %s and destination table name %s on peer %s",
+				source, destination, destinationPeer.Name),
+				slog.String("snapshotName", snapshotName),
+			)
+			err := s.cloneTable(boundSelector, ctx, snapshotName, v, destinationPeer)
+			if err != nil {
+				s.logger.Error("failed to start clone child workflow: ", err)
+				continue
+			}
 		}
 	}
 
@@ -251,6 +295,8 @@ func SnapshotFlowWorkflow(ctx workflow.Context, config *protos.FlowConnectionCon
 	se := &SnapshotFlowExecution{
 		config: config,
 		logger: logger,
+		incrementalSnapshotsVersioned: workflow.GetVersion(ctx, "incremental-snapshot",
+			workflow.DefaultVersion, 1) > workflow.DefaultVersion,
 	}
 
 	numTablesInParallel := int(config.SnapshotNumTablesInParallel)
@@ -260,7 +306,7 @@ func SnapshotFlowWorkflow(ctx workflow.Context, config *protos.FlowConnectionCon
 
 	replCtx := ctx
 
-	if !config.DoInitialCopy {
+	if !config.DoInitialCopy || se.incrementalSnapshot() {
 		_, err := se.setupReplication(replCtx)
 		if err != nil {
 			return fmt.Errorf("failed to setup replication: %w", err)
@@ -270,6 +316,12 @@ func SnapshotFlowWorkflow(ctx workflow.Context, config *protos.FlowConnectionCon
 			return fmt.Errorf("failed to close slot keep alive: %w", err)
 		}
 
+		if config.DoInitialCopy {
+			if err := se.requestIncrementalSnapshot(ctx); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 
@@ -302,3 +354,30 @@ func SnapshotFlowWorkflow(ctx workflow.Context, config *protos.FlowConnectionCon
 
 	return nil
 }
+
+// cloneSelectColumns returns the columns selected from a source table to clone it.
+func cloneSelectColumns(config *protos.FlowConnectionConfigs, mapping *protos.TableMapping, srcName string) string {
+	from := "*"
+	if len(mapping.Exclude) != 0 {
+		for _, v := range config.TableNameSchemaMapping {
+			if v.TableIdentifier == srcName {
+				colNames := make([]string, 0, utils.TableSchemaColumns(v))
+				for _, colName := range utils.TableSchemaColumnNames(v) {
+					// the source column is only part of the destination table, its value is selected below
+					if colName != config.SourceColName {
+						colNames = append(colNames, fmt.Sprintf(`"%s"`, colName))
+					}
+				}
+				from = strings.Join(colNames, ",")
+				break
+			}
+		}
+	}
+
+	// rows are loaded with their source, for mirrors from several sources into the same tables
+	if config.SourceColName != "" {
+		from += fmt.Sprintf(",%s AS %s", utils.QuoteLiteral(config.Source.Name),
+			utils.QuoteIdentifier(config.SourceColName))
+	}
+	return from
+}
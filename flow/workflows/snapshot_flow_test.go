@@ -0,0 +1,60 @@
+package peerflow
+
+import (
+	"testing"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneSelectColumnsWithSourceColumn(t *testing.T) {
+	config := &protos.FlowConnectionConfigs{
+		Source:        &protos.Peer{Name: "pg_eu", Type: protos.DBType_POSTGRES},
+		SourceColName: "_PEERDB_SOURCE",
+		TableNameSchemaMapping: map[string]*protos.TableSchema{
+			"public.users": {
+				TableIdentifier:   "public.users",
+				ColumnNames:       []string{"id", "name", "_PEERDB_SOURCE"},
+				ColumnTypes:       []string{"int64", "string", "string"},
+				PrimaryKeyColumns: []string{"id", "_PEERDB_SOURCE"},
+			},
+		},
+	}
+
+	require.Equal(t, `*,'pg_eu' AS "_PEERDB_SOURCE"`,
+		cloneSelectColumns(config, &protos.TableMapping{SourceTableIdentifier: "public.users"}, "public.users"))
+	// the source column isn't selected from the source table when columns are excluded
+	require.Equal(t, `"id","name",'pg_eu' AS "_PEERDB_SOURCE"`,
+		cloneSelectColumns(config, &protos.TableMapping{
+			SourceTableIdentifier: "public.users",
+			Exclude:               []string{"email"},
+		}, "public.users"))
+}
+
+func TestCloneFlowNames(t *testing.T) {
+	config := &protos.FlowConnectionConfigs{
+		FlowJobName:        "users",
+		Destination:        &protos.Peer{Name: "pg_dst"},
+		FanOutDestinations: []*protos.Peer{{Name: "bq_dst"}},
+		TableMappings: []*protos.TableMapping{
+			{SourceTableIdentifier: "public.users", DestinationTableIdentifier: "public.users"},
+		},
+	}
+	state := &CDCFlowWorkflowState{
+		SnapshotFlowID: "snapshot-flow-users-1",
+		TableResyncs: map[string]*TableResync{
+			"public.users": {DestinationTableIdentifier: "public.users", WorkflowID: "resync-table-flow-users-1"},
+		},
+	}
+
+	require.Equal(t, []string{
+		cloneFlowName("users", "snapshot-flow-users-1", "public.users"),
+		cloneFlowName("users", "snapshot-flow-users-1", "bq_dst_public.users"),
+		cloneFlowName("users", "resync-table-flow-users-1", "public.users_resync"),
+	}, state.CloneFlowNames(config))
+	require.Regexp(t, "^clone_users_bq_dst_public_users_[0-9a-f_]+$", state.CloneFlowNames(config)[1])
+
+	// clones of mirrors whose snapshot flow isn't known have random flow names
+	state.SnapshotFlowID = ""
+	require.Len(t, state.CloneFlowNames(config), 1)
+}
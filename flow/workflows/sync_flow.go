@@ -83,6 +83,12 @@ func (s *SyncFlowExecution) executeSyncFlow(
 		return nil, fmt.Errorf("failed to flow: %w", err)
 	}
 
+	// under other policies, the CDC flow decides what is done with schema changes
+	if workflow.GetVersion(ctx, "schema-change-policy", workflow.DefaultVersion, 1) > workflow.DefaultVersion &&
+		config.SchemaChangePolicy != protos.SchemaChangePolicy_SCHEMA_CHANGE_POLICY_APPLY {
+		return syncRes, nil
+	}
+
 	replayTableSchemaDeltaCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
 		StartToCloseTimeout: 30 * time.Minute,
 		WaitForCancellation: true,
@@ -0,0 +1,69 @@
+package peerflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PeerDB-io/peer-flow/generated/protos"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ValidateMirrorWorkflow compares the tables of a CDC mirror between source and destination,
+// all tables of the mirror are compared if no source tables are given.
+// The results are recorded in the catalog under the validation ID.
+func ValidateMirrorWorkflow(
+	ctx workflow.Context,
+	config *protos.FlowConnectionConfigs,
+	validationID string,
+	sourceTables []string,
+) error {
+	logger := workflow.GetLogger(ctx)
+
+	mappings := make([]*protos.TableMapping, 0, len(config.TableMappings))
+	for _, mapping := range config.TableMappings {
+		if len(sourceTables) == 0 || slices.Contains(sourceTables, mapping.SourceTableIdentifier) {
+			mappings = append(mappings, mapping)
+		}
+	}
+
+	catalogCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+	})
+	startFuture := workflow.ExecuteActivity(catalogCtx, flowable.StartMirrorValidation,
+		config, validationID, len(mappings))
+	if err := startFuture.Get(catalogCtx, nil); err != nil {
+		return fmt.Errorf("failed to start validation: %w", err)
+	}
+
+	validateCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 24 * time.Hour,
+		HeartbeatTimeout:    10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	// tables are compared one at a time, to limit the load on the source
+	var tableErrors []string
+	for _, mapping := range mappings {
+		validateFuture := workflow.ExecuteActivity(validateCtx, flowable.ValidateTable, config, validationID, mapping)
+		if err := validateFuture.Get(validateCtx, nil); err != nil {
+			logger.Error("failed to validate table", "table", mapping.SourceTableIdentifier, "error", err)
+			tableErrors = append(tableErrors, err.Error())
+		}
+	}
+
+	finishFuture := workflow.ExecuteActivity(catalogCtx, flowable.FinishMirrorValidation,
+		config, validationID, strings.Join(tableErrors, "; "))
+	if err := finishFuture.Get(catalogCtx, nil); err != nil {
+		return fmt.Errorf("failed to finish validation: %w", err)
+	}
+
+	if len(tableErrors) > 0 {
+		return fmt.Errorf("failed to validate %d tables", len(tableErrors))
+	}
+	logger.Info("validated mirror", "validationID", validationID, "tables", len(mappings))
+	return nil
+}
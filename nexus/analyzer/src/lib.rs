@@ -11,7 +11,7 @@ use pt::{
     flow_model::{FlowJob, FlowJobTableMapping, QRepFlowJob},
     peerdb_peers::{
         peer::Config, BigqueryConfig, ClickhouseConfig, DbType, EventHubConfig, MongoConfig, Peer,
-        PostgresConfig, S3Config, SnowflakeConfig, SqlServerConfig,
+        PostgresConfig, PostgresStandbyConfig, S3Config, SnowflakeConfig, SqlServerConfig,
     },
 };
 use qrep::process_options;
@@ -658,6 +658,7 @@ fn parse_db_options(
                 metadata_schema: opts.get("metadata_schema").map(|s| s.to_string()),
                 transaction_snapshot: "".to_string(),
                 ssh_config: None,
+                standby: parse_standby_info(opts)?,
             };
             let config = Config::PostgresConfig(postgres_config);
             Some(config)
@@ -823,6 +824,35 @@ fn parse_db_options(
     Ok(config)
 }
 
+// the standby of a postgres peer is configured with standby_host, standby_port,
+// standby_user and standby_password, defaulting to the user and password of the primary
+fn parse_standby_info(opts: &HashMap<&str, &str>) -> anyhow::Result<Option<PostgresStandbyConfig>> {
+    let host = match opts.get("standby_host") {
+        Some(host) => host.to_string(),
+        None => return Ok(None),
+    };
+
+    Ok(Some(PostgresStandbyConfig {
+        host,
+        port: opts
+            .get("standby_port")
+            .unwrap_or(&"5432")
+            .parse::<u32>()
+            .context("unable to parse standby port as valid int")?,
+        user: opts
+            .get("standby_user")
+            .or_else(|| opts.get("user"))
+            .context("no standby username specified")?
+            .to_string(),
+        password: opts
+            .get("standby_password")
+            .or_else(|| opts.get("password"))
+            .context("no standby password specified")?
+            .to_string(),
+        ssh_config: None,
+    }))
+}
+
 fn parse_metadata_db_info(conn_str: Option<&str>) -> anyhow::Result<Option<PostgresConfig>> {
     let conn_str = match conn_str {
         Some(conn_str) => conn_str,
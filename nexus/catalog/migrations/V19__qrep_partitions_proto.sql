@@ -0,0 +1,3 @@
+-- store partitions so that an interrupted initial load can resume from the remaining partitions
+ALTER TABLE peerdb_stats.qrep_partitions
+ADD COLUMN IF NOT EXISTS partition_proto BYTEA;
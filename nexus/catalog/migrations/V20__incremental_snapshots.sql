@@ -0,0 +1,13 @@
+-- progress of tables being snapshotted incrementally while changes are pulled.
+-- a chunk read by a pull is pending until the batch holding it is synced up to pending_lsn
+CREATE TABLE IF NOT EXISTS peerdb_stats.incremental_snapshots (
+    flow_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    last_key TEXT[],
+    pending_key TEXT[],
+    pending_lsn BIGINT,
+    pending_done BOOLEAN NOT NULL DEFAULT FALSE,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    requested_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (flow_name, table_name)
+);
@@ -0,0 +1,29 @@
+-- validations comparing the tables of a mirror between source and destination, range by range
+CREATE TABLE IF NOT EXISTS peerdb_stats.mirror_validations (
+    validation_id TEXT PRIMARY KEY,
+    flow_name TEXT NOT NULL,
+    num_tables INT NOT NULL,
+    num_ranges INT NOT NULL DEFAULT 0,
+    start_time TIMESTAMP NOT NULL DEFAULT now(),
+    end_time TIMESTAMP,
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_mirror_validations_flow_name
+    ON peerdb_stats.mirror_validations (flow_name, start_time);
+
+-- key ranges of tables whose rows differ between source and destination,
+-- bounds are NULL when the range is unbounded
+CREATE TABLE IF NOT EXISTS peerdb_stats.mirror_validation_mismatches (
+    validation_id TEXT NOT NULL REFERENCES peerdb_stats.mirror_validations(validation_id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    range_start TEXT,
+    range_end TEXT,
+    source_rows BIGINT NOT NULL,
+    destination_rows BIGINT NOT NULL,
+    keys_match BOOLEAN NOT NULL,
+    rows_match BOOLEAN NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mirror_validation_mismatches_validation_id
+    ON peerdb_stats.mirror_validation_mismatches (validation_id);
@@ -0,0 +1,14 @@
+-- records of mirrors whose values failed to convert, skipped with the dead letter record error policy
+CREATE TABLE IF NOT EXISTS peerdb_stats.dead_letter_records (
+    id BIGSERIAL PRIMARY KEY,
+    flow_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    lsn BIGINT NOT NULL,
+    column_name TEXT NOT NULL,
+    raw_value TEXT,
+    raw_record JSONB NOT NULL,
+    error_message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    -- records are pulled again when a sync is retried
+    UNIQUE (flow_name, lsn, table_name)
+);
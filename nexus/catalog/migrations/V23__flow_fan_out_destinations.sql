@@ -0,0 +1,6 @@
+-- destinations a CDC mirror fans out to besides the destination peer of its flows entries
+CREATE TABLE IF NOT EXISTS flow_fan_out_destinations (
+    flow_name TEXT NOT NULL,
+    peer_id INTEGER NOT NULL REFERENCES peers(id),
+    PRIMARY KEY (flow_name, peer_id)
+);
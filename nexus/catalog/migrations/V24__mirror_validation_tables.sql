@@ -0,0 +1,9 @@
+-- ranges compared per table of a validation, recorded once per table so that retried tables are not counted twice
+CREATE TABLE IF NOT EXISTS peerdb_stats.mirror_validation_tables (
+    validation_id TEXT NOT NULL REFERENCES peerdb_stats.mirror_validations(validation_id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    num_ranges INT NOT NULL,
+    PRIMARY KEY (validation_id, table_name)
+);
+
+ALTER TABLE peerdb_stats.mirror_validations DROP COLUMN IF EXISTS num_ranges;
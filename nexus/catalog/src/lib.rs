@@ -66,6 +66,7 @@ impl<'a> CatalogConfig<'a> {
             transaction_snapshot: "".to_string(),
             metadata_schema: Some("".to_string()),
             ssh_config: None,
+            standby: None,
         }
     }
 
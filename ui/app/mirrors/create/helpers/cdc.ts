@@ -1,3 +1,8 @@
+import {
+  ConflictResolution,
+  RecordErrorPolicy,
+  SchemaChangePolicy,
+} from '@/grpc_generated/flow';
 import { CDCConfig } from '../../../dto/MirrorsDTO';
 import { MirrorSetting } from './common';
 export const cdcSettings: MirrorSetting[] = [
@@ -127,4 +132,209 @@ export const cdcSettings: MirrorSetting[] = [
     type: 'switch',
     advanced: true,
   },
+  {
+    label: 'Backfill Unchanged TOAST Columns',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        backfillUnchangedToastColumns: (value as boolean) || false,
+      })),
+    tips: 'If set, PeerDB will fetch values of unchanged TOAST columns from the source table, so that every update carries all columns. Requires a primary key on the table.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Before Image Column Name',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        beforeImageColName: (value as string) || '',
+      })),
+    tips: 'If set, the values of a row before an update or delete are written as JSON to this column of the destination tables. Use REPLICA IDENTITY FULL on source tables to capture all columns.',
+    advanced: true,
+  },
+  {
+    label: 'History Mode',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        historyMode: (value as boolean) || false,
+      })),
+    tips: 'If set, every change is kept as a new version of the row in the destination tables, with _PEERDB_VALID_FROM, _PEERDB_VALID_TO and _PEERDB_IS_CURRENT columns, instead of overwriting it.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Transaction Metadata',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        transactionMetadata: (value as boolean) || false,
+      })),
+    tips: 'If set, the commit time, LSN and transaction ID of each change on the source are written to the _PEERDB_COMMIT_TS, _PEERDB_LSN and _PEERDB_XID columns of the destination tables.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Logical Message Prefixes',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        logicalMessagePrefixes: ((value as string) || '')
+          .split(',')
+          .map((prefix) => prefix.trim())
+          .filter((prefix) => prefix.length > 0),
+      })),
+    tips: 'Comma separated prefixes of messages emitted on the source with pg_logical_emit_message to capture. Requires Postgres 14 or later.',
+    advanced: true,
+  },
+  {
+    label: 'Logical Message Destination',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        logicalMessageDestination: (value as string) || '',
+      })),
+    tips: 'Table or event topic of the destination peer that captured logical messages are written to, with prefix, content, transactional and lsn columns.',
+    advanced: true,
+  },
+  {
+    label: 'Pull From Standby',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        pullFromStandby: (value as boolean) || false,
+      })),
+    tips: 'If set, changes are pulled from the standby configured for the source peer, taking replication load off the primary. Requires Postgres 16 or later and hot_standby_feedback on the standby.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Bidirectional',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        bidirectional: (value as boolean) || false,
+      })),
+    tips: 'For Postgres to Postgres mirrors running in both directions. Changes applied by PeerDB are not pulled back from the destination. Requires Postgres 16 or later on both peers.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Last Writer Wins',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        conflictResolution: value
+          ? ConflictResolution.CONFLICT_RESOLUTION_LAST_WRITER_WINS
+          : ConflictResolution.CONFLICT_RESOLUTION_SOURCE_PRIORITY,
+      })),
+    tips: 'If set, changes are not applied to rows changed on the destination after the change was committed on the source. Otherwise changes from the source always win. Requires track_commit_timestamp on the destination.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Sync Sequences',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        syncSequences: (value as boolean) || false,
+      })),
+    tips: 'For Postgres to Postgres mirrors. Sequences of serial and identity columns are periodically advanced on the destination past their values on the source, and once more when the mirror is paused for cutover.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Sequence Sync Margin',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        sequenceSyncMargin: parseInt(value as string, 10) || 1000,
+      })),
+    tips: 'Added to the values of source sequences when syncing them to the destination. The default value is 1000.',
+    default: '1000',
+    type: 'number',
+    advanced: true,
+  },
+  {
+    label: 'Replicate Table Metadata',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        replicateTableMetadata: (value as boolean) || false,
+      })),
+    tips: 'For Postgres to Postgres mirrors. Secondary indexes, unique and check constraints, defaults and column comments of the source tables are created on the destination tables.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Defer Index Creation',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        deferIndexCreation: (value as boolean) || false,
+      })),
+    tips: 'If set, secondary indexes and unique constraints are only created after the initial snapshot, which makes the initial load faster.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Incremental Snapshot',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        incrementalSnapshot: (value as boolean) || false,
+      })),
+    tips: 'If set, the initial snapshot does not hold a snapshot on the source. Tables are read in chunks by primary key while changes are replicated. Requires Postgres 14 or later and a primary key on every table.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Incremental Snapshot Chunk Size',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        incrementalSnapshotChunkSize: parseInt(value as string, 10) || 10000,
+      })),
+    tips: 'Number of rows read per chunk of an incremental snapshot. The default value is 10000.',
+    default: '10000',
+    type: 'number',
+    advanced: true,
+  },
+  {
+    label: 'Pause On Schema Changes',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        schemaChangePolicy: value
+          ? SchemaChangePolicy.SCHEMA_CHANGE_POLICY_PAUSE
+          : SchemaChangePolicy.SCHEMA_CHANGE_POLICY_APPLY,
+      })),
+    tips: 'If set, the mirror pauses when columns are added to source tables, until the schema changes are accepted or rejected through the API. Otherwise added columns are added to the destination tables.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Dead Letter Failed Records',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        recordErrorPolicy: value
+          ? RecordErrorPolicy.RECORD_ERROR_POLICY_DEAD_LETTER
+          : RecordErrorPolicy.RECORD_ERROR_POLICY_FAIL,
+      })),
+    tips: 'If set, records with values that fail to convert, such as invalid numerics or geometries, are stored in the dead letter table of the catalog and skipped. Otherwise the sync fails and is retried.',
+    type: 'switch',
+    advanced: true,
+  },
+  {
+    label: 'Source Column Name',
+    stateHandler: (value, setter) =>
+      setter((curr: CDCConfig) => ({
+        ...curr,
+        sourceColName: (value as string) || '',
+      })),
+    tips: 'For mirrors from several sources into the same tables, for example _PEERDB_SOURCE. The name of the source peer is stored in this column, which is added to the primary key of the destination tables so that rows from different sources do not collide.',
+    advanced: true,
+  },
 ];
@@ -1,7 +1,10 @@
 import {
+  ConflictResolution,
   FlowConnectionConfigs,
   QRepSyncMode,
   QRepWriteType,
+  RecordErrorPolicy,
+  SchemaChangePolicy,
 } from '@/grpc_generated/flow';
 import { Peer } from '@/grpc_generated/peers';
 
@@ -47,6 +50,25 @@ export const blankCDCSetting: FlowConnectionConfigs = {
   syncedAtColName: '',
   initialCopyOnly: false,
   idleTimeoutSeconds: 60,
+  backfillUnchangedToastColumns: false,
+  beforeImageColName: '',
+  historyMode: false,
+  transactionMetadata: false,
+  logicalMessagePrefixes: [],
+  logicalMessageDestination: '',
+  pullFromStandby: false,
+  bidirectional: false,
+  conflictResolution: ConflictResolution.CONFLICT_RESOLUTION_SOURCE_PRIORITY,
+  syncSequences: false,
+  sequenceSyncMargin: 0,
+  replicateTableMetadata: false,
+  deferIndexCreation: false,
+  incrementalSnapshot: false,
+  incrementalSnapshotChunkSize: 0,
+  schemaChangePolicy: SchemaChangePolicy.SCHEMA_CHANGE_POLICY_APPLY,
+  recordErrorPolicy: RecordErrorPolicy.RECORD_ERROR_POLICY_FAIL,
+  fanOutDestinations: [],
+  sourceColName: '',
 };
 
 export const blankQRepSetting = {